@@ -0,0 +1,30 @@
+package goscript
+
+import (
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+)
+
+// Errors returned by the routines of a script, wrapped with context such as the file path.
+// Match them with errors.Is instead of comparing error text:
+//
+//	if errors.Is(err, goscript.ErrFileOpen) {
+//		// the input file is missing or unreadable
+//	}
+var (
+	// ErrFileOpen is wrapped when a file cannot be opened for reading or writing.
+	ErrFileOpen = filesystem.ErrFileOpen
+	// ErrCodecParse is wrapped when a codec fails to parse the file or reader being read.
+	ErrCodecParse = filesystem.ErrCodecParse
+	// ErrCodecEncode is wrapped when a codec fails to encode a message being written.
+	ErrCodecEncode = filesystem.ErrCodecEncode
+	// ErrMaxBufferExceeded is wrapped when a record is larger than a codec can buffer,
+	// such as a line over 64KiB or a blob over its maximum size.
+	ErrMaxBufferExceeded = filesystem.ErrMaxBufferExceeded
+	// ErrTypeMismatch matches the TypeMismatchError reported by strict routines.
+	ErrTypeMismatch = routines.ErrTypeMismatch
+)
+
+// TypeMismatchError reports a message whose data is not of the type a routine expects.
+// Use errors.As to read the message ID and the types involved.
+type TypeMismatchError = routines.TypeMismatchError
@@ -0,0 +1,68 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/caiorcferreira/goscript"
+)
+
+// words is a custom routine emitting one message per word of each line, tagged with the
+// number of the line it came from.
+type words struct{}
+
+func (words) Start(ctx context.Context, pipe goscript.Pipe) error {
+	defer pipe.Close()
+
+	line := 0
+	for msg := range pipe.In() {
+		line++
+
+		for i, word := range strings.Fields(msg.Data.(string)) {
+			out := goscript.NewMsg(ctx, word).
+				WithID(fmt.Sprintf("%d.%d", line, i+1)).
+				WithMeta("line", line)
+
+			if err := pipe.Send(ctx, out); err != nil {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// describe renders each message with its ID and meta, replacing its data.
+type describe struct{}
+
+func (describe) Start(ctx context.Context, pipe goscript.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		line, _ := msg.MetaValue("line")
+		text := fmt.Sprintf("%s: %v (line %v)\n", msg.ID, msg.Data, line)
+
+		if err := pipe.Send(ctx, msg.WithData(text)); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func ExampleNewMsg() {
+	out, err := goscript.FromString("hello world\ngoodbye").
+		Chain(words{}).
+		Chain(describe{}).
+		ToString(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Print(out)
+	// Output:
+	// 1.1: hello (line 1)
+	// 1.2: world (line 1)
+	// 2.1: goodbye (line 2)
+}
@@ -1,29 +1,41 @@
-package examples
+package examples_test
 
-//func ExampleSubpipe() {
-//	println("This is an example function.")
-//
-//	// Set slog to debug level
-//	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-//		Level: slog.LevelDebug,
-//	}))
-//	slog.SetDefault(logger)
-//
-//	textFormatting := pipeline.New().
-//		Chain(routines.Transform(strings.ToUpper)).
-//		Chain(routines.Transform(func(t string) string {
-//			return strings.ReplaceAll(t, " ", "_")
-//		}))
-//
-//	script := goscript.New()
-//	script.
-//		BlobFileIn("data/example.txt").
-//		Chain(textFormatting).
-//		BlobFileOut("data/output/subpipe_5.txt")
-//
-//	ctx := context.Background()
-//	err := script.Run(ctx)
-//	if err != nil {
-//		panic(err)
-//	}
-//}
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/caiorcferreira/goscript"
+	"github.com/caiorcferreira/goscript/internal/routines"
+)
+
+func ExamplePipeline() {
+	// a reusable text formatting step shared by two scripts
+	textFormatting := goscript.Pipeline().
+		Chain(routines.Transform(strings.ToUpper)).
+		Chain(routines.Transform(func(t string) string {
+			return strings.ReplaceAll(t, " ", "_") + "\n"
+		}))
+
+	ctx := context.Background()
+
+	cities, err := goscript.FromString("north dayham\nbroken shield").
+		Chain(textFormatting).
+		ToString(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	streets, err := goscript.FromString("main street").
+		Chain(textFormatting).
+		ToString(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Print(cities, streets)
+	// Output:
+	// NORTH_DAYHAM
+	// BROKEN_SHIELD
+	// MAIN_STREET
+}
@@ -1,20 +1,77 @@
 package pipeline
 
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainPollInterval is how often Drain checks whether the consumer has emptied the out buffer.
+const drainPollInterval = 5 * time.Millisecond
+
+// PipeStats is a snapshot of the link from a pipe's Out to the next pipe's In.
+type PipeStats struct {
+	// Name identifies the stage writing to the link, set by Pipeline and Script snapshots
+	Name string
+	// Counted reports whether Sent and Received are tracked, see CountMessages
+	Counted bool
+	// Sent is the number of messages the producer has written to the link
+	Sent uint64
+	// Received is the number of messages the consumer has read from the link
+	Received uint64
+	// Buffered is the number of messages written but not yet read; a stalled consumer
+	// shows a buffer that stops draining
+	Buffered int
+	// Closed reports whether the producer has closed the pipe
+	Closed bool
+}
+
+// ErrPipeClosed is returned by Send when the pipe no longer accepts messages.
+var ErrPipeClosed = errors.New("pipe closed")
+
 type ChannelPipe struct {
 	in  chan Msg
 	out chan Msg
 
 	done chan struct{}
+
+	// closing is closed once the pipe stops accepting sends, waking any blocked Send.
+	closing chan struct{}
+	// mu guards closed; in-flight sends hold a read lock so out is never closed under them.
+	mu     sync.RWMutex
+	closed bool
+
+	// counted pipes relay out to link until stopRelay, tracking sent and delivered messages
+	counted   bool
+	stopRelay <-chan struct{}
+	link      chan Msg
+	sent      atomic.Uint64
+	delivered atomic.Uint64
 }
 
 func NewChanPipe() *ChannelPipe {
 	return &ChannelPipe{
-		in:   make(chan Msg, 1),
-		out:  make(chan Msg, 1),
-		done: make(chan struct{}),
+		in:      make(chan Msg, 1),
+		out:     make(chan Msg, 1),
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
 	}
 }
 
+// CountMessages makes the pipe count the messages crossing the link it is chained to
+// afterwards, reported by Stats. The link is relayed through an extra goroutine, adding
+// one message of buffering, until ctx is done, so pass the context of the consumer.
+// Counting is meant for diagnosing stalls rather than production runs.
+func (c *ChannelPipe) CountMessages(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counted = true
+	c.stopRelay = ctx.Done()
+}
+
 func (c *ChannelPipe) Done() <-chan struct{} {
 	return c.done
 }
@@ -36,16 +93,163 @@ func (c *ChannelPipe) SetOutChan(cout chan Msg) {
 }
 
 func (c *ChannelPipe) Chain(p Pipe) {
-	c.out = p.In()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.counted {
+		c.out = p.In()
+		return
+	}
+
+	tap := make(chan Msg)
+	c.out = tap
+	c.link = p.In()
+
+	go c.relay(tap, c.link, c.stopRelay)
+}
+
+// relay forwards every message written to tap into link, counting them, and closes link
+// once tap is closed or stop is, so a consumer that stopped reading does not leak it.
+func (c *ChannelPipe) relay(tap <-chan Msg, link chan Msg, stop <-chan struct{}) {
+	defer SafeClose(link)
+
+	for msg := range tap {
+		c.sent.Add(1)
+
+		select {
+		case <-stop:
+			return
+		case link <- msg:
+		}
+
+		c.delivered.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the pipe's out link. Sent and Received are only tracked
+// for pipes chained to another pipe after CountMessages.
+func (c *ChannelPipe) Stats() PipeStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := PipeStats{Closed: c.closed}
+
+	if c.link == nil {
+		stats.Buffered = len(c.out)
+		return stats
+	}
+
+	delivered := c.delivered.Load()
+	unread := uint64(len(c.link))
+
+	stats.Counted = true
+	stats.Sent = c.sent.Load()
+	stats.Received = delivered - min(unread, delivered)
+	stats.Buffered = int(stats.Sent - stats.Received)
+
+	return stats
+}
+
+// pending is the number of accepted messages the consumer has not read yet.
+func (c *ChannelPipe) pending() int {
+	if c.link == nil {
+		return len(c.out)
+	}
+
+	return int(c.sent.Load()-c.delivered.Load()) + len(c.link)
+}
+
+// Send writes msg to the out channel, blocking until it is read, ctx is done or the pipe
+// closes. It returns ctx.Err() when ctx ends first and ErrPipeClosed when the pipe is
+// closing. Unlike a raw write to Out(), Send never panics, even if the out channel was
+// closed elsewhere.
+func (c *ChannelPipe) Send(ctx context.Context, msg Msg) (err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return ErrPipeClosed
+	}
+
+	defer func() {
+		if recover() != nil {
+			err = ErrPipeClosed
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closing:
+		return ErrPipeClosed
+	case c.out <- msg:
+		return nil
+	}
 }
 
 func (c *ChannelPipe) Close() error {
+	c.stopSends()
 	SafeClose(c.done)
-	SafeClose(c.out)
 
 	return nil
 }
 
+// Drain closes the pipe gracefully: it stops accepting sends, waits for in-flight
+// sends to finish, closes out and only signals Done once the consumer has read every
+// accepted message. If ctx ends first, Done is signalled anyway and ctx.Err() is returned.
+func (c *ChannelPipe) Drain(ctx context.Context) error {
+	c.stopSends()
+	defer SafeClose(c.done)
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for c.pending() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// stopSends rejects new sends, waits for in-flight ones and closes out exactly once.
+func (c *ChannelPipe) stopSends() {
+	SafeClose(c.closing)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+	SafeClose(c.out)
+}
+
+// Reset reopens the pipe with fresh channels and clears its counters, so a closed pipe can
+// be reused instead of allocating a new one. It must only be called once nothing holds the
+// pipe's channels anymore.
+func (c *ChannelPipe) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.in = make(chan Msg, 1)
+	c.out = make(chan Msg, 1)
+	c.done = make(chan struct{})
+	c.closing = make(chan struct{})
+	c.closed = false
+
+	c.counted = false
+	c.stopRelay = nil
+	c.link = nil
+	c.sent.Store(0)
+	c.delivered.Store(0)
+}
+
 func SafeClose[T any](ch chan T) (justClosed bool) {
 	defer func() {
 		if recover() != nil {
@@ -0,0 +1,305 @@
+package pipeline_test
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelPipe_Send(t *testing.T) {
+	t.Run("rejects sends after close", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		require.NoError(t, pipe.Close())
+
+		assert.NotPanics(t, func() {
+			assert.ErrorIs(t, pipe.Send(context.Background(), pipeline.Msg{ID: "1"}), pipeline.ErrPipeClosed)
+		})
+	})
+
+	t.Run("unblocks pending send on close", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		require.NoError(t, pipe.Send(context.Background(), pipeline.Msg{ID: "1"}))
+
+		result := make(chan error, 1)
+		go func() {
+			result <- pipe.Send(context.Background(), pipeline.Msg{ID: "2"})
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		require.NoError(t, pipe.Close())
+
+		select {
+		case err := <-result:
+			assert.ErrorIs(t, err, pipeline.ErrPipeClosed)
+		case <-time.After(time.Second):
+			t.Fatal("send did not return after close")
+		}
+	})
+
+	t.Run("returns an error when out was closed elsewhere", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		out := make(chan pipeline.Msg)
+		pipe.SetOutChan(out)
+		close(out)
+
+		assert.NotPanics(t, func() {
+			assert.ErrorIs(t, pipe.Send(context.Background(), pipeline.Msg{ID: "1"}), pipeline.ErrPipeClosed)
+		})
+	})
+
+	t.Run("stops on context cancellation", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		require.NoError(t, pipe.Send(context.Background(), pipeline.Msg{ID: "1"}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, pipe.Send(ctx, pipeline.Msg{ID: "2"}), context.Canceled)
+	})
+}
+
+func TestChannelPipe_Drain(t *testing.T) {
+	t.Run("signals done only after buffer is consumed", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		require.NoError(t, pipe.Send(context.Background(), pipeline.Msg{ID: "1"}))
+
+		drained := make(chan error, 1)
+		go func() {
+			drained <- pipe.Drain(context.Background())
+		}()
+
+		select {
+		case <-pipe.Done():
+			t.Fatal("done signalled before buffered message was consumed")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		msg, ok := <-pipe.Out()
+		require.True(t, ok)
+		assert.Equal(t, "1", msg.ID)
+
+		require.NoError(t, <-drained)
+		<-pipe.Done()
+	})
+
+	t.Run("returns context error when consumer never reads", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		require.NoError(t, pipe.Send(context.Background(), pipeline.Msg{ID: "1"}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, pipe.Drain(ctx), context.DeadlineExceeded)
+
+		select {
+		case <-pipe.Done():
+		default:
+			t.Fatal("done not signalled after drain timeout")
+		}
+	})
+
+	t.Run("never loses accepted messages under concurrent shutdown", func(t *testing.T) {
+		for round := range 50 {
+			pipe := pipeline.NewChanPipe()
+			ctx, cancel := context.WithCancel(context.Background())
+
+			var accepted atomic.Int64
+			var senders sync.WaitGroup
+			for range 4 {
+				senders.Add(1)
+				go func() {
+					defer senders.Done()
+					for {
+						if pipe.Send(ctx, pipeline.Msg{ID: "x"}) != nil {
+							return
+						}
+						accepted.Add(1)
+					}
+				}()
+			}
+
+			var received int64
+			consumed := make(chan struct{})
+			go func() {
+				defer close(consumed)
+				for range pipe.Out() {
+					received++
+				}
+			}()
+
+			time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+			if round%2 == 0 {
+				cancel()
+			}
+
+			assert.NotPanics(t, func() {
+				assert.NoError(t, pipe.Drain(context.Background()))
+			})
+
+			senders.Wait()
+			<-consumed
+			cancel()
+
+			assert.Equal(t, accepted.Load(), received, "round %d", round)
+		}
+	})
+}
+
+func TestChannelPipe_Stats(t *testing.T) {
+	t.Run("counts a known stream", func(t *testing.T) {
+		producer := pipeline.NewChanPipe()
+		producer.CountMessages(t.Context())
+		consumer := pipeline.NewChanPipe()
+		producer.Chain(consumer)
+
+		go func() {
+			for i := range 10 {
+				producer.Out() <- pipeline.Msg{Data: i}
+			}
+			producer.Close()
+		}()
+
+		var received int
+		for range consumer.In() {
+			received++
+		}
+
+		stats := producer.Stats()
+		assert.Equal(t, 10, received)
+		assert.True(t, stats.Counted)
+		assert.EqualValues(t, 10, stats.Sent)
+		assert.EqualValues(t, 10, stats.Received)
+		assert.Zero(t, stats.Buffered)
+		assert.True(t, stats.Closed)
+	})
+
+	t.Run("stalled consumer shows a non-draining buffer", func(t *testing.T) {
+		producer := pipeline.NewChanPipe()
+		producer.CountMessages(t.Context())
+		consumer := pipeline.NewChanPipe()
+		producer.Chain(consumer)
+
+		go func() {
+			for i := range 5 {
+				producer.Out() <- pipeline.Msg{Data: i}
+			}
+		}()
+
+		var first pipeline.PipeStats
+		require.Eventually(t, func() bool {
+			first = producer.Stats()
+			return first.Buffered == 2
+		}, time.Second, 5*time.Millisecond)
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, first, producer.Stats(), "buffer should not drain without a consumer")
+		assert.Zero(t, first.Received)
+		assert.False(t, first.Closed)
+
+		<-consumer.In()
+		require.Eventually(t, func() bool {
+			return producer.Stats().Received == 1
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("stops relaying once its context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+
+		producer := pipeline.NewChanPipe()
+		producer.CountMessages(ctx)
+		consumer := pipeline.NewChanPipe()
+		producer.Chain(consumer)
+
+		go func() {
+			for i := range 3 {
+				producer.Send(ctx, pipeline.Msg{Data: i})
+			}
+		}()
+
+		require.Eventually(t, func() bool {
+			return producer.Stats().Buffered == 2
+		}, time.Second, 5*time.Millisecond)
+
+		cancel()
+
+		closed := make(chan int)
+		go func() {
+			var received int
+			for range consumer.In() {
+				received++
+			}
+			closed <- received
+		}()
+
+		select {
+		case received := <-closed:
+			assert.Equal(t, 1, received, "only the message already in the link is read")
+		case <-time.After(time.Second):
+			t.Fatal("relay kept the link open after its context was done")
+		}
+	})
+
+	t.Run("reports only buffer depth without counting", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		pipe.Out() <- pipeline.Msg{}
+
+		stats := pipe.Stats()
+		assert.False(t, stats.Counted)
+		assert.Equal(t, 1, stats.Buffered)
+	})
+
+	t.Run("drain waits for relayed messages", func(t *testing.T) {
+		producer := pipeline.NewChanPipe()
+		producer.CountMessages(t.Context())
+		consumer := pipeline.NewChanPipe()
+		producer.Chain(consumer)
+
+		producer.Out() <- pipeline.Msg{Data: 1}
+		producer.Out() <- pipeline.Msg{Data: 2}
+
+		drained := make(chan error, 1)
+		go func() { drained <- producer.Drain(context.Background()) }()
+
+		time.Sleep(20 * time.Millisecond)
+		select {
+		case <-producer.Done():
+			t.Fatal("drain finished before the consumer read")
+		default:
+		}
+
+		for range consumer.In() {
+		}
+		require.NoError(t, <-drained)
+	})
+}
+
+func TestChannelPipe_Reset(t *testing.T) {
+	t.Run("reopens a closed pipe", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+		require.NoError(t, pipe.Close())
+		require.ErrorIs(t, pipe.Send(context.Background(), pipeline.Msg{Data: 1}), pipeline.ErrPipeClosed)
+
+		pipe.Reset()
+
+		require.NoError(t, pipe.Send(context.Background(), pipeline.Msg{Data: 2}))
+		assert.Equal(t, 2, (<-pipe.Out()).Data)
+
+		pipe.In() <- pipeline.Msg{Data: 3}
+		assert.Equal(t, 3, (<-pipe.In()).Data)
+
+		assert.False(t, pipe.Stats().Closed)
+		select {
+		case <-pipe.Done():
+			t.Fatal("reset pipe reports done")
+		default:
+		}
+	})
+}
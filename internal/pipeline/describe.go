@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Describer is implemented by routines that can summarize their configuration in one line,
+// e.g. `read file "in.csv" with CSVCodec{Separator: ';'}`, for Script.Describe.
+type Describer interface {
+	Describe() string
+}
+
+// Describe returns r's own description if it is a Describer, or its type otherwise.
+func Describe(r Routine) string {
+	if describer, ok := r.(Describer); ok {
+		return describer.Describe()
+	}
+
+	return fmt.Sprintf("%T", r)
+}
+
+// DescribeSettings names v's type along with its exported fields that are not zero, such
+// as JSONCodec{JSONLines} for a codec reading JSON lines. True booleans are listed by name.
+// Fields tagged `describe:"rune"`, such as CSV separators, are quoted as characters; the tag
+// is needed because rune is an alias of int32, so reflection cannot tell them apart.
+func DescribeSettings(v any) string {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return fmt.Sprintf("%T", v)
+	}
+
+	var settings []string
+	for i := range value.NumField() {
+		field, fieldValue := value.Type().Field(i), value.Field(i)
+		if !field.IsExported() || fieldValue.IsZero() {
+			continue
+		}
+
+		switch {
+		case fieldValue.Kind() == reflect.Bool:
+			settings = append(settings, field.Name)
+		case fieldValue.Kind() == reflect.Struct:
+			settings = append(settings, fmt.Sprintf("%s: %s", field.Name, DescribeSettings(fieldValue.Interface())))
+		case fieldValue.Kind() == reflect.Int32 && field.Tag.Get("describe") == "rune":
+			settings = append(settings, fmt.Sprintf("%s: %q", field.Name, rune(fieldValue.Int())))
+		default:
+			settings = append(settings, fmt.Sprintf("%s: %v", field.Name, fieldValue.Interface()))
+		}
+	}
+
+	name := value.Type().Name()
+	if len(settings) == 0 {
+		return name
+	}
+
+	return name + "{" + strings.Join(settings, ", ") + "}"
+}
@@ -0,0 +1,53 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+type describedRoutine struct{}
+
+func (describedRoutine) Start(context.Context, pipeline.Pipe) error { return nil }
+
+func (describedRoutine) Describe() string { return "described" }
+
+type plainRoutine struct{}
+
+func (*plainRoutine) Start(context.Context, pipeline.Pipe) error { return nil }
+
+func TestDescribe(t *testing.T) {
+	assert.Equal(t, "described", pipeline.Describe(describedRoutine{}))
+	assert.Equal(t, "*pipeline_test.plainRoutine", pipeline.Describe(&plainRoutine{}))
+}
+
+func TestDescribeSettings(t *testing.T) {
+	type limits struct {
+		Strict bool
+	}
+	type codec struct {
+		Enabled   bool
+		Disabled  bool
+		Separator rune `describe:"rune"`
+		Retries   int32
+		Name      string
+		Timeout   time.Duration
+		Limits    limits
+		Empty     limits
+		internal  int
+	}
+
+	t.Run("lists the exported settings that are set", func(t *testing.T) {
+		c := &codec{Enabled: true, Separator: ';', Retries: 59, Name: "orders", Timeout: time.Second, Limits: limits{Strict: true}, internal: 1}
+
+		assert.Equal(t, `codec{Enabled, Separator: ';', Retries: 59, Name: orders, Timeout: 1s, Limits: limits{Strict}}`, pipeline.DescribeSettings(c))
+	})
+
+	t.Run("names a type without settings", func(t *testing.T) {
+		assert.Equal(t, "codec", pipeline.DescribeSettings(codec{}))
+		assert.Equal(t, "int", pipeline.DescribeSettings(7))
+	})
+}
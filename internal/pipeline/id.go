@@ -0,0 +1,44 @@
+package pipeline
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator returns a new message ID on every call. Sources and parallel workers call it
+// from many goroutines, so implementations must be safe for concurrent use.
+type IDGenerator func() string
+
+// RandomIDs generates random UUIDs, the default when no generator is configured.
+func RandomIDs() IDGenerator {
+	return uuid.NewString
+}
+
+// SequentialIDs generates "1", "2", "3"... from an atomic counter, so IDs are unique across
+// goroutines and a run over the same input in the same order reproduces them.
+func SequentialIDs() IDGenerator {
+	var counter atomic.Uint64
+
+	return func() string {
+		return strconv.FormatUint(counter.Add(1), 10)
+	}
+}
+
+type idGeneratorKey struct{}
+
+// WithIDGenerator returns a context whose routines create message IDs with gen.
+func WithIDGenerator(ctx context.Context, gen IDGenerator) context.Context {
+	return context.WithValue(ctx, idGeneratorKey{}, gen)
+}
+
+// NewID returns a message ID from the generator set on ctx, or a random UUID.
+func NewID(ctx context.Context) string {
+	if gen, ok := ctx.Value(idGeneratorKey{}).(IDGenerator); ok && gen != nil {
+		return gen()
+	}
+
+	return uuid.NewString()
+}
@@ -109,6 +109,20 @@ func (mr *MockPipeMockRecorder) Out() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Out", reflect.TypeOf((*MockPipe)(nil).Out))
 }
 
+// Send mocks base method.
+func (m *MockPipe) Send(ctx context.Context, msg pipeline.Msg) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, msg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockPipeMockRecorder) Send(ctx, msg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockPipe)(nil).Send), ctx, msg)
+}
+
 // MockRoutine is a mock of Routine interface.
 type MockRoutine struct {
 	ctrl     *gomock.Controller
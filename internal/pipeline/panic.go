@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicHandler is called with the value and stack of a recovered panic.
+type PanicHandler func(recovered any, stack []byte)
+
+type panicScopeKey struct{}
+
+// panicScope holds the panic handler of a context and counts the goroutines started under
+// it with Guard that have not returned yet.
+type panicScope struct {
+	handler PanicHandler
+
+	mu      sync.Mutex
+	idle    *sync.Cond
+	running int
+}
+
+// WithPanicHandler returns a context whose routines report panics, including those of the
+// goroutines they start, to handler instead of crashing the process. The returned function
+// waits until every goroutine started with Guard or Go under the context has returned, so
+// a caller can tell whether one panicked before reporting success: a panicking routine
+// closes its pipes while unwinding, before handler is called.
+func WithPanicHandler(ctx context.Context, handler PanicHandler) (context.Context, func()) {
+	scope := &panicScope{handler: handler}
+	scope.idle = sync.NewCond(&scope.mu)
+
+	return context.WithValue(ctx, panicScopeKey{}, scope), scope.wait
+}
+
+func (s *panicScope) add() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running++
+}
+
+func (s *panicScope) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running--; s.running == 0 {
+		s.idle.Broadcast()
+	}
+}
+
+func (s *panicScope) wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.running > 0 {
+		s.idle.Wait()
+	}
+}
+
+// Recover reports a panic of the calling goroutine to the handler set on ctx, and must be
+// deferred directly at the top of the goroutine. Without a handler the panic goes on and
+// crashes the process, as it would without Recover.
+func Recover(ctx context.Context) {
+	scope, ok := ctx.Value(panicScopeKey{}).(*panicScope)
+	if !ok {
+		return
+	}
+
+	if r := recover(); r != nil {
+		scope.handler(r, debug.Stack())
+	}
+}
+
+// Guard wraps fn, about to run on a goroutine of its own, so that a panic in it is
+// recovered like Recover and the goroutine is counted until it returns. It returns fn
+// unchanged when ctx has no panic handler. Guard must be called before the goroutine
+// starts, e.g. to hand the result to a worker pool.
+func Guard(ctx context.Context, fn func()) func() {
+	scope, ok := ctx.Value(panicScopeKey{}).(*panicScope)
+	if !ok {
+		return fn
+	}
+
+	scope.add()
+
+	return func() {
+		defer scope.done()
+		defer Recover(ctx)
+
+		fn()
+	}
+}
+
+// Go runs fn on a new goroutine guarded by Guard.
+//
+// Example:
+//
+//	pipeline.Go(ctx, func() {
+//		work(ctx)
+//	})
+func Go(ctx context.Context, fn func()) {
+	go Guard(ctx, fn)()
+}
@@ -0,0 +1,55 @@
+package pipeline_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGo(t *testing.T) {
+	t.Run("reports panics of started goroutines to the handler", func(t *testing.T) {
+		var (
+			mu        sync.Mutex
+			recovered []any
+		)
+
+		ctx, wait := pipeline.WithPanicHandler(context.Background(), func(r any, stack []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			recovered = append(recovered, r)
+			assert.Contains(t, string(stack), "panic_test.go")
+		})
+
+		pipeline.Go(ctx, func() { panic("first") })
+		pipeline.Go(ctx, func() {
+			// a goroutine started by a guarded one is guarded too
+			pipeline.Go(ctx, func() { panic("nested") })
+		})
+		pipeline.Go(ctx, func() {})
+
+		wait()
+
+		assert.ElementsMatch(t, []any{"first", "nested"}, recovered)
+	})
+
+	t.Run("leaves the goroutine unchanged without a handler", func(t *testing.T) {
+		called := false
+		task := pipeline.Guard(context.Background(), func() { called = true })
+
+		task()
+
+		assert.True(t, called)
+	})
+
+	t.Run("recover lets the panic through without a handler", func(t *testing.T) {
+		require.PanicsWithValue(t, "boom", func() {
+			defer pipeline.Recover(context.Background())
+			panic("boom")
+		})
+	})
+}
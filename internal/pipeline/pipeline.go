@@ -1,12 +1,35 @@
 package pipeline
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sync"
+)
+
+// ErrorPolicy decides what a Pipeline does when a stage returns an error.
+type ErrorPolicy int
+
+const (
+	// ContinueOnError logs stage errors and lets the other stages keep running. It is the default.
+	ContinueOnError ErrorPolicy = iota
+	// FailFast cancels every stage on the first stage error and returns it from Start.
+	FailFast
 )
 
 type Pipeline struct {
-	routines []Routine
+	stages      []stage
+	bufferSize  int
+	onPanic     func(recovered any, stack []byte)
+	errorPolicy ErrorPolicy
+	// countMessages makes every internal pipe count its messages, see Stats
+	countMessages bool
+
+	// pipes holds the internal pipes of the running pipeline, in flow order
+	mu    sync.Mutex
+	pipes []*ChannelPipe
 }
 
 // New creates a new instance of Pipeline with default values.
@@ -14,8 +37,65 @@ func New() *Pipeline {
 	return &Pipeline{}
 }
 
+// stage is a chained routine and the buffer size of its input, zero for the pipeline default.
+type stage struct {
+	routine    Routine
+	bufferSize int
+}
+
 func (s *Pipeline) Chain(r Routine) *Pipeline {
-	s.routines = append(s.routines, r)
+	s.stages = append(s.stages, stage{routine: r})
+
+	return s
+}
+
+// ChainWithBuffer chains r with an input buffer of size messages, letting the previous
+// stage run up to size messages ahead of it.
+func (s *Pipeline) ChainWithBuffer(r Routine, size int) *Pipeline {
+	s.stages = append(s.stages, stage{routine: r, bufferSize: size})
+
+	return s
+}
+
+// Routines returns the chained routines in flow order.
+func (s *Pipeline) Routines() []Routine {
+	routines := make([]Routine, len(s.stages))
+	for i, step := range s.stages {
+		routines[i] = step.routine
+	}
+
+	return routines
+}
+
+// WithBufferSize sets the input buffer of every stage chained without an explicit size.
+// The default of one message keeps stages in near lockstep; deeper buffers absorb bursts
+// between stages of uneven speed.
+func (s *Pipeline) WithBufferSize(size int) *Pipeline {
+	s.bufferSize = size
+
+	return s
+}
+
+// OnPanic recovers panics escaping any stage, or a goroutine a stage started, and reports
+// them to handler instead of crashing the process. The panicking stage stops; handler
+// decides how to shut down.
+func (s *Pipeline) OnPanic(handler func(recovered any, stack []byte)) *Pipeline {
+	s.onPanic = handler
+
+	return s
+}
+
+// WithMessageCounts makes every internal pipe count the messages crossing it, reported by
+// Stats, see ChannelPipe.CountMessages.
+func (s *Pipeline) WithMessageCounts() *Pipeline {
+	s.countMessages = true
+
+	return s
+}
+
+// WithErrorPolicy sets how stage errors are handled, see ContinueOnError and FailFast.
+func (s *Pipeline) WithErrorPolicy(policy ErrorPolicy) *Pipeline {
+	s.errorPolicy = policy
 
 	return s
 }
@@ -24,33 +104,81 @@ func (s *Pipeline) Start(ctx context.Context, pipe Pipe) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	var (
+		stages   sync.WaitGroup
+		errOnce  sync.Once
+		stageErr error
+	)
+
+	if s.onPanic != nil {
+		ctx, _ = WithPanicHandler(ctx, s.onPanic)
+	}
+
 	inPipe := NewChanPipe()
+	if s.countMessages {
+		inPipe.CountMessages(ctx)
+	}
 	previousPipe := inPipe
 
-	for _, routine := range s.routines {
+	pipes := []*ChannelPipe{inPipe}
+
+	for i, step := range s.stages {
+		routine := step.routine
+
 		stepPipe := NewChanPipe()
+		if size := cmp.Or(step.bufferSize, s.bufferSize); size > 0 {
+			stepPipe.SetInChan(make(chan Msg, size))
+		}
+		if s.countMessages {
+			stepPipe.CountMessages(ctx)
+		}
 
 		previousPipe.Chain(stepPipe)
 		previousPipe = stepPipe
+		pipes = append(pipes, stepPipe)
+
+		stages.Add(1)
+		Go(ctx, func() {
+			defer stages.Done()
 
-		go func() {
 			err := routine.Start(ctx, stepPipe)
-			if err != nil {
-				slog.Error("routine error", "error", err)
+			if err == nil {
+				return
 			}
-		}()
+
+			if s.errorPolicy == FailFast {
+				if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+					// stopped by the shutdown rather than failing on its own
+					return
+				}
+
+				errOnce.Do(func() {
+					stageErr = fmt.Errorf("stage %d failed: %w", i+1, err)
+					cancel()
+				})
+				return
+			}
+
+			slog.Error("routine error", "error", err)
+		})
 	}
 
+	// the last stage feeds an exit pipe so every stage writes to a chained link
+	exitPipe := NewChanPipe()
+	previousPipe.Chain(exitPipe)
+
+	s.mu.Lock()
+	s.pipes = pipes
+	s.mu.Unlock()
+
 	go func() {
 		defer inPipe.Close()
 
 		for msg := range pipe.In() {
 			slog.Debug("pipeline received message", "msg", msg)
 
-			select {
-			case <-ctx.Done():
+			if err := inPipe.Send(ctx, msg); err != nil {
 				return
-			case inPipe.Out() <- msg:
 			}
 		}
 	}()
@@ -58,18 +186,40 @@ func (s *Pipeline) Start(ctx context.Context, pipe Pipe) error {
 	go func() {
 		defer pipe.Close()
 
-		for msg := range previousPipe.Out() {
+		for msg := range exitPipe.In() {
 			slog.Debug("pipeline forwarding message", "msg", msg)
 
-			select {
-			case <-ctx.Done():
+			if err := pipe.Send(ctx, msg); err != nil {
 				return
-			case pipe.Out() <- msg:
 			}
 		}
 	}()
 
 	<-pipe.Done()
 
-	return nil
+	// a stage failing at the end of the stream closes its pipe before its error is
+	// recorded, so stop the stages still running and wait for every one to return
+	cancel()
+	stages.Wait()
+
+	return stageErr
+}
+
+// Stats returns a snapshot of every internal link of the running pipeline: the entry link
+// feeding the first stage, then one per stage. It is empty before Start.
+func (s *Pipeline) Stats() []PipeStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]PipeStats, len(s.pipes))
+	for i, pipe := range s.pipes {
+		stats[i] = pipe.Stats()
+		stats[i].Name = fmt.Sprintf("stage %d", i)
+	}
+
+	if len(stats) > 0 {
+		stats[0].Name = "pipeline entry"
+	}
+
+	return stats
 }
@@ -599,3 +599,258 @@ func TestPipeline_WithMockRoutine(t *testing.T) {
 
 	// Mock expectations are verified automatically by gomock
 }
+
+// passThrough forwards every message, spinning for work iterations per message.
+type passThrough struct {
+	work func(i int) int
+}
+
+func (p passThrough) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	i, sink := 0, 0
+	for msg := range pipe.In() {
+		if p.work != nil {
+			sink += spin(p.work(i))
+		}
+		i++
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case pipe.Out() <- msg:
+		}
+	}
+
+	_ = sink
+
+	return nil
+}
+
+// spin burns CPU proportional to n.
+func spin(n int) int {
+	sum := 0
+	for i := range n {
+		sum += i
+	}
+	return sum
+}
+
+// runStages pushes count messages through ppl and returns the received IDs.
+func runStages(t testing.TB, ppl *pipeline.Pipeline, count int) []string {
+	sourcePipe := pipeline.NewChanPipe()
+
+	go func() {
+		defer close(sourcePipe.In())
+		for i := range count {
+			sourcePipe.In() <- pipeline.Msg{ID: fmt.Sprint(i)}
+		}
+	}()
+
+	ids := make([]string, 0, count)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range sourcePipe.Out() {
+			ids = append(ids, msg.ID)
+		}
+	}()
+
+	require.NoError(t, ppl.Start(context.Background(), sourcePipe))
+	<-done
+
+	return ids
+}
+
+func TestPipeline_Start_BufferSize(t *testing.T) {
+	expected := make([]string, 200)
+	for i := range expected {
+		expected[i] = fmt.Sprint(i)
+	}
+
+	t.Run("delivers every message in order with a pipeline buffer", func(t *testing.T) {
+		ppl := pipeline.New().WithBufferSize(16).
+			Chain(passThrough{}).
+			Chain(passThrough{}).
+			Chain(passThrough{})
+
+		assert.Equal(t, expected, runStages(t, ppl, 200))
+	})
+
+	t.Run("delivers every message in order with per-stage buffers", func(t *testing.T) {
+		ppl := pipeline.New().
+			ChainWithBuffer(passThrough{}, 1).
+			ChainWithBuffer(passThrough{}, 64).
+			Chain(passThrough{})
+
+		assert.Equal(t, expected, runStages(t, ppl, 200))
+	})
+}
+
+func BenchmarkPipeline_BufferSize(b *testing.B) {
+	// stages with bursty, uneven cost: buffers let fast stretches absorb slow ones
+	bursty := func(offset int) func(int) int {
+		return func(i int) int {
+			if (i+offset)%16 == 0 {
+				return 20000
+			}
+			return 200
+		}
+	}
+
+	for _, size := range []int{1, 16, 256} {
+		b.Run(fmt.Sprintf("buffer=%d", size), func(b *testing.B) {
+			for b.Loop() {
+				ppl := pipeline.New().WithBufferSize(size).
+					Chain(passThrough{work: bursty(0)}).
+					Chain(passThrough{work: bursty(5)}).
+					Chain(passThrough{work: bursty(11)})
+
+				runStages(b, ppl, 1000)
+			}
+		})
+	}
+}
+
+func TestPipeline_Start_ErrorPolicy(t *testing.T) {
+	errBadRecord := errors.New("bad record")
+
+	stage := func(ctrl *gomock.Controller, failAt int, failAtEnd bool) *pipelinemocks.MockRoutine {
+		routine := pipelinemocks.NewMockRoutine(ctrl)
+		routine.EXPECT().Start(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, pipe pipeline.Pipe) error {
+				defer pipe.Close()
+
+				seen := 0
+				for msg := range pipe.In() {
+					if seen++; seen == failAt {
+						return errBadRecord
+					}
+
+					if pipe.Send(ctx, msg) != nil {
+						return nil
+					}
+				}
+
+				if failAtEnd {
+					return errBadRecord
+				}
+				return nil
+			},
+		)
+
+		return routine
+	}
+
+	collect := func(pipe pipeline.Pipe) <-chan []pipeline.Msg {
+		result := make(chan []pipeline.Msg, 1)
+		go func() {
+			var msgs []pipeline.Msg
+			for msg := range pipe.Out() {
+				msgs = append(msgs, msg)
+			}
+			result <- msgs
+		}()
+
+		return result
+	}
+
+	feed := func(pipe pipeline.Pipe, n int) {
+		go func() {
+			defer close(pipe.In())
+			for i := range n {
+				pipe.In() <- pipeline.Msg{ID: fmt.Sprint(i)}
+			}
+		}()
+	}
+
+	t.Run("fail fast aborts promptly on a failing middle stage", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		ppl := pipeline.New().
+			Chain(stage(ctrl, 0, false)).
+			Chain(stage(ctrl, 3, false)).
+			Chain(stage(ctrl, 0, false)).
+			WithErrorPolicy(pipeline.FailFast)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// a source that never ends on its own
+		inputPipe := pipeline.NewChanPipe()
+		go func() {
+			defer close(inputPipe.In())
+			for i := 0; ; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case inputPipe.In() <- pipeline.Msg{ID: fmt.Sprint(i)}:
+				}
+			}
+		}()
+		output := collect(inputPipe)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- ppl.Start(ctx, inputPipe)
+		}()
+
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, errBadRecord)
+			assert.ErrorContains(t, err, "stage 2 failed")
+		case <-time.After(2 * time.Second):
+			t.Fatal("pipeline did not abort after the stage failed")
+		}
+
+		// messages in flight when the stage failed may be dropped
+		assert.LessOrEqual(t, len(<-output), 2)
+	})
+
+	t.Run("continue on error processes the rest", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		ppl := pipeline.New().
+			Chain(stage(ctrl, 0, false)).
+			Chain(stage(ctrl, 0, true)).
+			Chain(stage(ctrl, 0, false))
+
+		inputPipe := pipeline.NewChanPipe()
+		feed(inputPipe, 10)
+		output := collect(inputPipe)
+
+		require.NoError(t, ppl.Start(context.Background(), inputPipe))
+		assert.Len(t, <-output, 10)
+	})
+
+	t.Run("continue on error keeps the other stages running after a mid-stream failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		ppl := pipeline.New().
+			Chain(stage(ctrl, 0, false)).
+			Chain(stage(ctrl, 4, false)).
+			Chain(stage(ctrl, 0, false))
+
+		inputPipe := pipeline.NewChanPipe()
+		feed(inputPipe, 10)
+		output := collect(inputPipe)
+
+		require.NoError(t, ppl.Start(context.Background(), inputPipe))
+		assert.Len(t, <-output, 3, "the failing stage forwarded the messages before its failure")
+	})
+
+	t.Run("fail fast reports an error raised at end of stream", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		ppl := pipeline.New().
+			Chain(stage(ctrl, 0, true)).
+			WithErrorPolicy(pipeline.FailFast)
+
+		inputPipe := pipeline.NewChanPipe()
+		feed(inputPipe, 10)
+		output := collect(inputPipe)
+
+		assert.ErrorIs(t, ppl.Start(context.Background(), inputPipe), errBadRecord)
+		<-output
+	})
+}
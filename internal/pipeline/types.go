@@ -3,16 +3,64 @@ package pipeline
 import (
 	"context"
 	"io"
+	"maps"
+	"time"
 )
 
 type Msg struct {
 	ID   string
 	Data any
+	// IngestedAt is when a source created the message. Transforms carry it along, so
+	// later stages can measure latency or drop stale messages.
+	IngestedAt time.Time
+	// Meta holds annotations routines attach to a message besides its data. Use WithMeta
+	// to set entries, since copies of a message share the map.
+	Meta map[string]any
+}
+
+// NewMsg creates a message stamped with the current time, for use by source routines and codecs.
+func NewMsg(id string, data any) Msg {
+	return Msg{ID: id, Data: data, IngestedAt: time.Now()}
+}
+
+// WithData returns a copy of m holding data, keeping its ID and ingestion time.
+func (m Msg) WithData(data any) Msg {
+	m.Data = data
+	return m
+}
+
+// WithID returns a copy of m with the given ID.
+func (m Msg) WithID(id string) Msg {
+	m.ID = id
+	return m
+}
+
+// WithMeta returns a copy of m with the meta entry key set to value. The meta map is
+// copied, so m and other copies of it are left unchanged.
+func (m Msg) WithMeta(key string, value any) Msg {
+	meta := maps.Clone(m.Meta)
+	if meta == nil {
+		meta = make(map[string]any, 1)
+	}
+
+	meta[key] = value
+	m.Meta = meta
+
+	return m
+}
+
+// MetaValue returns the meta entry key and whether it is set.
+func (m Msg) MetaValue(key string) (any, bool) {
+	value, ok := m.Meta[key]
+	return value, ok
 }
 
 type Pipe interface {
 	In() chan Msg
 	Out() chan Msg
+	// Send writes msg to Out unless ctx is done or the pipe is closed, returning an error
+	// instead of blocking forever or panicking on a closed channel
+	Send(ctx context.Context, msg Msg) error
 	Done() <-chan struct{}
 	Chain(p Pipe)
 	io.Closer
@@ -0,0 +1,114 @@
+package routines
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// BackoffPolicy decides how long to wait before a retry. Attempt is 1 for the first retry.
+// Retry, Reconnect and the HTTP routines share it, so one policy tunes every retry loop.
+type BackoffPolicy interface {
+	Next(attempt int) time.Duration
+}
+
+// DefaultBackoff returns the policy used by retry loops given a nil one: full jitter over
+// delays doubling from 100ms up to 10s.
+func DefaultBackoff() BackoffPolicy {
+	return NewFullJitterBackoff(100*time.Millisecond, 10*time.Second)
+}
+
+// retryWithBackoff calls call until it succeeds, fails with an error it reports as not
+// retryable, or has been retried maxRetries times, waiting policy.Next between calls. A nil
+// policy uses DefaultBackoff. It returns ctx.Err() if ctx ends while waiting; logAttrs
+// describe the call in the retry logs.
+func retryWithBackoff(
+	ctx context.Context, maxRetries int, policy BackoffPolicy, call func() (retryable bool, err error), logAttrs ...any,
+) error {
+	if policy == nil {
+		policy = DefaultBackoff()
+	}
+
+	for attempt := 1; ; attempt++ {
+		retryable, err := call()
+		if err == nil || !retryable || attempt > maxRetries {
+			return err
+		}
+
+		wait := policy.Next(attempt)
+		slog.Warn("retrying", append(logAttrs, "attempt", attempt, "wait", wait, "error", err)...)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// ConstantBackoff waits the same delay before every retry.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+func NewConstantBackoff(delay time.Duration) ConstantBackoff {
+	return ConstantBackoff{Delay: delay}
+}
+
+func (b ConstantBackoff) Next(int) time.Duration {
+	return b.Delay
+}
+
+// ExponentialBackoff doubles the delay on every retry, starting at Base and capped at Max.
+// A zero Max leaves the delay uncapped.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func NewExponentialBackoff(base, max time.Duration) ExponentialBackoff {
+	return ExponentialBackoff{Base: base, Max: max}
+}
+
+func (b ExponentialBackoff) Next(attempt int) time.Duration {
+	return exponentialDelay(b.Base, b.Max, attempt)
+}
+
+// FullJitterBackoff waits a random delay between zero and the exponential delay of the
+// attempt, spreading out retries of many clients failing at once.
+type FullJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func NewFullJitterBackoff(base, max time.Duration) FullJitterBackoff {
+	return FullJitterBackoff{Base: base, Max: max}
+}
+
+func (b FullJitterBackoff) Next(attempt int) time.Duration {
+	ceiling := exponentialDelay(b.Base, b.Max, attempt)
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return rand.N(ceiling + 1)
+}
+
+// exponentialDelay returns base * 2^(attempt-1), capped at max and safe from overflow.
+func exponentialDelay(base, max time.Duration, attempt int) time.Duration {
+	if max <= 0 {
+		max = math.MaxInt64
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		if delay > math.MaxInt64/2 {
+			return max
+		}
+		delay *= 2
+	}
+
+	return min(delay, max)
+}
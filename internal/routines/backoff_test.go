@@ -0,0 +1,57 @@
+package routines_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffPolicies(t *testing.T) {
+	t.Run("constant backoff waits the same delay", func(t *testing.T) {
+		policy := routines.NewConstantBackoff(50 * time.Millisecond)
+
+		for attempt := 1; attempt <= 5; attempt++ {
+			assert.Equal(t, 50*time.Millisecond, policy.Next(attempt))
+		}
+	})
+
+	t.Run("exponential backoff doubles up to the cap", func(t *testing.T) {
+		policy := routines.NewExponentialBackoff(100*time.Millisecond, time.Second)
+
+		var delays []time.Duration
+		for attempt := 1; attempt <= 6; attempt++ {
+			delays = append(delays, policy.Next(attempt))
+		}
+
+		assert.Equal(t, []time.Duration{
+			100 * time.Millisecond,
+			200 * time.Millisecond,
+			400 * time.Millisecond,
+			800 * time.Millisecond,
+			time.Second,
+			time.Second,
+		}, delays)
+	})
+
+	t.Run("exponential backoff without a cap does not overflow", func(t *testing.T) {
+		policy := routines.NewExponentialBackoff(time.Second, 0)
+
+		assert.Equal(t, 8*time.Second, policy.Next(4))
+		assert.Positive(t, policy.Next(1000))
+	})
+
+	t.Run("full jitter stays between zero and the capped exponential delay", func(t *testing.T) {
+		policy := routines.NewFullJitterBackoff(100*time.Millisecond, 300*time.Millisecond)
+		ceilings := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+
+		for i, ceiling := range ceilings {
+			for range 100 {
+				delay := policy.Next(i + 1)
+				assert.GreaterOrEqual(t, delay, time.Duration(0))
+				assert.LessOrEqual(t, delay, ceiling)
+			}
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package routines
+
+import (
+	"context"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// BatchRoutine groups messages into batches emitted as a single message whose Data is
+// the []any of the collected message data. A batch is flushed when it reaches size, when
+// its oldest message has waited maxWait, when the input has been idle for the idle flush
+// duration, and when the input closes. A partial batch is also flushed when ctx is
+// cancelled, as long as the next stage takes it within the cancel flush timeout.
+type BatchRoutine struct {
+	size        int
+	maxWait     time.Duration
+	idleFlush   time.Duration
+	cancelFlush time.Duration
+}
+
+// defaultCancelFlushTimeout bounds how long a cancelled routine waits to hand over its
+// partial batch or window, e.g. into a buffered pipe or a stage still draining, unless
+// WithCancelFlush sets another timeout.
+const defaultCancelFlushTimeout = 100 * time.Millisecond
+
+// Batch collects up to size messages per batch. A size of zero or less leaves batches
+// unbounded, so only the time based flushes and the end of input emit them.
+func Batch(size int) *BatchRoutine {
+	return &BatchRoutine{size: size, cancelFlush: defaultCancelFlushTimeout}
+}
+
+// WithCancelFlush sets how long a cancelled routine waits for the next stage to take its
+// partial batch, e.g. the drain timeout of the script; zero or less drops it. Defaults to
+// 100ms.
+func (b *BatchRoutine) WithCancelFlush(d time.Duration) *BatchRoutine {
+	b.cancelFlush = d
+	return b
+}
+
+// WithMaxWait flushes a partial batch once its first message has waited d.
+func (b *BatchRoutine) WithMaxWait(d time.Duration) *BatchRoutine {
+	b.maxWait = d
+	return b
+}
+
+// WithIdleFlush flushes a partial batch when no new message arrives within d, cutting
+// latency during quiet periods of an interactive stream.
+func (b *BatchRoutine) WithIdleFlush(d time.Duration) *BatchRoutine {
+	b.idleFlush = d
+	return b
+}
+
+// Stateful marks BatchRoutine as a StatefulRoutine, since a batch spans several messages.
+func (b *BatchRoutine) Stateful() {}
+
+func (b *BatchRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	var (
+		batch []any
+
+		maxWaitTimer, idleTimer *time.Timer
+		maxWaitC, idleC         <-chan time.Time
+	)
+
+	stopTimers := func() {
+		if maxWaitTimer != nil {
+			maxWaitTimer.Stop()
+			maxWaitTimer, maxWaitC = nil, nil
+		}
+		if idleTimer != nil {
+			idleTimer.Stop()
+			idleTimer, idleC = nil, nil
+		}
+	}
+	defer stopTimers()
+
+	flush := func(ctx context.Context) bool {
+		stopTimers()
+
+		if len(batch) == 0 {
+			return true
+		}
+
+		msg := pipeline.Msg{ID: pipeline.NewID(ctx), Data: batch}
+		batch = nil
+
+		return pipe.Send(ctx, msg) == nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if b.cancelFlush > 0 {
+				flushCtx, cancel := cancelFlushContext(ctx, b.cancelFlush)
+				defer cancel()
+
+				flush(flushCtx)
+			}
+			return nil
+		case msg, ok := <-pipe.In():
+			if !ok {
+				flush(ctx)
+				return nil
+			}
+
+			if len(batch) == 0 && b.maxWait > 0 {
+				maxWaitTimer = time.NewTimer(b.maxWait)
+				maxWaitC = maxWaitTimer.C
+			}
+
+			batch = append(batch, msg.Data)
+
+			if b.size > 0 && len(batch) >= b.size {
+				if !flush(ctx) {
+					return nil
+				}
+				continue
+			}
+
+			if b.idleFlush > 0 {
+				if idleTimer == nil {
+					idleTimer = time.NewTimer(b.idleFlush)
+					idleC = idleTimer.C
+				} else {
+					idleTimer.Reset(b.idleFlush)
+				}
+			}
+		case <-maxWaitC:
+			if !flush(ctx) {
+				return nil
+			}
+		case <-idleC:
+			if !flush(ctx) {
+				return nil
+			}
+		}
+	}
+}
+
+// cancelFlushContext returns a context for handing over what a cancelled routine holds,
+// keeping the values of ctx but ending timeout after it is created.
+func cancelFlushContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), timeout)
+}
@@ -0,0 +1,171 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchRoutine_Start(t *testing.T) {
+	t.Run("groups messages by size and flushes the remainder", func(t *testing.T) {
+		results := runRoutine(t, routines.Batch(2), generateTestMsgs(1, 5))
+
+		require.Len(t, results, 3)
+		assert.Equal(t, []any{1, 2}, results[0].Data)
+		assert.Equal(t, []any{3, 4}, results[1].Data)
+		assert.Equal(t, []any{5}, results[2].Data)
+	})
+
+	t.Run("emits only full batches for an exact multiple", func(t *testing.T) {
+		results := runRoutine(t, routines.Batch(3), generateTestMsgs(1, 6))
+
+		require.Len(t, results, 2)
+		assert.Equal(t, []any{1, 2, 3}, results[0].Data)
+		assert.Equal(t, []any{4, 5, 6}, results[1].Data)
+	})
+
+	t.Run("wraps every message with a size of one", func(t *testing.T) {
+		results := runRoutine(t, routines.Batch(1), generateTestMsgs(1, 3))
+
+		require.Len(t, results, 3)
+		for i, msg := range results {
+			assert.Equal(t, []any{i + 1}, msg.Data)
+		}
+	})
+
+	t.Run("flushes a partial batch when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			_ = routines.Batch(10).Start(ctx, pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: 1}
+		pipe.In() <- pipeline.Msg{Data: 2}
+		// the routine has read the first message once the second fits the input buffer
+		pipe.In() <- pipeline.Msg{Data: 3}
+		cancel()
+
+		var batches []any
+		for msg := range pipe.Out() {
+			batches = append(batches, msg.Data)
+		}
+
+		require.Len(t, batches, 1)
+		assert.Subset(t, []any{1, 2, 3}, batches[0])
+		assert.GreaterOrEqual(t, len(batches[0].([]any)), 2)
+	})
+
+	t.Run("waits for the next stage up to the cancel flush timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		pipe := pipeline.NewChanPipe()
+		pipe.SetInChan(make(chan pipeline.Msg))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Batch(10).WithCancelFlush(time.Minute).Start(ctx, pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: 1}
+		cancel()
+
+		// slower than the default timeout, which would drop the batch
+		time.Sleep(300 * time.Millisecond)
+
+		var batches []any
+		for msg := range pipe.Out() {
+			batches = append(batches, msg.Data)
+		}
+		require.NoError(t, <-done)
+
+		assert.Equal(t, []any{[]any{1}}, batches)
+	})
+
+	t.Run("drops a partial batch when cancelled without a cancel flush timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		pipe := pipeline.NewChanPipe()
+		pipe.SetInChan(make(chan pipeline.Msg))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Batch(10).WithCancelFlush(0).Start(ctx, pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: 1}
+		cancel()
+
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("flushes a partial batch when the input goes idle before max wait", func(t *testing.T) {
+		routine := routines.Batch(100).WithMaxWait(5 * time.Second).WithIdleFlush(30 * time.Millisecond)
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			_ = routine.Start(t.Context(), pipe)
+		}()
+
+		// burst, then go quiet
+		for i := 1; i <= 3; i++ {
+			pipe.In() <- pipeline.Msg{Data: i}
+		}
+
+		start := time.Now()
+		select {
+		case batch := <-pipe.Out():
+			assert.Equal(t, []any{1, 2, 3}, batch.Data)
+			assert.Less(t, time.Since(start), time.Second, "idle flush should happen well before max wait")
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected an idle flush")
+		}
+
+		// a second burst starts a new batch
+		pipe.In() <- pipeline.Msg{Data: 4}
+		close(pipe.In())
+
+		batch := <-pipe.Out()
+		assert.Equal(t, []any{4}, batch.Data)
+
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+	})
+
+	t.Run("flushes after max wait while messages keep arriving", func(t *testing.T) {
+		routine := routines.Batch(0).WithMaxWait(50 * time.Millisecond)
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			_ = routine.Start(t.Context(), pipe)
+		}()
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				case pipe.In() <- pipeline.Msg{Data: i}:
+					time.Sleep(5 * time.Millisecond)
+				}
+			}
+		}()
+
+		select {
+		case batch := <-pipe.Out():
+			assert.NotEmpty(t, batch.Data)
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected a max wait flush")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package routines
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"sync"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// ChecksumAlgorithm names the hash used by Checksum.
+type ChecksumAlgorithm string
+
+const (
+	CRC32  ChecksumAlgorithm = "crc32"
+	SHA256 ChecksumAlgorithm = "sha256"
+)
+
+// ChecksumRoutine forwards every message unchanged while hashing its bytes. Messages are
+// hashed back to back, so the digest equals the hash of the concatenated output.
+type ChecksumRoutine struct {
+	algo       ChecksumAlgorithm
+	emitDigest bool
+
+	mu     sync.Mutex
+	digest []byte
+}
+
+func Checksum(algo ChecksumAlgorithm) *ChecksumRoutine {
+	return &ChecksumRoutine{algo: algo}
+}
+
+// EmitDigest makes the routine send the hex digest as a final message once the input closes.
+func (c *ChecksumRoutine) EmitDigest() *ChecksumRoutine {
+	c.emitDigest = true
+	return c
+}
+
+// Digest returns the final digest, or nil while the routine is still running.
+func (c *ChecksumRoutine) Digest() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.digest
+}
+
+// HexDigest returns the final digest hex encoded.
+func (c *ChecksumRoutine) HexDigest() string {
+	return hex.EncodeToString(c.Digest())
+}
+
+// Stateful marks ChecksumRoutine as a StatefulRoutine, since the digest covers every
+// message in stream order.
+func (c *ChecksumRoutine) Stateful() {}
+
+func (c *ChecksumRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	h, err := newHash(c.algo)
+	if err != nil {
+		return err
+	}
+
+	for msg := range pipe.In() {
+		h.Write(messageBytes(msg.Data))
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	digest := h.Sum(nil)
+
+	c.mu.Lock()
+	c.digest = digest
+	c.mu.Unlock()
+
+	if !c.emitDigest {
+		return nil
+	}
+
+	_ = pipe.Send(ctx, pipeline.Msg{ID: pipeline.NewID(ctx), Data: hex.EncodeToString(digest)})
+
+	return nil
+}
+
+// newHash returns a new hash computing algo.
+func newHash(algo ChecksumAlgorithm) (hash.Hash, error) {
+	switch algo {
+	case CRC32:
+		return crc32.NewIEEE(), nil
+	case SHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+}
+
+// messageBytes returns the raw bytes of a message as they would be written to a blob.
+func messageBytes(data any) []byte {
+	switch v := data.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	default:
+		return []byte(fmt.Sprintf("%v", v))
+	}
+}
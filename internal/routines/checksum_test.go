@@ -0,0 +1,71 @@
+package routines_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumRoutine_Start(t *testing.T) {
+	stream := func(data ...any) []pipeline.Msg {
+		msgs := make([]pipeline.Msg, len(data))
+		for i, d := range data {
+			msgs[i] = pipeline.Msg{ID: "", Data: d}
+		}
+		return msgs
+	}
+
+	t.Run("forwards messages unchanged", func(t *testing.T) {
+		input := stream("a", []byte("b"), 3)
+
+		results := runRoutine(t, routines.Checksum(routines.SHA256), input)
+
+		assert.Equal(t, input, results)
+	})
+
+	t.Run("identical streams produce identical checksums", func(t *testing.T) {
+		for _, algo := range []routines.ChecksumAlgorithm{routines.CRC32, routines.SHA256} {
+			first := routines.Checksum(algo)
+			second := routines.Checksum(algo)
+			other := routines.Checksum(algo)
+
+			runRoutine(t, first, stream("line1", "line2", 42))
+			runRoutine(t, second, stream("line1", "line2", 42))
+			runRoutine(t, other, stream("line1", "line3", 42))
+
+			require.NotEmpty(t, first.Digest(), algo)
+			assert.Equal(t, first.HexDigest(), second.HexDigest(), algo)
+			assert.NotEqual(t, first.HexDigest(), other.HexDigest(), algo)
+		}
+	})
+
+	t.Run("digest matches the hash of the concatenated output", func(t *testing.T) {
+		checksum := routines.Checksum(routines.SHA256)
+		runRoutine(t, checksum, stream("hello ", []byte("world")))
+
+		expected := sha256.Sum256([]byte("hello world"))
+		assert.Equal(t, hex.EncodeToString(expected[:]), checksum.HexDigest())
+	})
+
+	t.Run("emits digest as final message", func(t *testing.T) {
+		checksum := routines.Checksum(routines.CRC32).EmitDigest()
+
+		results := runRoutine(t, checksum, stream("a", "b"))
+
+		require.Len(t, results, 3)
+		assert.Equal(t, checksum.HexDigest(), results[2].Data)
+	})
+
+	t.Run("rejects unknown algorithms", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+
+		err := routines.Checksum("md4").Start(t.Context(), pipe)
+
+		assert.ErrorContains(t, err, "unsupported checksum algorithm")
+	})
+}
@@ -18,16 +18,18 @@ func Debounce(debounceTime time.Duration) DebounceRoutine {
 	}
 }
 
+// Stateful marks DebounceRoutine as a StatefulRoutine, since the quiet period is measured
+// across the whole stream.
+func (p DebounceRoutine) Stateful() {}
+
 func (p DebounceRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
 	defer pipe.Close()
 
 	for msg := range pipe.In() {
 		time.Sleep(p.debounceTime)
 
-		select {
-		case <-ctx.Done():
+		if err := pipe.Send(ctx, msg); err != nil {
 			return nil
-		case pipe.Out() <- msg:
 		}
 	}
 
@@ -0,0 +1,124 @@
+package routines
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// DebounceByKeyRoutine coalesces rapid updates per key: each message replaces the pending
+// one of its key and restarts that key's timer, and a key's latest message is emitted once
+// no update for it arrived for delay. Keys are debounced independently, so a busy key does
+// not hold back the others. When the input closes, pending messages are flushed at once,
+// oldest deadline first.
+type DebounceByKeyRoutine struct {
+	keyFn func(pipeline.Msg) string
+	delay time.Duration
+}
+
+func DebounceByKey(keyFn func(pipeline.Msg) string, delay time.Duration) *DebounceByKeyRoutine {
+	return &DebounceByKeyRoutine{keyFn: keyFn, delay: delay}
+}
+
+// PreservesOrder reports false: a key's message waits for its own timer, so keys updated
+// later can be emitted first.
+func (d *DebounceByKeyRoutine) PreservesOrder() bool {
+	return false
+}
+
+// pendingUpdate is the latest message of a key, waiting for the key's timer.
+type pendingUpdate struct {
+	msg   pipeline.Msg
+	gen   uint64
+	timer *time.Timer
+}
+
+// keyTimeout is sent by a key's timer; gen tells a stale timer from the current one.
+type keyTimeout struct {
+	key string
+	gen uint64
+}
+
+// Stateful marks DebounceByKeyRoutine as a StatefulRoutine, since updates to a key must
+// reach the same pending entry.
+func (d *DebounceByKeyRoutine) Stateful() {}
+
+func (d *DebounceByKeyRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	pending := make(map[string]*pendingUpdate)
+	fired := make(chan keyTimeout)
+
+	done := make(chan struct{})
+	defer close(done)
+	defer func() {
+		for _, update := range pending {
+			update.timer.Stop()
+		}
+	}()
+
+	var gen uint64
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-pipe.In():
+			if !ok {
+				d.flush(ctx, pipe, pending)
+				return nil
+			}
+
+			key := d.keyFn(msg)
+			if update, found := pending[key]; found {
+				update.timer.Stop()
+			}
+
+			gen++
+			timeout := keyTimeout{key: key, gen: gen}
+			pending[key] = &pendingUpdate{
+				msg: msg,
+				gen: gen,
+				timer: time.AfterFunc(d.delay, func() {
+					select {
+					case fired <- timeout:
+					case <-done:
+					}
+				}),
+			}
+		case timeout := <-fired:
+			update, found := pending[timeout.key]
+			if !found || update.gen != timeout.gen {
+				continue
+			}
+
+			delete(pending, timeout.key)
+
+			if err := pipe.Send(ctx, update.msg); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// flush emits every pending message, oldest deadline first, and clears pending. Deadlines
+// follow update order, which gen records.
+func (d *DebounceByKeyRoutine) flush(ctx context.Context, pipe pipeline.Pipe, pending map[string]*pendingUpdate) {
+	updates := make([]*pendingUpdate, 0, len(pending))
+	for key, update := range pending {
+		update.timer.Stop()
+		updates = append(updates, update)
+		delete(pending, key)
+	}
+
+	slices.SortFunc(updates, func(a, b *pendingUpdate) int { return cmp.Compare(a.gen, b.gen) })
+
+	for _, update := range updates {
+		if err := pipe.Send(ctx, update.msg); err != nil {
+			return
+		}
+	}
+}
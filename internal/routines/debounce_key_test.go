@@ -0,0 +1,77 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebounceByKeyRoutine_Start(t *testing.T) {
+	entity := func(msg pipeline.Msg) string { return msg.Data.(string)[:1] }
+
+	start := func(delay time.Duration) *pipeline.ChannelPipe {
+		pipe := pipeline.NewChanPipe()
+		go routines.DebounceByKey(entity, delay).Start(context.Background(), pipe)
+		return pipe
+	}
+
+	t.Run("emits only the latest update per key after a quiet period", func(t *testing.T) {
+		pipe := start(50 * time.Millisecond)
+
+		for _, update := range []string{"a1", "b1", "a2", "b2", "a3"} {
+			pipe.In() <- pipeline.Msg{ID: update, Data: update}
+		}
+
+		// both keys go quiet at about the same time, so either may fire first
+		var data []any
+		for range 2 {
+			select {
+			case msg := <-pipe.Out():
+				data = append(data, msg.Data)
+			case <-time.After(time.Second):
+				t.Fatal("pending update not emitted")
+			}
+		}
+		assert.ElementsMatch(t, []any{"a3", "b2"}, data)
+
+		close(pipe.In())
+		_, open := <-pipe.Out()
+		assert.False(t, open, "nothing else is pending")
+	})
+
+	t.Run("keeps deferring a key while it keeps updating", func(t *testing.T) {
+		pipe := start(60 * time.Millisecond)
+
+		pipe.In() <- pipeline.Msg{Data: "b1"}
+		for _, update := range []string{"a1", "a2", "a3", "a4"} {
+			pipe.In() <- pipeline.Msg{Data: update}
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		// b went quiet first, while a was still updating
+		assert.Equal(t, "b1", (<-pipe.Out()).Data)
+		assert.Equal(t, "a4", (<-pipe.Out()).Data)
+
+		close(pipe.In())
+	})
+
+	t.Run("flushes pending updates when the input closes", func(t *testing.T) {
+		pipe := start(time.Hour)
+
+		for _, update := range []string{"a1", "b1", "a2"} {
+			pipe.In() <- pipeline.Msg{Data: update}
+		}
+		close(pipe.In())
+
+		var data []any
+		for msg := range pipe.Out() {
+			data = append(data, msg.Data)
+		}
+		require.Equal(t, []any{"b1", "a2"}, data)
+	})
+}
@@ -0,0 +1,74 @@
+package routines
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// DedupRoutine forwards the first message of each key and drops the duplicates after it.
+//
+// By default duplicates are dropped wherever they appear, so every key seen is held in
+// memory for the life of the routine and memory grows with key cardinality. Consecutive
+// drops only repeats of the previous message's key, like uniq, holding a single key.
+type DedupRoutine struct {
+	key         func(pipeline.Msg) string
+	consecutive bool
+}
+
+// Dedup drops messages whose keyFn result was already seen.
+//
+// Example:
+//
+//	byLine := func(msg pipeline.Msg) string { return msg.Data.(string) }
+//	script.Chain(routines.Dedup(byLine).Consecutive())
+func Dedup(keyFn func(pipeline.Msg) string) *DedupRoutine {
+	return &DedupRoutine{key: keyFn}
+}
+
+// Consecutive drops only duplicates adjacent to each other, which is enough for sorted
+// input and keeps memory constant.
+func (d *DedupRoutine) Consecutive() *DedupRoutine {
+	d.consecutive = true
+	return d
+}
+
+// Stateful marks DedupRoutine as a StatefulRoutine, since duplicates are only found among
+// the keys it has seen.
+func (d *DedupRoutine) Stateful() {}
+
+func (d *DedupRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	seen := make(map[string]struct{})
+
+	var (
+		previous    string
+		hasPrevious bool
+	)
+
+	for msg := range pipe.In() {
+		key := d.key(msg)
+
+		var duplicate bool
+		if d.consecutive {
+			duplicate = hasPrevious && key == previous
+			previous, hasPrevious = key, true
+		} else {
+			_, duplicate = seen[key]
+			seen[key] = struct{}{}
+		}
+
+		if duplicate {
+			slog.Debug("dedup dropped message", "msg_id", msg.ID, "key", key)
+			continue
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package routines_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupRoutine_Start(t *testing.T) {
+	byData := func(msg pipeline.Msg) string { return msg.Data.(string) }
+
+	lines := func(data ...string) []pipeline.Msg {
+		msgs := make([]pipeline.Msg, len(data))
+		for i, d := range data {
+			msgs[i] = pipeline.Msg{ID: strconv.Itoa(i), Data: d}
+		}
+		return msgs
+	}
+
+	t.Run("drops every previously seen key by default", func(t *testing.T) {
+		input := lines("a", "b", "a", "c", "b", "a")
+
+		results := runRoutine(t, routines.Dedup(byData), input)
+
+		assert.Equal(t, []pipeline.Msg{input[0], input[1], input[3]}, results)
+	})
+
+	t.Run("drops only adjacent duplicates when consecutive", func(t *testing.T) {
+		input := lines("a", "a", "b", "a", "a", "a", "c", "c")
+
+		results := runRoutine(t, routines.Dedup(byData).Consecutive(), input)
+
+		assert.Equal(t, []pipeline.Msg{input[0], input[2], input[3], input[6]}, results)
+	})
+
+	t.Run("keeps an empty key as a key", func(t *testing.T) {
+		input := lines("", "", "a", "")
+
+		assert.Equal(t, []pipeline.Msg{input[0], input[2]}, runRoutine(t, routines.Dedup(byData), input))
+		assert.Equal(t, []pipeline.Msg{input[0], input[2], input[3]}, runRoutine(t, routines.Dedup(byData).Consecutive(), input))
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		pipe := pipeline.NewChanPipe()
+		pipe.In() <- pipeline.Msg{Data: "a"}
+		pipe.Out() <- pipeline.Msg{Data: "fills the output"}
+		close(pipe.In())
+
+		assert.NoError(t, routines.Dedup(byData).Start(ctx, pipe))
+
+		assert.Equal(t, "fills the output", (<-pipe.Out()).Data)
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+	})
+}
@@ -0,0 +1,176 @@
+package routines
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// StderrPolicy decides what the Exec routine does with the command's stderr.
+type StderrPolicy int
+
+const (
+	// StderrSideSink copies stderr to a side writer, os.Stderr unless set with WithStderrSink
+	StderrSideSink StderrPolicy = iota
+	// StderrError fails the routine with the stderr output if the command wrote any
+	StderrError
+	// StderrMerge emits stderr lines as messages alongside stdout lines
+	StderrMerge
+	// StderrIgnore discards stderr
+	StderrIgnore
+)
+
+// ExecRoutine runs a command and emits each line of its stdout as a message. Stderr is
+// handled separately according to its StderrPolicy so diagnostics never corrupt the data
+// stream unless merged explicitly. A non-zero exit status fails the routine.
+type ExecRoutine struct {
+	name string
+	args []string
+
+	stderrPolicy StderrPolicy
+	stderrSink   io.Writer
+	maxLineSize  int
+}
+
+// defaultExecMaxLineSize is the longest output line Exec reads unless WithMaxLineSize
+// sets another limit.
+const defaultExecMaxLineSize = 1 << 20
+
+func Exec(name string, args ...string) *ExecRoutine {
+	return &ExecRoutine{
+		name:         name,
+		args:         args,
+		stderrPolicy: StderrSideSink,
+		stderrSink:   os.Stderr,
+		maxLineSize:  defaultExecMaxLineSize,
+	}
+}
+
+// WithMaxLineSize sets the longest output line, in bytes, the routine reads; a longer one
+// fails it with bufio.ErrTooLong. Defaults to 1 MiB.
+func (e *ExecRoutine) WithMaxLineSize(n int) *ExecRoutine {
+	e.maxLineSize = n
+	return e
+}
+
+func (e *ExecRoutine) WithStderr(policy StderrPolicy) *ExecRoutine {
+	e.stderrPolicy = policy
+	return e
+}
+
+// WithStderrSink copies stderr to w, implying StderrSideSink.
+func (e *ExecRoutine) WithStderrSink(w io.Writer) *ExecRoutine {
+	e.stderrPolicy = StderrSideSink
+	e.stderrSink = w
+	return e
+}
+
+func (e *ExecRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	cmd := exec.CommandContext(ctx, e.name, e.args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout of %s: %w", e.name, err)
+	}
+
+	var stderr bytes.Buffer
+	var stderrPipe io.ReadCloser
+
+	switch e.stderrPolicy {
+	case StderrSideSink:
+		cmd.Stderr = e.stderrSink
+	case StderrError:
+		cmd.Stderr = &stderr
+	case StderrMerge:
+		if stderrPipe, err = cmd.StderrPipe(); err != nil {
+			return fmt.Errorf("failed to open stderr of %s: %w", e.name, err)
+		}
+	case StderrIgnore:
+		cmd.Stderr = io.Discard
+	default:
+		return fmt.Errorf("unknown stderr policy %d", e.stderrPolicy)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", e.name, err)
+	}
+
+	var wg sync.WaitGroup
+	emitErrs := make([]error, 2)
+
+	wg.Add(1)
+	pipeline.Go(ctx, func() {
+		defer wg.Done()
+		emitErrs[0] = e.emitLines(ctx, stdout, pipe)
+	})
+
+	if stderrPipe != nil {
+		wg.Add(1)
+		pipeline.Go(ctx, func() {
+			defer wg.Done()
+			emitErrs[1] = e.emitLines(ctx, stderrPipe, pipe)
+		})
+	}
+
+	// all reads must finish before Wait closes the pipes
+	wg.Wait()
+
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if err := errors.Join(emitErrs...); err != nil {
+		return fmt.Errorf("failed to read output of %s: %w", e.name, err)
+	}
+
+	if waitErr != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("%s failed: %w: %s", e.name, waitErr, strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("%s failed: %w", e.name, waitErr)
+	}
+
+	if e.stderrPolicy == StderrError && stderr.Len() > 0 {
+		return fmt.Errorf("%s wrote to stderr: %s", e.name, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// emitLines sends every line read from reader as a message. Whatever is left unread when
+// it stops early is discarded, so the command never blocks writing to a full pipe.
+func (e *ExecRoutine) emitLines(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
+	defer func() {
+		_, _ = io.Copy(io.Discard, reader)
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, min(bufio.MaxScanTokenSize, e.maxLineSize)), e.maxLineSize)
+
+	for scanner.Scan() {
+		if err := pipe.Send(ctx, pipeline.NewMsg(pipeline.NewID(ctx), scanner.Text())); err != nil {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("line longer than %d bytes: %w", e.maxLineSize, err)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package routines_test
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bothStreams writes two lines to stdout and one to stderr.
+const bothStreams = `echo out1; echo err1 >&2; echo out2`
+
+func execLines(msgs []pipeline.Msg) []string {
+	lines := make([]string, len(msgs))
+	for i, msg := range msgs {
+		lines[i] = msg.Data.(string)
+	}
+	return lines
+}
+
+func startExec(t *testing.T, routine *routines.ExecRoutine) ([]pipeline.Msg, error) {
+	t.Helper()
+
+	pipe := pipeline.NewChanPipe()
+	close(pipe.In())
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- routine.Start(t.Context(), pipe)
+	}()
+
+	var results []pipeline.Msg
+	for msg := range pipe.Out() {
+		results = append(results, msg)
+	}
+
+	return results, <-errCh
+}
+
+func TestExecRoutine_Start(t *testing.T) {
+	t.Run("forwards stdout and copies stderr to a side sink", func(t *testing.T) {
+		var sink bytes.Buffer
+
+		results, err := startExec(t, routines.Exec("sh", "-c", bothStreams).WithStderrSink(&sink))
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"out1", "out2"}, execLines(results))
+		assert.Equal(t, "err1\n", sink.String())
+	})
+
+	t.Run("fails with stderr output under the error policy", func(t *testing.T) {
+		results, err := startExec(t, routines.Exec("sh", "-c", bothStreams).WithStderr(routines.StderrError))
+
+		assert.ErrorContains(t, err, "wrote to stderr: err1")
+		assert.Equal(t, []string{"out1", "out2"}, execLines(results))
+	})
+
+	t.Run("merges stderr lines into the stream", func(t *testing.T) {
+		results, err := startExec(t, routines.Exec("sh", "-c", bothStreams).WithStderr(routines.StderrMerge))
+
+		require.NoError(t, err)
+		lines := execLines(results)
+		sort.Strings(lines)
+		assert.Equal(t, []string{"err1", "out1", "out2"}, lines)
+	})
+
+	t.Run("ignores stderr", func(t *testing.T) {
+		results, err := startExec(t, routines.Exec("sh", "-c", bothStreams).WithStderr(routines.StderrIgnore))
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"out1", "out2"}, execLines(results))
+	})
+
+	t.Run("fails on a line over the limit without blocking the command", func(t *testing.T) {
+		// far more output than a pipe buffers follows the long line
+		script := `head -c 100 /dev/zero | tr '\0' a; echo; seq 1 100000`
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := startExec(t, routines.Exec("sh", "-c", script).WithMaxLineSize(64))
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, bufio.ErrTooLong)
+			assert.ErrorContains(t, err, "longer than 64 bytes")
+		case <-time.After(5 * time.Second):
+			t.Fatal("exec hung after a line over the limit")
+		}
+	})
+
+	t.Run("reads lines longer than the default scanner buffer", func(t *testing.T) {
+		results, err := startExec(t, routines.Exec("sh", "-c", `head -c 100000 /dev/zero | tr '\0' a; echo`))
+
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Len(t, results[0].Data, 100000)
+	})
+
+	t.Run("reports a non-zero exit with stderr", func(t *testing.T) {
+		_, err := startExec(t, routines.Exec("sh", "-c", "echo boom >&2; exit 3").WithStderr(routines.StderrError))
+
+		assert.ErrorContains(t, err, "exit status 3")
+		assert.ErrorContains(t, err, "boom")
+	})
+}
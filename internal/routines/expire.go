@@ -0,0 +1,55 @@
+package routines
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// DropExpiredRoutine forwards messages whose timestamp is at most maxAge old when they
+// reach the stage, and drops the rest. Useful after a backpressure buildup in real-time
+// pipelines, where stale messages are no longer worth processing.
+type DropExpiredRoutine struct {
+	tsFn      func(pipeline.Msg) time.Time
+	maxAge    time.Duration
+	onExpired func(pipeline.Msg)
+}
+
+// IngestedAt returns the time a source created msg, a ready-made timestamp for DropExpired.
+func IngestedAt(msg pipeline.Msg) time.Time {
+	return msg.IngestedAt
+}
+
+func DropExpired(tsFn func(pipeline.Msg) time.Time, maxAge time.Duration) *DropExpiredRoutine {
+	return &DropExpiredRoutine{tsFn: tsFn, maxAge: maxAge}
+}
+
+// OnExpired routes dropped messages to fn instead of discarding them, e.g. to count them
+// or send them to a dead letter file. fn runs on the routine goroutine.
+func (d *DropExpiredRoutine) OnExpired(fn func(pipeline.Msg)) *DropExpiredRoutine {
+	d.onExpired = fn
+	return d
+}
+
+func (d *DropExpiredRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		if age := time.Since(d.tsFn(msg)); age > d.maxAge {
+			slog.Debug("dropping expired message", "id", msg.ID, "age", age)
+
+			if d.onExpired != nil {
+				d.onExpired(msg)
+			}
+			continue
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package routines_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropExpiredRoutine_Start(t *testing.T) {
+	now := time.Now()
+	byTimestamp := func(msg pipeline.Msg) time.Time { return msg.Data.(time.Time) }
+
+	input := []pipeline.Msg{
+		{ID: "fresh-1", Data: now},
+		{ID: "stale-1", Data: now.Add(-time.Hour)},
+		{ID: "fresh-2", Data: now.Add(-time.Second)},
+		{ID: "stale-2", Data: now.Add(-2 * time.Minute)},
+	}
+
+	t.Run("forwards fresh messages and drops stale ones", func(t *testing.T) {
+		results := runRoutine(t, routines.DropExpired(byTimestamp, time.Minute), input)
+
+		assert.Equal(t, []pipeline.Msg{input[0], input[2]}, results)
+	})
+
+	t.Run("routes expired messages to the handler", func(t *testing.T) {
+		var expired []string
+		routine := routines.DropExpired(byTimestamp, time.Minute).OnExpired(func(msg pipeline.Msg) {
+			expired = append(expired, msg.ID)
+		})
+
+		results := runRoutine(t, routine, input)
+
+		assert.Len(t, results, 2)
+		assert.Equal(t, []string{"stale-1", "stale-2"}, expired)
+	})
+}
+
+func TestIngestedAt(t *testing.T) {
+	t.Run("set by sources and preserved by transforms", func(t *testing.T) {
+		before := time.Now()
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			_ = filesystem.NewLineCodec().Parse(t.Context(), strings.NewReader("1\n2"), pipe)
+		}()
+
+		var parsed []pipeline.Msg
+		for msg := range pipe.Out() {
+			parsed = append(parsed, msg)
+		}
+
+		require.Len(t, parsed, 2)
+		for _, msg := range parsed {
+			assert.False(t, msg.IngestedAt.Before(before))
+			assert.False(t, msg.IngestedAt.After(time.Now()))
+		}
+
+		doubled := runRoutine(t, routines.Transform(func(s string) string { return s + s }), parsed)
+		decoded := runRoutine(t, routines.JSONAs[int](), doubled)
+
+		require.Len(t, decoded, 2)
+		for i, msg := range decoded {
+			assert.Equal(t, parsed[i].ID, msg.ID)
+			assert.Equal(t, parsed[i].IngestedAt, msg.IngestedAt)
+		}
+		assert.Equal(t, 11, decoded[0].Data)
+	})
+
+	t.Run("drives DropExpired", func(t *testing.T) {
+		input := []pipeline.Msg{
+			pipeline.NewMsg("fresh", 1),
+			{ID: "stale", Data: 2, IngestedAt: time.Now().Add(-time.Hour)},
+		}
+
+		results := runRoutine(t, routines.DropExpired(routines.IngestedAt, time.Minute), input)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, "fresh", results[0].ID)
+	})
+}
@@ -0,0 +1,21 @@
+package routines
+
+import "time"
+
+// WithTicker makes the routine start its ticker with fn.
+func (p *PeriodicSnapshotRoutine) WithTicker(fn func(d time.Duration) (<-chan time.Time, func())) *PeriodicSnapshotRoutine {
+	p.newTicker = fn
+	return p
+}
+
+// WithTicker makes the routine start its ticker with fn.
+func (w *WindowRoutine) WithTicker(fn func(d time.Duration) (<-chan time.Time, func())) *WindowRoutine {
+	w.newTicker = fn
+	return w
+}
+
+// WithTicker makes the routine start its ticker with fn.
+func (s *StatusLineRoutine) WithTicker(fn func(d time.Duration) (<-chan time.Time, func())) *StatusLineRoutine {
+	s.newTicker = fn
+	return s
+}
@@ -0,0 +1,279 @@
+package routines
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// defaultMaxFanIn is how many runs ExternalSort merges at once unless WithMaxFanIn sets
+// another limit.
+const defaultMaxFanIn = 64
+
+// registerRunTypes registers with gob the common decoded shapes (e.g. from the JSON codec)
+// it does not know by default, once for every ExternalSortRoutine.
+var registerRunTypes sync.Once
+
+// ExternalSortRoutine sorts a stream larger than memory. It sorts chunks of chunkSize
+// messages in memory, spills each sorted run to a temporary file and k-way merges the
+// runs, so at most chunkSize messages are held at once. When there are more runs than the
+// maximum fan-in, they are first merged that many at a time into longer runs, so no more
+// files than that are open at once.
+//
+// Runs are gob encoded: message data must be gob encodable, and custom types stored in
+// Data must be registered with gob.Register.
+type ExternalSortRoutine struct {
+	less      func(a, b pipeline.Msg) bool
+	tempDir   string
+	chunkSize int
+	maxFanIn  int
+}
+
+func ExternalSort(less func(a, b pipeline.Msg) bool, tempDir string, chunkSize int) *ExternalSortRoutine {
+	return &ExternalSortRoutine{less: less, tempDir: tempDir, chunkSize: chunkSize, maxFanIn: defaultMaxFanIn}
+}
+
+// WithMaxFanIn sets how many runs are merged at once, and so how many run files are open
+// at once. Defaults to 64.
+func (s *ExternalSortRoutine) WithMaxFanIn(n int) *ExternalSortRoutine {
+	s.maxFanIn = n
+	return s
+}
+
+// Stateful marks ExternalSortRoutine as a StatefulRoutine, since sorting needs every
+// message.
+func (s *ExternalSortRoutine) Stateful() {}
+
+func (s *ExternalSortRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if s.chunkSize <= 0 {
+		return fmt.Errorf("external sort chunk size must be positive, got %d", s.chunkSize)
+	}
+
+	if s.maxFanIn < 2 {
+		return fmt.Errorf("external sort fan-in must be at least 2, got %d", s.maxFanIn)
+	}
+
+	registerRunTypes.Do(func() {
+		gob.Register(map[string]any{})
+		gob.Register([]any{})
+	})
+
+	var runs []string
+	defer func() {
+		removeRuns(runs)
+	}()
+
+	chunk := make([]pipeline.Msg, 0, s.chunkSize)
+
+	for msg := range pipe.In() {
+		chunk = append(chunk, msg)
+		if len(chunk) < s.chunkSize {
+			continue
+		}
+
+		run, err := s.spill(chunk)
+		if err != nil {
+			// keep the upstream stage from blocking on a routine that stopped reading
+			for range pipe.In() {
+			}
+			return err
+		}
+
+		runs = append(runs, run)
+		chunk = chunk[:0]
+	}
+
+	// everything fit in memory, no merge needed
+	if len(runs) == 0 {
+		sort.SliceStable(chunk, func(i, j int) bool { return s.less(chunk[i], chunk[j]) })
+
+		for _, msg := range chunk {
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	if len(chunk) > 0 {
+		run, err := s.spill(chunk)
+		if err != nil {
+			return err
+		}
+
+		runs = append(runs, run)
+	}
+
+	runs, err := s.reduceRuns(ctx, runs)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	return s.merge(runs, func(msg pipeline.Msg) bool {
+		return pipe.Send(ctx, msg) == nil
+	})
+}
+
+// removeRuns deletes the run files at paths.
+func removeRuns(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			slog.Error("failed to remove sort run", "path", path, "error", err)
+		}
+	}
+}
+
+// reduceRuns merges consecutive groups of maxFanIn runs into longer runs until at most
+// maxFanIn are left, returning the runs still on disk. Merging neighbours keeps equal
+// messages in input order.
+func (s *ExternalSortRoutine) reduceRuns(ctx context.Context, runs []string) ([]string, error) {
+	for len(runs) > s.maxFanIn {
+		next := make([]string, 0, (len(runs)+s.maxFanIn-1)/s.maxFanIn)
+
+		for start := 0; start < len(runs); start += s.maxFanIn {
+			group := runs[start:min(start+s.maxFanIn, len(runs))]
+			if len(group) == 1 {
+				next = append(next, group[0])
+				continue
+			}
+
+			run, err := s.mergeToRun(ctx, group)
+			if err != nil {
+				return append(next, runs[start:]...), err
+			}
+
+			removeRuns(group)
+			next = append(next, run)
+		}
+
+		slog.Debug("merged sort runs", "from", len(runs), "to", len(next))
+
+		runs = next
+	}
+
+	return runs, nil
+}
+
+// mergeToRun merges runs into a new temporary run file, returning its path.
+func (s *ExternalSortRoutine) mergeToRun(ctx context.Context, runs []string) (string, error) {
+	return s.writeRun(func(encoder *gob.Encoder) error {
+		var encodeErr error
+
+		err := s.merge(runs, func(msg pipeline.Msg) bool {
+			if encodeErr = ctx.Err(); encodeErr != nil {
+				return false
+			}
+
+			if err := encoder.Encode(msg); err != nil {
+				encodeErr = fmt.Errorf("failed to encode message to sort run: %w", err)
+				return false
+			}
+
+			return true
+		})
+
+		return cmp.Or(err, encodeErr)
+	})
+}
+
+// spill sorts chunk and writes it to a new temporary run file, returning its path.
+func (s *ExternalSortRoutine) spill(chunk []pipeline.Msg) (string, error) {
+	sort.SliceStable(chunk, func(i, j int) bool { return s.less(chunk[i], chunk[j]) })
+
+	path, err := s.writeRun(func(encoder *gob.Encoder) error {
+		for _, msg := range chunk {
+			if err := encoder.Encode(msg); err != nil {
+				return fmt.Errorf("failed to encode message to sort run: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	slog.Debug("spilled sort run", "path", path, "size", len(chunk))
+
+	return path, nil
+}
+
+// writeRun creates a temporary run file and fills it with write, returning its path. The
+// file is removed if write fails.
+func (s *ExternalSortRoutine) writeRun(write func(encoder *gob.Encoder) error) (path string, err error) {
+	file, err := os.CreateTemp(s.tempDir, "goscript-sort-*.run")
+	if err != nil {
+		return "", fmt.Errorf("failed to create sort run: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close sort run: %w", closeErr)
+		}
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+
+	writer := bufio.NewWriter(file)
+
+	if err := write(gob.NewEncoder(writer)); err != nil {
+		return "", err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return "", fmt.Errorf("failed to write sort run: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+// merge emits the globally smallest head of all runs until every run is exhausted or emit
+// reports false.
+func (s *ExternalSortRoutine) merge(runs []string, emit func(pipeline.Msg) bool) error {
+	cursors := make([]*mergeCursor, len(runs))
+
+	for i, path := range runs {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open sort run: %w", err)
+		}
+		defer file.Close()
+
+		decoder := gob.NewDecoder(bufio.NewReader(file))
+
+		cursors[i] = &mergeCursor{
+			index: i,
+			pull: func() (pipeline.Msg, bool, error) {
+				var msg pipeline.Msg
+				if err := decoder.Decode(&msg); err != nil {
+					if errors.Is(err, io.EOF) {
+						return pipeline.Msg{}, false, nil
+					}
+
+					return pipeline.Msg{}, false, fmt.Errorf("failed to decode sort run: %w", err)
+				}
+
+				return msg, true, nil
+			},
+		}
+	}
+
+	return mergeCursors(cursors, s.less, emit)
+}
@@ -0,0 +1,135 @@
+package routines_test
+
+import (
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func byIntData(a, b pipeline.Msg) bool {
+	return a.Data.(int) < b.Data.(int)
+}
+
+func TestExternalSortRoutine_Start(t *testing.T) {
+	t.Run("sorts more records than chunk size by spilling", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		values := rand.Perm(100)
+		input := make([]pipeline.Msg, len(values))
+		for i, v := range values {
+			input[i] = pipeline.Msg{ID: "", Data: v}
+		}
+
+		results := runRoutine(t, routines.ExternalSort(byIntData, tempDir, 7), input)
+
+		require.Len(t, results, 100)
+		for i, msg := range results {
+			assert.Equal(t, i, msg.Data)
+		}
+
+		entries, err := os.ReadDir(tempDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "temporary runs should be cleaned up")
+	})
+
+	t.Run("merges runs a few at a time beyond the fan-in", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		values := rand.Perm(50)
+		input := make([]pipeline.Msg, len(values))
+		for i, v := range values {
+			input[i] = pipeline.Msg{ID: strconv.Itoa(i), Data: v % 10}
+		}
+
+		results := runRoutine(t, routines.ExternalSort(byIntData, tempDir, 2).WithMaxFanIn(3), input)
+
+		require.Len(t, results, 50)
+		assert.True(t, sort.SliceIsSorted(results, func(i, j int) bool { return byIntData(results[i], results[j]) }))
+
+		// equal records keep their input order through every pass
+		for i := 1; i < len(results); i++ {
+			if results[i].Data == results[i-1].Data {
+				prev, _ := strconv.Atoi(results[i-1].ID)
+				cur, _ := strconv.Atoi(results[i].ID)
+				assert.Less(t, prev, cur)
+			}
+		}
+
+		entries, err := os.ReadDir(tempDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "temporary runs should be cleaned up")
+	})
+
+	t.Run("rejects a fan-in below two", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.ExternalSort(byIntData, t.TempDir(), 2).WithMaxFanIn(1).Start(t.Context(), pipe)
+		assert.ErrorContains(t, err, "fan-in must be at least 2")
+	})
+
+	t.Run("keeps equal records in input order across runs", func(t *testing.T) {
+		input := []pipeline.Msg{
+			{ID: "a", Data: 2}, {ID: "b", Data: 1}, {ID: "c", Data: 2},
+			{ID: "d", Data: 1}, {ID: "e", Data: 2}, {ID: "f", Data: 1},
+		}
+
+		results := runRoutine(t, routines.ExternalSort(byIntData, t.TempDir(), 2), input)
+
+		ids := make([]string, len(results))
+		for i, msg := range results {
+			ids[i] = msg.ID
+		}
+		assert.Equal(t, []string{"b", "d", "f", "a", "c", "e"}, ids)
+	})
+
+	t.Run("sorts in memory when input fits in one chunk", func(t *testing.T) {
+		tempDir := t.TempDir()
+		input := generateTestMsgs(1, 5)
+		sort.Slice(input, func(i, j int) bool { return !byIntData(input[i], input[j]) })
+
+		results := runRoutine(t, routines.ExternalSort(byIntData, tempDir, 10), input)
+
+		assert.Equal(t, generateTestMsgs(1, 5), results)
+	})
+
+	t.Run("cleans up runs on error", func(t *testing.T) {
+		tempDir := t.TempDir()
+		input := []pipeline.Msg{{Data: 2}, {Data: 1}, {Data: 3}, {Data: 0}}
+		less := func(a, b pipeline.Msg) bool { return false }
+
+		// channels cannot be gob encoded, so the third spill fails with input left unread
+		input = append(input, pipeline.Msg{Data: make(chan int)}, pipeline.Msg{Data: make(chan int)}, pipeline.Msg{Data: 4})
+
+		pipe := pipeline.NewChanPipe()
+		produced := make(chan struct{})
+		go func() {
+			defer close(produced)
+			for _, msg := range input {
+				pipe.In() <- msg
+			}
+			close(pipe.In())
+		}()
+
+		err := routines.ExternalSort(less, tempDir, 2).Start(t.Context(), pipe)
+		assert.Error(t, err)
+
+		select {
+		case <-produced:
+		case <-time.After(5 * time.Second):
+			t.Fatal("upstream blocked after the sort failed")
+		}
+
+		entries, readErr := os.ReadDir(tempDir)
+		require.NoError(t, readErr)
+		assert.Empty(t, entries)
+	})
+}
@@ -2,19 +2,30 @@ package filesystem
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
-	"github.com/google/uuid"
 )
 
 // BlobCodec returns the entire file content as a single message
 type BlobCodec struct {
 	// AsString when true, returns content as string, otherwise as []byte
 	AsString bool
+	// MaxSize when positive, is the largest content size in bytes Parse accepts
+	MaxSize int64
+	// Numbers controls how non-text values are rendered
+	Numbers NumberFormat
+	// ChunkSize when positive, makes Parse emit the content as []byte messages of at most
+	// ChunkSize bytes instead of a single message
+	ChunkSize int
 }
 
+// ErrBlobTooLarge is returned when blob content exceeds the codec's MaxSize. It wraps
+// ErrMaxBufferExceeded.
+var ErrBlobTooLarge = fmt.Errorf("blob too large: %w", ErrMaxBufferExceeded)
+
 // Ensure BlobCodec implements all interfaces
 var _ ReadCodec = (*BlobCodec)(nil)
 var _ WriteCodec = (*BlobCodec)(nil)
@@ -35,14 +46,52 @@ func (c *BlobCodec) AsStrings() *BlobCodec {
 	return c
 }
 
+// WithMaxSize makes Parse fail with ErrBlobTooLarge instead of loading content larger
+// than n bytes into memory
+func (c *BlobCodec) WithMaxSize(n int64) *BlobCodec {
+	c.MaxSize = n
+	return c
+}
+
+// Chunked reads the content in size-byte windows, emitting each as a []byte message, so
+// large binary files can be hashed or compressed in bounded memory. Chunks are bytes even
+// when AsString is set, since a window may split a multi-byte character. Only the last
+// chunk may be shorter than size.
+func (c *BlobCodec) Chunked(size int) *BlobCodec {
+	c.ChunkSize = size
+	return c
+}
+
+// WithNumberFormat sets how numbers and other non-text values are rendered
+func (c *BlobCodec) WithNumberFormat(format NumberFormat) *BlobCodec {
+	c.Numbers = format
+	return c
+}
+
 func (c *BlobCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
 	defer pipe.Close()
 
-	data, err := io.ReadAll(reader)
+	if c.MaxSize > 0 {
+		// read one byte past the limit to detect oversized content without loading it all
+		reader = io.LimitReader(reader, c.MaxSize+1)
+	}
+
+	if c.ChunkSize > 0 {
+		return c.parseChunks(ctx, reader, pipe)
+	}
+
+	data, err := io.ReadAll(contextReader{ctx: ctx, reader: reader})
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
 		return err
 	}
 
+	if c.MaxSize > 0 && int64(len(data)) > c.MaxSize {
+		return fmt.Errorf("%w: content is larger than %d bytes", ErrBlobTooLarge, c.MaxSize)
+	}
+
 	var msgData any
 	if c.AsString {
 		msgData = string(data)
@@ -50,20 +99,64 @@ func (c *BlobCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.P
 		msgData = data
 	}
 
-	msg := pipeline.Msg{
-		ID:   uuid.NewString(),
-		Data: msgData,
-	}
+	msg := pipeline.NewMsg(pipeline.NewID(ctx), msgData)
 
-	select {
-	case pipe.Out() <- msg:
-	case <-ctx.Done():
+	if err := pipe.Send(ctx, msg); err != nil {
 		return nil
 	}
 
 	return nil
 }
 
+// parseChunks emits the content of reader in ChunkSize windows, checking ctx between reads.
+func (c *BlobCodec) parseChunks(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
+	var total int64
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		chunk := make([]byte, c.ChunkSize)
+
+		n, err := io.ReadFull(reader, chunk)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+
+		total += int64(n)
+		if c.MaxSize > 0 && total > c.MaxSize {
+			return fmt.Errorf("%w: content is larger than %d bytes", ErrBlobTooLarge, c.MaxSize)
+		}
+
+		if sendErr := pipe.Send(ctx, pipeline.NewMsg(pipeline.NewID(ctx), chunk[:n])); sendErr != nil {
+			return nil
+		}
+
+		// a short read means the content ended within this chunk
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// contextReader stops a long read between chunks once ctx is done.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
+
 // Encode implements WriteCodec interface for BlobCodec
 func (c *BlobCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error {
 	switch v := msg.Data.(type) {
@@ -77,7 +170,7 @@ func (c *BlobCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writ
 		}
 	default:
 		// Convert other types to string representation
-		if _, err := writer.Write([]byte(fmt.Sprintf("%v", v))); err != nil {
+		if _, err := writer.Write([]byte(c.Numbers.Format(v))); err != nil {
 			return err
 		}
 	}
@@ -3,6 +3,8 @@ package filesystem_test
 import (
 	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -10,6 +12,7 @@ import (
 	"github.com/caiorcferreira/goscript/internal/pipeline"
 	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBlobCodec_Parse(t *testing.T) {
@@ -134,6 +137,104 @@ func TestBlobCodec_Parse(t *testing.T) {
 	})
 }
 
+func TestBlobCodec_MaxSize(t *testing.T) {
+	parse := func(codec *filesystem.BlobCodec, content string) ([]string, error) {
+		pipe := pipeline.NewChanPipe()
+
+		var results []string
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			for msg := range pipe.Out() {
+				results = append(results, msg.Data.(string))
+			}
+		}()
+
+		err := codec.Parse(context.Background(), strings.NewReader(content), pipe)
+		wg.Wait()
+
+		return results, err
+	}
+
+	t.Run("reads content under and at the limit", func(t *testing.T) {
+		for _, content := range []string{"small", "exactly10!"} {
+			results, err := parse(filesystem.NewBlobCodec().WithMaxSize(10), content)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{content}, results)
+		}
+	})
+
+	t.Run("errors on content over the limit", func(t *testing.T) {
+		results, err := parse(filesystem.NewBlobCodec().WithMaxSize(10), strings.Repeat("x", 1<<20))
+
+		assert.ErrorIs(t, err, filesystem.ErrBlobTooLarge)
+		assert.ErrorIs(t, err, filesystem.ErrMaxBufferExceeded)
+		assert.ErrorContains(t, err, "larger than 10 bytes")
+		assert.Empty(t, results)
+	})
+}
+
+func TestBlobCodec_Chunked(t *testing.T) {
+	readChunks := func(t *testing.T, codec *filesystem.BlobCodec, content []byte) ([][]byte, error) {
+		path := filepath.Join(t.TempDir(), "data.bin")
+		require.NoError(t, os.WriteFile(path, content, 0644))
+
+		pipe := pipeline.NewChanPipe()
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- filesystem.File(path).Read().WithCodec(codec).Start(context.Background(), pipe)
+		}()
+
+		var chunks [][]byte
+		for msg := range pipe.Out() {
+			chunks = append(chunks, msg.Data.([]byte))
+		}
+
+		return chunks, <-errCh
+	}
+
+	content := make([]byte, 10_500)
+	for i := range content {
+		content[i] = byte(i * 31)
+	}
+
+	t.Run("splits a large file into fixed-size chunks", func(t *testing.T) {
+		chunks, err := readChunks(t, filesystem.NewBlobCodec().Chunked(1000), content)
+		require.NoError(t, err)
+
+		require.Len(t, chunks, 11)
+		for _, chunk := range chunks[:10] {
+			assert.Len(t, chunk, 1000)
+		}
+		assert.Len(t, chunks[10], 500)
+		assert.Equal(t, content, bytes.Join(chunks, nil))
+	})
+
+	t.Run("exact multiple has no empty trailing chunk", func(t *testing.T) {
+		chunks, err := readChunks(t, filesystem.NewBlobCodec().Chunked(500), content)
+		require.NoError(t, err)
+
+		assert.Len(t, chunks, 21)
+		assert.Equal(t, content, bytes.Join(chunks, nil))
+	})
+
+	t.Run("empty file emits nothing", func(t *testing.T) {
+		chunks, err := readChunks(t, filesystem.NewBlobCodec().Chunked(500), nil)
+		require.NoError(t, err)
+
+		assert.Empty(t, chunks)
+	})
+
+	t.Run("honors max size", func(t *testing.T) {
+		_, err := readChunks(t, filesystem.NewBlobCodec().Chunked(1000).WithMaxSize(4000), content)
+
+		assert.ErrorIs(t, err, filesystem.ErrBlobTooLarge)
+	})
+}
+
 func TestBlobCodec_Encode(t *testing.T) {
 	t.Run("encodes string messages", func(t *testing.T) {
 		codec := filesystem.NewBlobCodec()
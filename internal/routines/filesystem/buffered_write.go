@@ -0,0 +1,140 @@
+package filesystem
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/template"
+)
+
+// defaultBufferSize is the write buffer of each file unless WithBufferSize is set.
+const defaultBufferSize = 4096
+
+type bufferedFile struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// writeBuffered keeps an open, buffered file per rendered path and flushes all of them
+// every flush interval, if set, and once the input is exhausted. Past the open file cap
+// the least recently written file is flushed and closed, to be reopened when needed.
+func (w *WriteFileRoutine) writeBuffered(ctx context.Context, pipe pipeline.Pipe) (err error) {
+	files := newOpenFiles(cmp.Or(w.maxOpenFiles, defaultMaxOpenFiles()), cmp.Or(w.bufferSize, defaultBufferSize), w.closeFile)
+
+	defer func() {
+		err = errors.Join(err, files.closeAll())
+	}()
+
+	// a nil channel never ticks, leaving flushes to full buffers and the end of input
+	var ticks <-chan time.Time
+	if w.flushInterval > 0 {
+		ticker := time.NewTicker(w.flushInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+			files.each(func(bf *bufferedFile) {
+				if err := bf.writer.Flush(); err != nil {
+					slog.Error("failed to flush file", "path", bf.path, "error", err)
+				}
+			})
+		case msg, ok := <-pipe.In():
+			if !ok {
+				return nil
+			}
+
+			filePath, err := template.RenderAs[string](w.renderer, w.path, msg.Data)
+			if err != nil {
+				if err := w.writeFailed(msg, w.path, fmt.Errorf("failed to render file path: %w", err)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			bf, err := files.get(filePath)
+			if err != nil {
+				return err
+			}
+
+			if err := w.writeCodec.Encode(ctx, msg, bf.writer); err != nil {
+				if err := w.writeFailed(msg, filePath, fmt.Errorf("%w message: %w", ErrCodecEncode, err)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			slog.Debug("message buffered for file", "path", filePath)
+		}
+	}
+}
+
+// streamBuffer buffers the writes of a stream codec and may be flushed from another
+// goroutine while the codec writes to it.
+type streamBuffer struct {
+	mu     sync.Mutex
+	writer *bufio.Writer
+}
+
+func newStreamBuffer(writer io.Writer, size int) *streamBuffer {
+	return &streamBuffer{writer: bufio.NewWriterSize(writer, size)}
+}
+
+func (s *streamBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writer.Write(p)
+}
+
+// Flush writes the buffered data to the underlying writer.
+func (s *streamBuffer) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writer.Flush()
+}
+
+// flushEvery flushes the buffer every interval until ctx is done or the returned stop is
+// called, which waits for any flush in progress.
+func (s *streamBuffer) flushEvery(ctx context.Context, interval time.Duration, path string) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+
+	pipeline.Go(ctx, func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Flush(); err != nil {
+					slog.Error("failed to flush file", "path", path, "error", err)
+				}
+			}
+		}
+	})
+
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
@@ -2,9 +2,12 @@ package filesystem
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
 )
@@ -23,33 +26,130 @@ type WriteCodec interface {
 	Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error
 }
 
-var extensionToCodec = map[string]any{
-	".json":  NewJSONCodec(),
-	".jsonl": NewJSONCodec().WithJSONLinesMode(),
-	".csv":   NewCSVCodec(),
-	".txt":   NewLineCodec(),
+// StreamWriteCodec is implemented by write codecs that need the whole message stream,
+// e.g. to wrap every message in a single JSON array. File writers hand such codecs all
+// messages through one file instead of calling Encode per message.
+type StreamWriteCodec interface {
+	// EncodeStream writes every message received from msgs until it is closed or ctx is done
+	EncodeStream(ctx context.Context, msgs <-chan pipeline.Msg, writer io.Writer) error
 }
 
-func buildReadCodec(path string) ReadCodec {
-	ext := filepath.Ext(path)
-	ext = strings.ToLower(ext)
+// flusher is implemented by writers buffering their own output, such as a stream flushed
+// every interval. Stream codecs hand each record to them as soon as it is encoded instead
+// of holding it back in a buffer of their own, so the writer's flushes reach the file.
+type flusher interface {
+	Flush() error
+}
 
-	codec, found := extensionToCodec[ext]
-	if !found {
-		return NewLineCodec()
+// parseInto runs codec over reader on its own sub-pipe and forwards every message to pipe,
+// leaving pipe open. The sub-pipe buffers up to bufferSize messages, letting the codec read
+// ahead of a slower consumer.
+func parseInto(ctx context.Context, codec ReadCodec, reader io.Reader, pipe pipeline.Pipe, bufferSize int) error {
+	subPipe := pipeline.NewChanPipe()
+	if bufferSize > 0 {
+		subPipe.SetOutChan(make(chan pipeline.Msg, bufferSize))
 	}
 
-	return codec.(ReadCodec)
+	errCh := make(chan error, 1)
+	pipeline.Go(ctx, func() {
+		errCh <- Parse(ctx, codec, reader, subPipe)
+	})
+
+	for msg := range subPipe.Out() {
+		if err := pipe.Send(ctx, msg); err != nil {
+			return <-errCh
+		}
+	}
+
+	return <-errCh
 }
 
-func buildWriteCodec(path string) WriteCodec {
-	ext := filepath.Ext(path)
+// ErrRecordPanic is returned when a codec panics while parsing its input, either on a
+// single record or outside of any record.
+var ErrRecordPanic = errors.New("codec panicked while parsing")
+
+// Parse runs codec.Parse and converts a panic into an error wrapping ErrRecordPanic, so one
+// pathological record cannot crash the whole process whatever the codec. Codecs that
+// decode records one by one recover per record first; this is the outer safety net. The pipe is
+// closed in case the codec had not done so yet. Routines parse through it rather than
+// calling the codec directly.
+func Parse(ctx context.Context, codec ReadCodec, reader io.Reader, pipe pipeline.Pipe) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pipe.Close()
+			err = fmt.Errorf("%w: %v", ErrRecordPanic, r)
+		}
+	}()
+
+	return codec.Parse(ctx, reader, pipe)
+}
+
+type codecEntry struct {
+	read  ReadCodec
+	write WriteCodec
+}
+
+var (
+	codecRegistryMu  sync.RWMutex
+	extensionToCodec = map[string]codecEntry{
+		".json":  {read: NewJSONCodec(), write: NewJSONCodec()},
+		".jsonl": {read: NewJSONCodec().WithJSONLinesMode(), write: NewJSONCodec().WithJSONLinesMode()},
+		".csv":   {read: NewCSVCodec(), write: NewCSVCodec()},
+		".txt":   {read: defaultLineCodec(), write: defaultLineCodec()},
+	}
+)
+
+// RegisterCodec sets the codecs used for files with the given extension, overriding any
+// previous registration. A nil codec makes that direction fall back to the line codec.
+// It is safe to call concurrently with file routines being built.
+//
+// Example:
+//
+//	filesystem.RegisterCodec(".tab", filesystem.NewCSVCodec().WithSeparator('\t'), filesystem.NewCSVCodec().WithSeparator('\t'))
+func RegisterCodec(ext string, read ReadCodec, write WriteCodec) {
+	codecRegistryMu.Lock()
+	defer codecRegistryMu.Unlock()
+
+	extensionToCodec[normalizeExtension(ext)] = codecEntry{read: read, write: write}
+}
+
+func normalizeExtension(ext string) string {
 	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	return ext
+}
+
+func lookupCodec(path string) codecEntry {
+	codecRegistryMu.RLock()
+	defer codecRegistryMu.RUnlock()
+
+	return extensionToCodec[strings.ToLower(filepath.Ext(path))]
+}
 
-	codec, found := extensionToCodec[ext]
-	if !found {
-		return NewLineCodec()
+// defaultLineCodec is the codec of text files and of extensions without a registered one.
+// It keeps line endings, so a file read and written with the default codecs, as by
+// Script.FileIn and Script.FileOut, is reproduced byte for byte.
+func defaultLineCodec() *LineCodec {
+	return NewLineCodec().WithLineEndings()
+}
+
+func buildReadCodec(path string) ReadCodec {
+	codec := lookupCodec(path)
+	if codec.read == nil {
+		return defaultLineCodec()
+	}
+
+	return codec.read
+}
+
+func buildWriteCodec(path string) WriteCodec {
+	codec := lookupCodec(path)
+	if codec.write == nil {
+		return defaultLineCodec()
 	}
 
-	return codec.(WriteCodec)
+	return codec.write
 }
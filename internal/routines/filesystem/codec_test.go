@@ -0,0 +1,88 @@
+package filesystem_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixCodec reads the whole input as one message and marks everything it reads or writes.
+type prefixCodec struct{}
+
+func (prefixCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	pipe.Out() <- pipeline.Msg{Data: "custom:" + string(data)}
+	return nil
+}
+
+func (prefixCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error {
+	_, err := io.WriteString(writer, "custom:"+msg.Data.(string))
+	return err
+}
+
+func TestRegisterCodec(t *testing.T) {
+	filesystem.RegisterCodec("CUSTOM", prefixCodec{}, prefixCodec{})
+
+	t.Run("reads registered extension with custom codec", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "x.custom")
+		require.NoError(t, os.WriteFile(path, []byte("a\nb"), 0644))
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			assert.NoError(t, filesystem.File(path).Read().Start(context.Background(), pipe))
+		}()
+
+		var results []any
+		for msg := range pipe.Out() {
+			results = append(results, msg.Data)
+		}
+
+		assert.Equal(t, []any{"custom:a\nb"}, results)
+	})
+
+	t.Run("writes registered extension with custom codec", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "x.Custom")
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			pipe.In() <- pipeline.Msg{Data: "value"}
+			close(pipe.In())
+		}()
+
+		require.NoError(t, filesystem.File(path).Write().Start(context.Background(), pipe))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "custom:value", string(content))
+	})
+
+	t.Run("allows concurrent registration and lookup", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				filesystem.RegisterCodec(".concurrent", filesystem.NewLineCodec(), nil)
+			}()
+			go func() {
+				defer wg.Done()
+				_ = filesystem.File("data.concurrent").Read()
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -3,23 +3,42 @@ package filesystem
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
-	"github.com/google/uuid"
 )
 
 // CSVCodec parses CSV file content
 type CSVCodec struct {
-	Separator rune
-	Comment   rune
+	Separator rune `describe:"rune"`
+	Comment   rune `describe:"rune"`
 	Headers   []string
+	// WriteHeader when true, writes Headers as the first row of a streamed file
+	WriteHeader bool
+	// CommentOnlyLeading when true, only treats comment lines before the first record as
+	// comments, so data rows may start with the comment character
+	CommentOnlyLeading bool
+	// Numbers controls how non-text fields are rendered
+	Numbers NumberFormat
+	// HeaderMode when true, reads the first record as the header and emits every following
+	// record as a map[string]any keyed by it
+	HeaderMode bool
+	// EmitHeaderMessage when true in header mode, also emits the header as a []string
+	// message, marked with the CSVHeaderMeta meta entry, before the rows
+	EmitHeaderMessage bool
 }
 
+// CSVHeaderMeta is the meta entry set to true on the header message emitted in header mode
+// with EmitHeaderMessage, telling it apart from data rows.
+const CSVHeaderMeta = "csv.header"
+
 // Ensure CSVCodec implements all interfaces
 var _ ReadCodec = (*CSVCodec)(nil)
 var _ WriteCodec = (*CSVCodec)(nil)
+var _ StreamWriteCodec = (*CSVCodec)(nil)
 
 func NewCSVCodec() *CSVCodec {
 	return &CSVCodec{
@@ -28,6 +47,20 @@ func NewCSVCodec() *CSVCodec {
 	}
 }
 
+// WithNumberFormat sets how numbers and other non-text fields are rendered
+func (c *CSVCodec) WithNumberFormat(format NumberFormat) *CSVCodec {
+	c.Numbers = format
+	return c
+}
+
+// WithHeaderRow sets the columns written for map rows and writes them once as the first
+// row of the file
+func (c *CSVCodec) WithHeaderRow(headers ...string) *CSVCodec {
+	c.Headers = headers
+	c.WriteHeader = true
+	return c
+}
+
 func (c *CSVCodec) WithSeparator(sep rune) *CSVCodec {
 	c.Separator = sep
 	return c
@@ -38,36 +71,166 @@ func (c *CSVCodec) WithComment(comment rune) *CSVCodec {
 	return c
 }
 
+// WithHeaderMode reads the first record as the header and emits the other records as
+// maps from column name to value instead of []string rows
+func (c *CSVCodec) WithHeaderMode() *CSVCodec {
+	c.HeaderMode = true
+	return c
+}
+
+// WithEmitHeaderMessage enables header mode and emits the header itself as a []string
+// message before the rows, e.g. to write it elsewhere. The message has the CSVHeaderMeta
+// meta entry set to true. By default the header is consumed silently.
+func (c *CSVCodec) WithEmitHeaderMessage() *CSVCodec {
+	c.HeaderMode = true
+	c.EmitHeaderMessage = true
+	return c
+}
+
+// WithCommentOnlyLeading stops treating lines as comments once the first record (usually
+// the header) is read, e.g. for files with a commented preamble and IDs starting with '#'
+func (c *CSVCodec) WithCommentOnlyLeading() *CSVCodec {
+	c.CommentOnlyLeading = true
+	return c
+}
+
 func (c *CSVCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
 	defer pipe.Close()
 
-	csvReader := csv.NewReader(reader)
+	recorder := newLineRecorder(reader)
+
+	csvReader := csv.NewReader(recorder)
 	csvReader.Comma = c.Separator
 	csvReader.Comment = c.Comment
 
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return err
-	}
+	var header []string
 
-	for _, record := range records {
+	for recordNumber := 1; ; recordNumber++ {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
-			msg := pipeline.Msg{
-				ID:   uuid.NewString(),
-				Data: record,
+		}
+
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return newCSVParseError(recordNumber, recorder, err)
+		}
+
+		line, _ := csvReader.FieldPos(0)
+		recorder.forgetBefore(line)
+
+		// the reader checks Comment per record, so clearing it ends the comment region
+		if c.CommentOnlyLeading {
+			csvReader.Comment = 0
+		}
+
+		var msg pipeline.Msg
+
+		switch {
+		case !c.HeaderMode:
+			msg = pipeline.NewMsg(pipeline.NewID(ctx), record)
+		case header == nil:
+			header = record
+			if !c.EmitHeaderMessage {
+				continue
 			}
-			select {
-			case pipe.Out() <- msg:
-			case <-ctx.Done():
-				return nil
+
+			msg = pipeline.NewMsg(pipeline.NewID(ctx), record).WithMeta(CSVHeaderMeta, true)
+		default:
+			row := make(map[string]any, len(header))
+			for i, column := range header {
+				row[column] = record[i]
 			}
+
+			msg = pipeline.NewMsg(pipeline.NewID(ctx), row)
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
 		}
 	}
+}
 
-	return nil
+// CSVParseError reports a malformed CSV record together with its position in the input
+type CSVParseError struct {
+	// Record is the 1-based number of the record that failed to parse
+	Record int
+	// Line is the 1-based input line where the error was detected
+	Line int
+	// Raw is the raw text of the offending line
+	Raw string
+	Err error
+}
+
+func (e *CSVParseError) Error() string {
+	return fmt.Sprintf("csv record %d (line %d): %v: %q", e.Record, e.Line, e.Err, e.Raw)
+}
+
+func (e *CSVParseError) Unwrap() error {
+	return e.Err
+}
+
+func newCSVParseError(record int, recorder *lineRecorder, err error) error {
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) {
+		return fmt.Errorf("csv record %d: %w", record, err)
+	}
+
+	return &CSVParseError{
+		Record: record,
+		Line:   parseErr.Line,
+		Raw:    recorder.line(parseErr.Line),
+		Err:    err,
+	}
+}
+
+// lineRecorder remembers the raw lines read through it, so parse errors can quote the
+// offending line. Lines of records already parsed are forgotten to keep memory bounded.
+type lineRecorder struct {
+	reader  io.Reader
+	lines   map[int]string
+	partial []byte
+	current int
+	oldest  int
+}
+
+func newLineRecorder(reader io.Reader) *lineRecorder {
+	return &lineRecorder{reader: reader, lines: make(map[int]string), current: 1, oldest: 1}
+}
+
+func (l *lineRecorder) Read(p []byte) (int, error) {
+	n, err := l.reader.Read(p)
+
+	for _, b := range p[:n] {
+		if b != '\n' {
+			l.partial = append(l.partial, b)
+			continue
+		}
+
+		l.lines[l.current] = strings.TrimSuffix(string(l.partial), "\r")
+		l.partial = l.partial[:0]
+		l.current++
+	}
+
+	return n, err
+}
+
+func (l *lineRecorder) line(number int) string {
+	if number == l.current {
+		return string(l.partial)
+	}
+
+	return l.lines[number]
+}
+
+func (l *lineRecorder) forgetBefore(number int) {
+	for ; l.oldest < number; l.oldest++ {
+		delete(l.lines, l.oldest)
+	}
 }
 
 func (c *CSVCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error {
@@ -83,6 +246,44 @@ func (c *CSVCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Write
 	return nil
 }
 
+// EncodeStream implements StreamWriteCodec interface for CSVCodec, writing every row through
+// a single CSV writer, preceded by the header row when WriteHeader is set
+func (c *CSVCodec) EncodeStream(ctx context.Context, msgs <-chan pipeline.Msg, writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Comma = c.Separator
+	_, flushRows := writer.(flusher)
+
+	if c.WriteHeader && len(c.Headers) > 0 {
+		if err := csvWriter.Write(c.Headers); err != nil {
+			return fmt.Errorf("failed to write csv header: %w", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			csvWriter.Flush()
+			return csvWriter.Error()
+		case msg, ok := <-msgs:
+			if !ok {
+				csvWriter.Flush()
+				return csvWriter.Error()
+			}
+
+			if err := csvWriter.Write(c.castDataToCSVRow(msg.Data)); err != nil {
+				return fmt.Errorf("failed to write csv row: %w", err)
+			}
+
+			if flushRows {
+				csvWriter.Flush()
+				if err := csvWriter.Error(); err != nil {
+					return fmt.Errorf("failed to write csv row: %w", err)
+				}
+			}
+		}
+	}
+}
+
 func (c *CSVCodec) castDataToCSVRow(data any) []string {
 	switch v := data.(type) {
 	case []string:
@@ -93,7 +294,7 @@ func (c *CSVCodec) castDataToCSVRow(data any) []string {
 		values := make([]string, 0, len(c.Headers))
 		for _, header := range c.Headers {
 			if val, ok := v[header]; ok {
-				values = append(values, fmt.Sprintf("%v", val))
+				values = append(values, c.Numbers.Format(val))
 			} else {
 				values = append(values, "")
 			}
@@ -103,12 +304,12 @@ func (c *CSVCodec) castDataToCSVRow(data any) []string {
 	case []any:
 		values := make([]string, len(v))
 		for i, item := range v {
-			values[i] = fmt.Sprintf("%v", item)
+			values[i] = c.Numbers.Format(item)
 		}
 
 		return values
 	default:
 		// Convert to string and write as single field
-		return []string{fmt.Sprintf("%v", v)}
+		return []string{c.Numbers.Format(v)}
 	}
 }
@@ -3,6 +3,9 @@ package filesystem_test
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -187,6 +190,153 @@ John,30,"extra quote`
 	})
 }
 
+func TestCSVCodec_ParseErrors(t *testing.T) {
+	parse := func(content string) ([][]string, error) {
+		pipe := pipeline.NewChanPipe()
+
+		var results [][]string
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			for msg := range pipe.Out() {
+				results = append(results, msg.Data.([]string))
+			}
+		}()
+
+		err := filesystem.NewCSVCodec().Parse(context.Background(), strings.NewReader(content), pipe)
+		wg.Wait()
+
+		return results, err
+	}
+
+	t.Run("reports record number and raw line for a bare quote", func(t *testing.T) {
+		results, err := parse("name,age\n# comment\nJohn,30\nJa\"ne,25\nBob,40")
+
+		var parseErr *filesystem.CSVParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, 3, parseErr.Record)
+		assert.Equal(t, 4, parseErr.Line)
+		assert.Equal(t, `Ja"ne,25`, parseErr.Raw)
+		assert.ErrorIs(t, err, csv.ErrBareQuote)
+		assert.Contains(t, err.Error(), "csv record 3")
+
+		// records before the malformed one are streamed
+		assert.Equal(t, [][]string{{"name", "age"}, {"John", "30"}}, results)
+	})
+
+	t.Run("reports record number for a wrong field count", func(t *testing.T) {
+		_, err := parse("a,b\nc,d\ne,f,g\n")
+
+		var parseErr *filesystem.CSVParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, 3, parseErr.Record)
+		assert.Equal(t, "e,f,g", parseErr.Raw)
+		assert.ErrorIs(t, err, csv.ErrFieldCount)
+	})
+}
+
+func TestCSVCodec_CommentOnlyLeading(t *testing.T) {
+	parse := func(codec *filesystem.CSVCodec, content string) [][]string {
+		pipe := pipeline.NewChanPipe()
+
+		var results [][]string
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			for msg := range pipe.Out() {
+				results = append(results, msg.Data.([]string))
+			}
+		}()
+
+		require.NoError(t, codec.Parse(context.Background(), strings.NewReader(content), pipe))
+		wg.Wait()
+
+		return results
+	}
+
+	content := "# exported by billing\n# 2024-01-01\nid,note\n#1,first\n2,uses # inside\n#3,third\n"
+
+	t.Run("skips leading comments and keeps data rows starting with the comment char", func(t *testing.T) {
+		results := parse(filesystem.NewCSVCodec().WithCommentOnlyLeading(), content)
+
+		assert.Equal(t, [][]string{
+			{"id", "note"},
+			{"#1", "first"},
+			{"2", "uses # inside"},
+			{"#3", "third"},
+		}, results)
+	})
+
+	t.Run("treats every comment line as a comment by default", func(t *testing.T) {
+		results := parse(filesystem.NewCSVCodec(), content)
+
+		assert.Equal(t, [][]string{
+			{"id", "note"},
+			{"2", "uses # inside"},
+		}, results)
+	})
+}
+
+func TestCSVCodec_HeaderMode(t *testing.T) {
+	parse := func(codec *filesystem.CSVCodec, content string) []pipeline.Msg {
+		pipe := pipeline.NewChanPipe()
+
+		var results []pipeline.Msg
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			for msg := range pipe.Out() {
+				results = append(results, msg)
+			}
+		}()
+
+		require.NoError(t, codec.Parse(context.Background(), strings.NewReader(content), pipe))
+		wg.Wait()
+
+		return results
+	}
+
+	content := "name,age\nana,30\nbob,25\n"
+
+	t.Run("consumes the header silently by default", func(t *testing.T) {
+		results := parse(filesystem.NewCSVCodec().WithHeaderMode(), content)
+
+		require.Len(t, results, 2)
+		assert.Equal(t, map[string]any{"name": "ana", "age": "30"}, results[0].Data)
+		assert.Equal(t, map[string]any{"name": "bob", "age": "25"}, results[1].Data)
+
+		_, isHeader := results[0].MetaValue(filesystem.CSVHeaderMeta)
+		assert.False(t, isHeader)
+	})
+
+	t.Run("emits the header first when enabled", func(t *testing.T) {
+		results := parse(filesystem.NewCSVCodec().WithEmitHeaderMessage(), content)
+
+		require.Len(t, results, 3)
+		assert.Equal(t, []string{"name", "age"}, results[0].Data)
+
+		isHeader, ok := results[0].MetaValue(filesystem.CSVHeaderMeta)
+		assert.True(t, ok)
+		assert.Equal(t, true, isHeader)
+
+		assert.Equal(t, map[string]any{"name": "ana", "age": "30"}, results[1].Data)
+		assert.Nil(t, results[1].Meta)
+	})
+
+	t.Run("emits nothing but the header for a header-only file", func(t *testing.T) {
+		results := parse(filesystem.NewCSVCodec().WithEmitHeaderMessage(), "name,age\n")
+
+		require.Len(t, results, 1)
+		assert.Equal(t, []string{"name", "age"}, results[0].Data)
+	})
+}
+
 func TestCSVCodec_Encode(t *testing.T) {
 	t.Run("encodes string slice messages", func(t *testing.T) {
 		codec := filesystem.NewCSVCodec()
@@ -468,3 +618,22 @@ func TestCSVCodec_Encode(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestCSVCodec_EncodeStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.csv")
+
+	pipe := pipeline.NewChanPipe()
+	go func() {
+		pipe.In() <- pipeline.Msg{Data: map[string]any{"name": "alice", "age": 30.0}}
+		pipe.In() <- pipeline.Msg{Data: map[string]any{"name": "bob", "age": 25.0}}
+		pipe.In() <- pipeline.Msg{Data: []string{"carol", "41"}}
+		close(pipe.In())
+	}()
+
+	codec := filesystem.NewCSVCodec().WithHeaderRow("name", "age")
+	require.NoError(t, filesystem.File(path).Write().WithCodec(codec).Start(context.Background(), pipe))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "name,age\nalice,30\nbob,25\ncarol,41\n", string(content))
+}
@@ -0,0 +1,32 @@
+package filesystem
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+)
+
+// Errors wrapped by the file routines and codecs, so callers can tell failures apart with
+// errors.Is rather than by their text. The underlying error, such as an *fs.PathError or
+// a *json.SyntaxError, stays in the chain for errors.As.
+var (
+	// ErrFileOpen is wrapped when a file cannot be opened or created.
+	ErrFileOpen = errors.New("failed to open file")
+	// ErrCodecParse is wrapped when a codec fails to parse the content being read.
+	ErrCodecParse = errors.New("failed to parse")
+	// ErrCodecEncode is wrapped when a codec fails to encode a message being written.
+	ErrCodecEncode = errors.New("failed to encode")
+	// ErrMaxBufferExceeded is wrapped when a record does not fit the buffer a codec reads
+	// it into, such as a line longer than the line scanner allows.
+	ErrMaxBufferExceeded = errors.New("maximum buffer size exceeded")
+)
+
+// scanErr reports a line too long for a bufio.Scanner reading lines of up to maxSize bytes
+// as ErrMaxBufferExceeded, keeping bufio.ErrTooLong in the chain.
+func scanErr(err error, maxSize int) error {
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("line longer than %d bytes: %w: %w", maxSize, ErrMaxBufferExceeded, err)
+	}
+
+	return err
+}
@@ -0,0 +1,42 @@
+package filesystem
+
+import (
+	"io"
+	"os"
+)
+
+// WithSyncFile makes the routine sync files with fn.
+func (w *WriteFileRoutine) WithSyncFile(fn func(file *os.File) error) *WriteFileRoutine {
+	w.syncFile = fn
+	return w
+}
+
+// WithFileWriter makes the routine write streams to the writer fn returns for their file.
+func (w *WriteFileRoutine) WithFileWriter(fn func(file *os.File) io.Writer) *WriteFileRoutine {
+	w.fileWriter = fn
+	return w
+}
+
+// OpenFiles is the open file cache of buffered writes.
+type OpenFiles = openFiles
+
+// NewOpenFiles returns a cache keeping at most size files open.
+func NewOpenFiles(size int) *OpenFiles {
+	return newOpenFiles(size, defaultBufferSize, func(file *os.File) { file.Close() })
+}
+
+// WriteString writes s to the file at path through the cache.
+func (o *openFiles) WriteString(path, s string) error {
+	bf, err := o.get(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = bf.writer.WriteString(s)
+	return err
+}
+
+// CloseAll flushes and closes every open file.
+func (o *openFiles) CloseAll() error {
+	return o.closeAll()
+}
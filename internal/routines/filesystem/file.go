@@ -1,12 +1,17 @@
 package filesystem
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/caiorcferreira/goscript/internal/template"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
 )
@@ -39,12 +44,15 @@ func (f FileRoutineBuilder) Write() *WriteFileRoutine {
 		path:       f.path,
 		writeCodec: writeCodec,
 		renderer:   template.NewRenderer(),
+		syncFile:   (*os.File).Sync,
+		fileWriter: func(file *os.File) io.Writer { return file },
 	}
 }
 
 const (
-	modeRead  = os.O_RDONLY
-	modeWrite = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	modeRead        = os.O_RDONLY
+	modeWrite       = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	modeStreamWrite = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
 )
 
 // ReadFileRoutineBuilder builds and executes file reading operations
@@ -60,18 +68,19 @@ func (r *ReadFileRoutineBuilder) Start(ctx context.Context, pipe pipeline.Pipe)
 		slog.Info("finished reading file", "path", r.path)
 	}()
 
+	// close the pipe even when the file cannot be opened, so the stages after it finish
+	defer pipe.Close()
+
 	file, err := os.OpenFile(r.path, modeRead, 0)
 	if err != nil {
-		return fmt.Errorf("failed to open file for read: %w", err)
+		return fmt.Errorf("%w for read: %w", ErrFileOpen, err)
 	}
-
-	defer pipe.Close()
 	defer file.Close()
 
 	// Use codec to parse file content and write to pipe with context support
-	err = r.readCodec.Parse(ctx, file, pipe)
+	err = Parse(ctx, r.readCodec, file, pipe)
 	if err != nil {
-		return fmt.Errorf("failed to parse file with codec: %w", err)
+		return fmt.Errorf("%w file with codec: %w", ErrCodecParse, err)
 	}
 
 	return nil
@@ -81,6 +90,17 @@ func (r *ReadFileRoutineBuilder) Start(ctx context.Context, pipe pipeline.Pipe)
 type ReadFileRoutine struct {
 	path      string
 	readCodec ReadCodec
+	readAhead int
+}
+
+// Describe summarizes the file and codec read, for Script.Describe.
+func (r *ReadFileRoutine) Describe() string {
+	desc := fmt.Sprintf("read file %q with %s", r.path, pipeline.DescribeSettings(r.readCodec))
+	if r.readAhead > 0 {
+		desc += fmt.Sprintf(", read ahead %d", r.readAhead)
+	}
+
+	return desc
 }
 
 func (r *ReadFileRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
@@ -89,23 +109,35 @@ func (r *ReadFileRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
 		slog.Info("finished reading file", "path", r.path)
 	}()
 
+	// close the pipe even when the file cannot be opened, so the stages after it finish
+	defer pipe.Close()
+
 	file, err := os.OpenFile(r.path, modeRead, 0)
 	if err != nil {
-		return fmt.Errorf("failed to open file for read: %w", err)
+		return fmt.Errorf("%w for read: %w", ErrFileOpen, err)
 	}
-
-	defer pipe.Close()
 	defer file.Close()
 
 	// Use codec to parse file content and write to pipe with context support
-	err = r.readCodec.Parse(ctx, file, pipe)
+	if r.readAhead > 0 {
+		err = parseInto(ctx, r.readCodec, file, pipe, r.readAhead)
+	} else {
+		err = Parse(ctx, r.readCodec, file, pipe)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to parse file with codec: %w", err)
+		return fmt.Errorf("%w file with codec: %w", ErrCodecParse, err)
 	}
 
 	return nil
 }
 
+// WithReadAhead lets the codec parse up to depth records ahead of the downstream
+// consumer, so disk reads overlap with processing
+func (r *ReadFileRoutine) WithReadAhead(depth int) *ReadFileRoutine {
+	r.readAhead = depth
+	return r
+}
+
 // WithCodec sets the codec for reading files
 func (r *ReadFileRoutine) WithCodec(codec ReadCodec) *ReadFileRoutine {
 	r.readCodec = codec
@@ -136,11 +168,53 @@ func (r *ReadFileRoutine) WithBlobCodec() *ReadFileRoutine {
 	return r
 }
 
+// WriteErrorPolicy selects what a file writer does with a message it fails to write,
+// because its path cannot be rendered or the codec cannot encode it.
+type WriteErrorPolicy int
+
+const (
+	// SkipFailedWrites logs the failure with the path and message ID and moves on to the
+	// next message. It is the default.
+	SkipFailedWrites WriteErrorPolicy = iota
+	// FailOnWriteError stops writing and returns the failure from Start.
+	FailOnWriteError
+)
+
 // WriteFileRoutine handles file writing operations
 type WriteFileRoutine struct {
 	path       string
 	writeCodec WriteCodec
 	renderer   template.Renderer
+
+	flushInterval time.Duration
+	bufferSize    int
+	maxOpenFiles  int
+	sync          bool
+	errorPolicy   WriteErrorPolicy
+
+	// syncFile commits file contents to stable storage when sync is enabled
+	syncFile func(file *os.File) error
+	// fileWriter returns the writer a stream is written to through its file
+	fileWriter func(file *os.File) io.Writer
+}
+
+// Describe summarizes the file, codec and buffering of the writes, for Script.Describe.
+func (w *WriteFileRoutine) Describe() string {
+	desc := fmt.Sprintf("write file %q with %s", w.path, pipeline.DescribeSettings(w.writeCodec))
+	if w.bufferSize > 0 {
+		desc += fmt.Sprintf(", buffer %d bytes", w.bufferSize)
+	}
+	if w.flushInterval > 0 {
+		desc += fmt.Sprintf(", flush every %s", w.flushInterval)
+	}
+	if w.maxOpenFiles > 0 {
+		desc += fmt.Sprintf(", max open files %d", w.maxOpenFiles)
+	}
+	if w.sync {
+		desc += ", sync"
+	}
+
+	return desc
 }
 
 func (w *WriteFileRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
@@ -151,23 +225,45 @@ func (w *WriteFileRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error
 
 	defer pipe.Close()
 
+	// a templated path may route each message to a different file, so only a static
+	// path can be written as one stream
+	if streamCodec, ok := w.writeCodec.(StreamWriteCodec); ok && isStaticPath(w.path) {
+		return w.writeStream(ctx, pipe, streamCodec)
+	}
+
+	// a static path names the output even when no message arrives, so an empty source
+	// still leaves an empty file behind
+	if isStaticPath(w.path) {
+		if err := createFile(w.path, modeWrite); err != nil {
+			return err
+		}
+	}
+
+	if w.flushInterval > 0 || w.bufferSize > 0 || w.maxOpenFiles > 0 {
+		return w.writeBuffered(ctx, pipe)
+	}
+
 	for msg := range pipe.In() {
 		filePath, err := template.RenderAs[string](w.renderer, w.path, msg.Data)
 		if err != nil {
-			slog.Error("failed to render file", "path", w.path, "error", err)
+			if err := w.writeFailed(msg, w.path, fmt.Errorf("failed to render file path: %w", err)); err != nil {
+				return err
+			}
 			continue
 		}
 
 		file, err := openWritingFile(filePath, modeWrite)
 		if err != nil {
-			return fmt.Errorf("failed to open file for write: %w", err)
+			return err
 		}
 
 		err = w.writeCodec.Encode(ctx, msg, file)
-		file.Close() // Close file immediately after writing each message
+		w.closeFile(file) // Close file immediately after writing each message
 
 		if err != nil {
-			slog.Error("failed to encode message to file", "path", filePath, "error", err)
+			if err := w.writeFailed(msg, filePath, fmt.Errorf("%w message: %w", ErrCodecEncode, err)); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -177,15 +273,137 @@ func (w *WriteFileRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error
 	return nil
 }
 
+// writeStream hands every message to a StreamWriteCodec through a single file, whose
+// path is rendered from the first message.
+func (w *WriteFileRoutine) writeStream(ctx context.Context, pipe pipeline.Pipe, codec StreamWriteCodec) (err error) {
+	first, ok := <-pipe.In()
+	if !ok {
+		// an empty source still leaves an empty file behind
+		if err := createFile(w.path, modeStreamWrite); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	filePath, err := template.RenderAs[string](w.renderer, w.path, first.Data)
+	if err != nil {
+		return fmt.Errorf("failed to render file path %s: %w", w.path, err)
+	}
+
+	file, err := openWritingFile(filePath, modeStreamWrite)
+	if err != nil {
+		return err
+	}
+	defer w.closeFile(file)
+
+	writer := w.fileWriter(file)
+	if w.bufferSize > 0 || w.flushInterval > 0 {
+		buffered := newStreamBuffer(writer, cmp.Or(w.bufferSize, defaultBufferSize))
+		defer func() {
+			if flushErr := buffered.Flush(); flushErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to flush file %s: %w", filePath, flushErr))
+			}
+		}()
+		writer = buffered
+
+		if w.flushInterval > 0 {
+			// deferred after the final flush, so the ticker is stopped before it runs
+			stop := buffered.flushEvery(ctx, w.flushInterval, filePath)
+			defer stop()
+		}
+	}
+
+	// the forwarder stops once the codec returns, and whatever it left is drained so a codec
+	// failing mid-stream does not block the upstream stage
+	streamCtx, cancel := context.WithCancel(ctx)
+	forwarded := make(chan struct{})
+	defer func() {
+		cancel()
+		<-forwarded
+		for range pipe.In() {
+		}
+	}()
+
+	msgs := make(chan pipeline.Msg)
+	pipeline.Go(ctx, func() {
+		defer close(forwarded)
+		defer close(msgs)
+
+		select {
+		case <-streamCtx.Done():
+			return
+		case msgs <- first:
+		}
+
+		for msg := range pipe.In() {
+			select {
+			case <-streamCtx.Done():
+				return
+			case msgs <- msg:
+			}
+		}
+	})
+
+	if err := codec.EncodeStream(streamCtx, msgs, writer); err != nil {
+		return fmt.Errorf("%w messages to file %s: %w", ErrCodecEncode, filePath, err)
+	}
+
+	return nil
+}
+
+// writeFailed reports a message that could not be written to path, returning the failure
+// with its context when the error policy is FailOnWriteError and logging it otherwise.
+func (w *WriteFileRoutine) writeFailed(msg pipeline.Msg, path string, err error) error {
+	err = fmt.Errorf("failed to write message %s to %s: %w", msg.ID, path, err)
+
+	if w.errorPolicy == FailOnWriteError {
+		return err
+	}
+
+	slog.Error("skipping message that failed to write", "path", path, "msg_id", msg.ID, "error", err)
+
+	return nil
+}
+
+// closeFile closes file, syncing it to stable storage first when sync is enabled.
+func (w *WriteFileRoutine) closeFile(file *os.File) {
+	if w.sync {
+		if err := w.syncFile(file); err != nil {
+			slog.Error("failed to sync file", "path", file.Name(), "error", err)
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		slog.Error("failed to close file", "path", file.Name(), "error", err)
+	}
+}
+
+// isStaticPath reports whether path holds no template actions.
+func isStaticPath(path string) bool {
+	return !strings.Contains(path, "{{")
+}
+
+// createFile opens path with mode, creating it and its directory, and closes it right away.
+func createFile(path string, mode int) error {
+	file, err := openWritingFile(path, mode)
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// openWritingFile opens path with mode, creating its directory. Failures wrap ErrFileOpen
+// with the path, so callers return them as is.
 func openWritingFile(path string, mode int) (*os.File, error) {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+		return nil, fmt.Errorf("%w %s for write: failed to create directory %s: %w", ErrFileOpen, path, dir, err)
 	}
 
 	file, err := os.OpenFile(path, mode, 0644)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+		return nil, fmt.Errorf("%w %s for write: %w", ErrFileOpen, path, err)
 	}
 
 	return file, nil
@@ -197,6 +415,46 @@ func (w *WriteFileRoutine) WithCodec(codec WriteCodec) *WriteFileRoutine {
 	return w
 }
 
+// WithFlushInterval keeps written files open behind a buffer that is flushed every interval
+// and when the routine finishes, trading latency for fewer write calls
+func (w *WriteFileRoutine) WithFlushInterval(interval time.Duration) *WriteFileRoutine {
+	w.flushInterval = interval
+	return w
+}
+
+// WithBufferSize writes through a buffer of size bytes, flushed when full and when the
+// routine finishes, cutting write calls for output made of many small records. Files are
+// kept open between messages, as with WithFlushInterval, which may be combined with it to
+// also flush periodically.
+func (w *WriteFileRoutine) WithBufferSize(size int) *WriteFileRoutine {
+	w.bufferSize = size
+	return w
+}
+
+// WithMaxOpenFiles buffers writes and keeps files open between them, as WithBufferSize
+// does, but at most n at once: the least recently written file is flushed and closed to
+// open another, and reopened in append mode when written again. It prevents "too many
+// open files" errors when a templated path partitions messages across thousands of
+// files. Buffered writes otherwise cap open files at half the soft limit on file
+// descriptors (ulimit -n).
+func (w *WriteFileRoutine) WithMaxOpenFiles(n int) *WriteFileRoutine {
+	w.maxOpenFiles = n
+	return w
+}
+
+// WithSync calls Sync on every file before closing it, so written data survives a crash
+func (w *WriteFileRoutine) WithSync() *WriteFileRoutine {
+	w.sync = true
+	return w
+}
+
+// WithWriteErrorPolicy sets what happens to messages that fail to render or encode, see
+// SkipFailedWrites and FailOnWriteError
+func (w *WriteFileRoutine) WithWriteErrorPolicy(policy WriteErrorPolicy) *WriteFileRoutine {
+	w.errorPolicy = policy
+	return w
+}
+
 // WithLineCodec sets the codec to LineCodec for line-by-line writing
 func (w *WriteFileRoutine) WithLineCodec() *WriteFileRoutine {
 	w.writeCodec = NewLineCodec()
@@ -215,6 +473,12 @@ func (w *WriteFileRoutine) WithJSONCodec() *WriteFileRoutine {
 	return w
 }
 
+// WithJSONArrayCodec sets the codec to JSONWriteCodec writing all messages as one JSON array
+func (w *WriteFileRoutine) WithJSONArrayCodec() *WriteFileRoutine {
+	w.writeCodec = NewJSONWriteCodec().WithJSONArrayMode()
+	return w
+}
+
 // WithBlobCodec sets the codec to BlobCodec for raw data writing
 func (w *WriteFileRoutine) WithBlobCodec() *WriteFileRoutine {
 	w.writeCodec = NewBlobCodec()
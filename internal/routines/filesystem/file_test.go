@@ -1,9 +1,15 @@
 package filesystem_test
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -116,6 +122,42 @@ func TestFileRoutine_Read(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to open file for read")
+		assert.ErrorIs(t, err, filesystem.ErrFileOpen)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("wraps codec failures in ErrCodecParse", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "broken.json")
+		require.NoError(t, os.WriteFile(testFile, []byte(`{"id": 1`), 0644))
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		err := filesystem.File(testFile).Read().Start(context.Background(), pipe)
+
+		assert.ErrorIs(t, err, filesystem.ErrCodecParse)
+		assert.NotErrorIs(t, err, filesystem.ErrFileOpen)
+	})
+
+	t.Run("reports lines longer than the scanner buffer", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "long.txt")
+		require.NoError(t, os.WriteFile(testFile, []byte(strings.Repeat("x", 70*1024)+"\n"), 0644))
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		err := filesystem.File(testFile).Read().Start(context.Background(), pipe)
+
+		assert.ErrorIs(t, err, filesystem.ErrCodecParse)
+		assert.ErrorIs(t, err, filesystem.ErrMaxBufferExceeded)
+		assert.ErrorIs(t, err, bufio.ErrTooLong)
+		assert.ErrorContains(t, err, fmt.Sprintf("line longer than %d bytes", bufio.MaxScanTokenSize))
 	})
 
 	t.Run("closes pipe after reading", func(t *testing.T) {
@@ -144,6 +186,73 @@ func TestFileRoutine_Read(t *testing.T) {
 	})
 }
 
+// panickingCodec emits one line and then panics.
+type panickingCodec struct{}
+
+func (panickingCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	pipe.Send(ctx, pipeline.Msg{Data: "before"})
+	panic("codec bug")
+}
+
+func TestReadFileRoutine_ParsePanic(t *testing.T) {
+	read := func(t *testing.T, codec filesystem.ReadCodec) ([]any, error) {
+		t.Helper()
+
+		testFile := filepath.Join(t.TempDir(), "in.jsonl")
+		content := "{\"name\": \"first\"}\n{\"name\": \"boom\"}\n{\"name\": \"last\"}\n"
+		require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+		pipe := pipeline.NewChanPipe()
+
+		var data []any
+		collected := make(chan struct{})
+		go func() {
+			defer close(collected)
+			for msg := range pipe.Out() {
+				data = append(data, msg.Data)
+			}
+		}()
+
+		var err error
+		assert.NotPanics(t, func() {
+			err = filesystem.File(testFile).Read().WithCodec(codec).Start(context.Background(), pipe)
+		})
+		<-collected
+
+		return data, err
+	}
+
+	t.Run("skips a panicking JSON record under the skip-errors policy", func(t *testing.T) {
+		codec := filesystem.WithJSONType[panickyRecord](filesystem.NewJSONCodec().WithJSONLinesMode().WithSkipErrors())
+
+		data, err := read(t, codec)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []any{panickyRecord{Name: "first"}, panickyRecord{Name: "last"}}, data)
+	})
+
+	t.Run("converts a JSON decoding panic into an error", func(t *testing.T) {
+		codec := filesystem.WithJSONType[panickyRecord](filesystem.NewJSONCodec().WithJSONLinesMode())
+
+		data, err := read(t, codec)
+
+		assert.ErrorIs(t, err, filesystem.ErrRecordPanic)
+		assert.Contains(t, err.Error(), "assignment to entry in nil map")
+		assert.Equal(t, []any{panickyRecord{Name: "first"}}, data)
+	})
+
+	t.Run("converts a panic of any codec into an error", func(t *testing.T) {
+		data, err := read(t, panickingCodec{})
+
+		assert.ErrorIs(t, err, filesystem.ErrRecordPanic)
+		assert.ErrorIs(t, err, filesystem.ErrCodecParse)
+		assert.Contains(t, err.Error(), "codec bug")
+		assert.Equal(t, []any{"before"}, data)
+	})
+}
+
 func TestFileRoutine_Write(t *testing.T) {
 	t.Run("writes string messages to file", func(t *testing.T) {
 		tempDir := t.TempDir()
@@ -176,6 +285,29 @@ func TestFileRoutine_Write(t *testing.T) {
 		assert.Equal(t, expectedContent, string(content))
 	})
 
+	t.Run("creates an empty file for an empty source", func(t *testing.T) {
+		routines := map[string]func(path string) *filesystem.WriteFileRoutine{
+			"per message": func(path string) *filesystem.WriteFileRoutine { return filesystem.File(path).Write() },
+			"buffered": func(path string) *filesystem.WriteFileRoutine {
+				return filesystem.File(path).Write().WithBufferSize(1024)
+			},
+			"stream": func(path string) *filesystem.WriteFileRoutine { return filesystem.File(path).Write().WithCSVCodec() },
+		}
+
+		for name, build := range routines {
+			testFile := filepath.Join(t.TempDir(), "output.txt")
+
+			pipe := pipeline.NewChanPipe()
+			close(pipe.In())
+
+			require.NoError(t, build(testFile).Start(context.Background(), pipe), name)
+
+			content, err := os.ReadFile(testFile)
+			require.NoError(t, err, name)
+			assert.Empty(t, content, name)
+		}
+	})
+
 	t.Run("writes byte slice messages to file", func(t *testing.T) {
 		tempDir := t.TempDir()
 		testFile := filepath.Join(tempDir, "output.txt")
@@ -329,6 +461,33 @@ func TestFileRoutine_ErrorHandling(t *testing.T) {
 		}
 	})
 
+	t.Run("wraps write open failures in ErrFileOpen once", func(t *testing.T) {
+		parent := filepath.Join(t.TempDir(), "not-a-dir")
+		require.NoError(t, os.WriteFile(parent, nil, 0644))
+		testFile := filepath.Join(parent, "out.txt")
+
+		writers := map[string]*filesystem.WriteFileRoutine{
+			"per message": filesystem.File(parent + "/{{.}}.txt").Write(),
+			"static":      filesystem.File(testFile).Write(),
+			"buffered":    filesystem.File(parent + "/{{.}}.txt").Write().WithBufferSize(1024),
+			"stream":      filesystem.File(testFile).Write().WithJSONArrayCodec(),
+		}
+
+		for name, routine := range writers {
+			pipe := pipeline.NewChanPipe()
+			go func() {
+				pipe.In() <- pipeline.Msg{Data: "out"}
+				close(pipe.In())
+			}()
+
+			err := routine.Start(context.Background(), pipe)
+
+			assert.ErrorIs(t, err, filesystem.ErrFileOpen, name)
+			assert.Equal(t, 1, strings.Count(err.Error(), filesystem.ErrFileOpen.Error()), name)
+			assert.Contains(t, err.Error(), parent, name)
+		}
+	})
+
 	t.Run("handles read from directory as error", func(t *testing.T) {
 		tempDir := t.TempDir()
 
@@ -539,3 +698,403 @@ func TestFileRoutine_WithCodec(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to parse file with codec")
 	})
 }
+
+func TestReadFileRoutine_WithReadAhead(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte(numberedLines(100)), 0644))
+
+	pipe := pipeline.NewChanPipe()
+
+	go func() {
+		err := filesystem.File(testFile).Read().WithReadAhead(16).Start(context.Background(), pipe)
+		assert.NoError(t, err)
+	}()
+
+	var results []string
+	for msg := range pipe.Out() {
+		results = append(results, msg.Data.(string))
+	}
+
+	assert.Equal(t, strings.Split(numberedLines(100), "\n"), results)
+}
+
+func BenchmarkReadFileRoutine_ReadAhead(b *testing.B) {
+	testFile := filepath.Join(b.TempDir(), "bench.txt")
+	require.NoError(b, os.WriteFile(testFile, []byte(numberedLines(2000)), 0644))
+
+	for _, depth := range []int{0, 256} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			for range b.N {
+				pipe := pipeline.NewChanPipe()
+
+				go func() {
+					_ = filesystem.File(testFile).Read().WithReadAhead(depth).Start(context.Background(), pipe)
+				}()
+
+				// simulate a compute-heavy downstream stage
+				for msg := range pipe.Out() {
+					sum := []byte(msg.Data.(string))
+					for range 50 {
+						digest := sha256.Sum256(sum)
+						sum = digest[:]
+					}
+				}
+			}
+		})
+	}
+}
+
+// countingWriter counts the Write calls reaching the wrapped writer.
+type countingWriter struct {
+	io.Writer
+	writes *int
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	*c.writes++
+	return c.Writer.Write(p)
+}
+
+func BenchmarkWriteFileRoutine_BufferSize(b *testing.B) {
+	lines := strings.Split(numberedLines(2000), "\n")
+
+	// both cases stream JSON lines through one open file, so only the buffer differs
+	for _, size := range []int{0, 64 * 1024} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			testFile := filepath.Join(b.TempDir(), "bench.jsonl")
+
+			var writes int
+			countWrites := func(file *os.File) io.Writer {
+				return countingWriter{Writer: file, writes: &writes}
+			}
+
+			for range b.N {
+				pipe := pipeline.NewChanPipe()
+				go func() {
+					defer close(pipe.In())
+					for _, line := range lines {
+						pipe.In() <- pipeline.Msg{Data: line}
+					}
+				}()
+
+				routine := filesystem.File(testFile).Write().WithCodec(filesystem.NewJSONWriteCodec()).WithFileWriter(countWrites)
+				if size > 0 {
+					routine = routine.WithBufferSize(size)
+				}
+				if err := routine.Start(context.Background(), pipe); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ReportMetric(float64(writes)/float64(b.N), "writes/op")
+		})
+	}
+}
+
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range n {
+		lines[i] = fmt.Sprintf("line-%d", i)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestWriteFileRoutine_WithFlushInterval(t *testing.T) {
+	// writeMidStream sends first, waits until the file reads want, then sends second and
+	// returns the final content
+	writeMidStream := func(t *testing.T, routine *filesystem.WriteFileRoutine, testFile string, first, second any, want string) string {
+		t.Helper()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routine.Start(ctx, pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: first}
+
+		// data becomes visible mid-stream once the interval elapses
+		assert.Eventually(t, func() bool {
+			content, err := os.ReadFile(testFile)
+			return err == nil && string(content) == want
+		}, time.Second, 10*time.Millisecond)
+
+		pipe.In() <- pipeline.Msg{Data: second}
+		close(pipe.In())
+		require.NoError(t, <-done)
+
+		content, err := os.ReadFile(testFile)
+		require.NoError(t, err)
+
+		return string(content)
+	}
+
+	t.Run("flushes lines mid-stream", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "out.txt")
+		routine := filesystem.File(testFile).Write().WithFlushInterval(20 * time.Millisecond)
+
+		content := writeMidStream(t, routine, testFile, "first", "second", "first\n")
+
+		assert.Equal(t, "first\nsecond\n", content)
+	})
+
+	t.Run("flushes a streamed CSV file mid-stream", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "out.csv")
+		routine := filesystem.File(testFile).Write().WithFlushInterval(20 * time.Millisecond).
+			WithCodec(filesystem.NewCSVCodec().WithHeaderRow("id", "name"))
+
+		content := writeMidStream(t, routine, testFile, []string{"1", "a"}, []string{"2", "b"}, "id,name\n1,a\n")
+
+		assert.Equal(t, "id,name\n1,a\n2,b\n", content)
+	})
+
+	t.Run("flushes a streamed JSON array mid-stream", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "out.json")
+		routine := filesystem.File(testFile).Write().WithFlushInterval(20 * time.Millisecond).WithJSONArrayCodec()
+
+		content := writeMidStream(t, routine, testFile, 1, 2, "[1")
+
+		assert.Equal(t, "[1,2]\n", content)
+	})
+}
+
+func TestWriteFileRoutine_WithBufferSize(t *testing.T) {
+	lines := strings.Split(numberedLines(5000), "\n")
+
+	writeAll := func(t *testing.T, routine *filesystem.WriteFileRoutine) {
+		t.Helper()
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			defer close(pipe.In())
+			for _, line := range lines {
+				pipe.In() <- pipeline.Msg{Data: line}
+			}
+		}()
+
+		require.NoError(t, routine.Start(context.Background(), pipe))
+	}
+
+	t.Run("flushes every line written through the buffer", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "out.txt")
+
+		writeAll(t, filesystem.File(testFile).Write().WithBufferSize(1024))
+
+		content, err := os.ReadFile(testFile)
+		require.NoError(t, err)
+		assert.Equal(t, numberedLines(5000)+"\n", string(content))
+	})
+
+	t.Run("flushes a streamed JSON lines file", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "out.jsonl")
+
+		routine := filesystem.File(testFile).Write().WithBufferSize(1024).
+			WithCodec(filesystem.NewJSONWriteCodec())
+		writeAll(t, routine)
+
+		content, err := os.ReadFile(testFile)
+		require.NoError(t, err)
+		assert.Len(t, strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"), len(lines))
+	})
+}
+
+func TestWriteFileRoutine_WithSync(t *testing.T) {
+	var mu sync.Mutex
+	synced := make(map[string]int)
+
+	recordSync := func(file *os.File) error {
+		mu.Lock()
+		defer mu.Unlock()
+		synced[file.Name()]++
+		return file.Sync()
+	}
+
+	write := func(t *testing.T, routine *filesystem.WriteFileRoutine, data ...string) {
+		routine.WithSyncFile(recordSync)
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			for _, d := range data {
+				pipe.In() <- pipeline.Msg{Data: d}
+			}
+			close(pipe.In())
+		}()
+
+		require.NoError(t, routine.Start(context.Background(), pipe))
+	}
+
+	t.Run("syncs every per-message write", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "out.txt")
+
+		write(t, filesystem.File(testFile).Write().WithSync(), "a", "b")
+
+		assert.Equal(t, 2, synced[testFile])
+	})
+
+	t.Run("syncs buffered files on close", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "out.txt")
+
+		write(t, filesystem.File(testFile).Write().WithFlushInterval(time.Hour).WithSync(), "a", "b")
+
+		assert.Equal(t, 1, synced[testFile])
+
+		content, err := os.ReadFile(testFile)
+		require.NoError(t, err)
+		assert.Equal(t, "a\nb\n", string(content))
+	})
+
+	t.Run("does not sync when disabled", func(t *testing.T) {
+		testFile := filepath.Join(t.TempDir(), "out.txt")
+
+		write(t, filesystem.File(testFile).Write(), "a")
+
+		assert.Zero(t, synced[testFile])
+	})
+}
+
+func TestFileRoutine_LineEndingsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "trailing newline", content: "a\nb\nc\n"},
+		{name: "no trailing newline", content: "a\nb\nc"},
+		{name: "crlf and blank lines", content: "a\r\n\r\nb\n\nc"},
+		{name: "empty file", content: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "in.txt")
+			dst := filepath.Join(dir, "out.txt")
+			require.NoError(t, os.WriteFile(src, []byte(tt.content), 0644))
+
+			ctx := context.Background()
+			readPipe := pipeline.NewChanPipe()
+			writePipe := pipeline.NewChanPipe()
+			readPipe.Chain(writePipe)
+
+			go func() {
+				err := filesystem.File(src).Read().WithCodec(filesystem.NewLineCodec().WithLineEndings()).Start(ctx, readPipe)
+				assert.NoError(t, err)
+			}()
+
+			err := filesystem.File(dst).Write().WithCodec(filesystem.NewLineCodec().WithLineEndings()).Start(ctx, writePipe)
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(dst)
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(content))
+		})
+	}
+}
+
+// rejectingCodec writes string messages as lines and fails to encode anything else.
+type rejectingCodec struct{}
+
+func (rejectingCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error {
+	line, ok := msg.Data.(string)
+	if !ok {
+		return fmt.Errorf("cannot encode %T", msg.Data)
+	}
+
+	_, err := io.WriteString(writer, line+"\n")
+	return err
+}
+
+// failingStreamCodec reads one message of the stream and then fails.
+type failingStreamCodec struct{}
+
+func (failingStreamCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error {
+	return nil
+}
+
+func (failingStreamCodec) EncodeStream(ctx context.Context, msgs <-chan pipeline.Msg, writer io.Writer) error {
+	<-msgs
+	return fmt.Errorf("stream broken")
+}
+
+func TestWriteFileRoutine_StreamCodecFailure(t *testing.T) {
+	pipe := pipeline.NewChanPipe()
+	produced := make(chan struct{})
+	go func() {
+		defer close(produced)
+		defer close(pipe.In())
+		for i := range 100 {
+			pipe.In() <- pipeline.Msg{Data: i}
+		}
+	}()
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	err := filesystem.File(path).Write().WithCodec(failingStreamCodec{}).Start(context.Background(), pipe)
+
+	require.ErrorIs(t, err, filesystem.ErrCodecEncode)
+	assert.Contains(t, err.Error(), "stream broken")
+
+	select {
+	case <-produced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream blocked after the codec failed")
+	}
+}
+
+func TestWriteFileRoutine_WithWriteErrorPolicy(t *testing.T) {
+	input := []pipeline.Msg{
+		{ID: "1", Data: "first"},
+		{ID: "2", Data: 42},
+		{ID: "3", Data: "third"},
+	}
+
+	write := func(routine *filesystem.WriteFileRoutine) error {
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			defer close(pipe.In())
+			for _, msg := range input {
+				pipe.In() <- msg
+			}
+		}()
+
+		return routine.Start(context.Background(), pipe)
+	}
+
+	for _, buffered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("buffered=%t", buffered), func(t *testing.T) {
+			build := func(path string) *filesystem.WriteFileRoutine {
+				routine := filesystem.File(path).Write().WithCodec(rejectingCodec{})
+				if buffered {
+					routine.WithFlushInterval(time.Hour)
+				}
+				return routine
+			}
+
+			t.Run("skips failed messages by default", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "out.txt")
+
+				require.NoError(t, write(build(path)))
+
+				content, err := os.ReadFile(path)
+				require.NoError(t, err)
+				assert.Equal(t, "first\nthird\n", string(content))
+			})
+
+			t.Run("fails with the path and message ID", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "out.txt")
+
+				err := write(build(path).WithWriteErrorPolicy(filesystem.FailOnWriteError))
+
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "failed to write message 2 to "+path)
+				assert.Contains(t, err.Error(), "cannot encode int")
+
+				content, readErr := os.ReadFile(path)
+				require.NoError(t, readErr)
+				assert.Equal(t, "first\n", string(content))
+			})
+		})
+	}
+}
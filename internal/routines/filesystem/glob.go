@@ -0,0 +1,235 @@
+package filesystem
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// globReorderBuffer is how many records an ordered Glob buffers per file while an earlier
+// file is still being emitted.
+const globReorderBuffer = 64
+
+// Glob creates a source that emits the parsed content of every file matching pattern, in
+// lexical path order. Files are parsed with the codec chosen from their extension unless
+// one is set with WithCodec.
+func Glob(pattern string) *GlobRoutine {
+	return &GlobRoutine{pattern: pattern, concurrency: 1}
+}
+
+// GlobRoutine reads every file matching a pattern, optionally several at a time
+type GlobRoutine struct {
+	pattern      string
+	readCodec    ReadCodec
+	concurrency  int
+	maxOpenFiles int
+	ordered      bool
+}
+
+// WithCodec sets the codec used to parse every matched file
+func (g *GlobRoutine) WithCodec(codec ReadCodec) *GlobRoutine {
+	g.readCodec = codec
+	return g
+}
+
+// WithConcurrency reads up to n files at the same time. Records of files read together
+// interleave unless Ordered is set.
+func (g *GlobRoutine) WithConcurrency(n int) *GlobRoutine {
+	g.concurrency = n
+	return g
+}
+
+// WithMaxOpenFiles caps how many files are open at once, lowering the concurrency if it
+// is higher. Without it the cap is half the soft limit on file descriptors (ulimit -n).
+func (g *GlobRoutine) WithMaxOpenFiles(n int) *GlobRoutine {
+	g.maxOpenFiles = n
+	return g
+}
+
+// Ordered when true, emits all records of a file before those of the next file, even when
+// files are read concurrently. Records of later files are buffered until their turn.
+func (g *GlobRoutine) Ordered(ordered bool) *GlobRoutine {
+	g.ordered = ordered
+	return g
+}
+
+// PreservesOrder reports whether records come out in path order, which holds when files
+// are read one at a time or Ordered is set.
+func (g *GlobRoutine) PreservesOrder() bool {
+	return g.ordered || g.concurrency <= 1 || g.maxOpenFiles == 1
+}
+
+// Describe summarizes the pattern, codec and concurrency of the reads, for Script.Describe.
+func (g *GlobRoutine) Describe() string {
+	codec := "the codec of each extension"
+	if g.readCodec != nil {
+		codec = pipeline.DescribeSettings(g.readCodec)
+	}
+
+	desc := fmt.Sprintf("glob %q with %s, concurrency %d", g.pattern, codec, max(g.concurrency, 1))
+	if g.ordered {
+		desc += ", ordered"
+	}
+
+	return desc
+}
+
+func (g *GlobRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	paths, err := g.match()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	// results[i] receives the records of paths[i] in ordered mode
+	var results []chan pipeline.Msg
+	if g.ordered {
+		results = make([]chan pipeline.Msg, len(paths))
+		for i := range results {
+			results[i] = make(chan pipeline.Msg, globReorderBuffer)
+		}
+	}
+
+	jobs := make(chan int)
+
+	// each worker holds one file open while reading it
+	workers := max(min(g.concurrency, cmp.Or(g.maxOpenFiles, defaultMaxOpenFiles())), 1)
+
+	for range workers {
+		wg.Add(1)
+		pipeline.Go(ctx, func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				out := pipe
+				if g.ordered {
+					sub := pipeline.NewChanPipe()
+					sub.SetOutChan(results[i])
+					out = sub
+				}
+
+				err := g.readFile(ctx, paths[i], out)
+
+				if g.ordered {
+					close(results[i])
+				}
+
+				if err != nil {
+					fail(err)
+				}
+			}
+		})
+	}
+
+	pipeline.Go(ctx, func() {
+		defer close(jobs)
+
+		for i := range paths {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- i:
+			}
+		}
+	})
+
+	if g.ordered {
+		g.emitInOrder(ctx, results, pipe)
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// match lists the regular files matching the pattern in lexical order.
+func (g *GlobRoutine) match() ([]string, error) {
+	matches, err := filepath.Glob(g.pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", g.pattern, err)
+	}
+
+	sort.Strings(matches)
+
+	paths := make([]string, 0, len(matches))
+	for _, path := range matches {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// emitInOrder forwards the records of each file in turn, waiting for a file to be fully
+// read before moving on to the next. It stops once ctx is done, since the channels of
+// files never handed to a worker are never closed.
+func (g *GlobRoutine) emitInOrder(ctx context.Context, results []chan pipeline.Msg, pipe pipeline.Pipe) {
+	for _, records := range results {
+		for {
+			var (
+				msg pipeline.Msg
+				ok  bool
+			)
+
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok = <-records:
+			}
+
+			if !ok {
+				break
+			}
+
+			if err := pipe.Send(ctx, msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (g *GlobRoutine) readFile(ctx context.Context, path string, pipe pipeline.Pipe) error {
+	slog.Info("reading file", "path", path)
+
+	file, err := os.OpenFile(path, modeRead, 0)
+	if err != nil {
+		return fmt.Errorf("%w for read: %w", ErrFileOpen, err)
+	}
+	defer file.Close()
+
+	readCodec := g.readCodec
+	if readCodec == nil {
+		readCodec = buildReadCodec(path)
+	}
+
+	if err := parseInto(ctx, readCodec, file, pipe, 0); err != nil {
+		return fmt.Errorf("%w file %s with codec: %w", ErrCodecParse, path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package filesystem_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobRoutine_Start(t *testing.T) {
+	dir := t.TempDir()
+
+	const files, linesPerFile = 6, 200
+
+	var expected []string
+	for f := range files {
+		var content string
+		for l := range linesPerFile {
+			line := fmt.Sprintf("file-%d line-%d", f, l)
+			content += line + "\n"
+			expected = append(expected, line)
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("part-%d.txt", f)), []byte(content), 0644))
+	}
+
+	// neither a match nor a regular file
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.md"), []byte("skip me\n"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "part-dir.txt"), 0755))
+
+	collect := func(t *testing.T, routine *filesystem.GlobRoutine) []string {
+		pipe := pipeline.NewChanPipe()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- routine.Start(context.Background(), pipe)
+		}()
+
+		var lines []string
+		for msg := range pipe.Out() {
+			lines = append(lines, msg.Data.(string))
+		}
+		require.NoError(t, <-errCh)
+
+		return lines
+	}
+
+	pattern := filepath.Join(dir, "part-*.txt")
+
+	t.Run("sequential reads files in path order", func(t *testing.T) {
+		assert.Equal(t, expected, collect(t, filesystem.Glob(pattern)))
+	})
+
+	t.Run("concurrent reads emit every record", func(t *testing.T) {
+		assert.ElementsMatch(t, expected, collect(t, filesystem.Glob(pattern).WithConcurrency(4)))
+	})
+
+	t.Run("max open files lowers the concurrency", func(t *testing.T) {
+		// a single open file leaves one reader, which keeps path order
+		assert.Equal(t, expected, collect(t, filesystem.Glob(pattern).WithConcurrency(4).WithMaxOpenFiles(1)))
+	})
+
+	t.Run("concurrent ordered reads keep files whole and in order", func(t *testing.T) {
+		for range 5 {
+			assert.Equal(t, expected, collect(t, filesystem.Glob(pattern).WithConcurrency(4).Ordered(true)))
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+
+		err := filesystem.Glob("[").Start(context.Background(), pipe)
+		assert.ErrorContains(t, err, "invalid glob pattern")
+	})
+
+	t.Run("unreadable file fails the source", func(t *testing.T) {
+		badDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(badDir, "a.json"), []byte("{not json"), 0644))
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		err := filesystem.Glob(filepath.Join(badDir, "*.json")).WithConcurrency(2).Ordered(true).Start(context.Background(), pipe)
+		assert.Error(t, err)
+	})
+	t.Run("ordered read stops after a failing file", func(t *testing.T) {
+		badDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(badDir, "a.json"), []byte("{not json"), 0644))
+		for i := range 10 {
+			require.NoError(t, os.WriteFile(filepath.Join(badDir, fmt.Sprintf("b%d.json", i)), []byte(`{"ok":true}`), 0644))
+		}
+
+		for range 10 {
+			pipe := pipeline.NewChanPipe()
+			go func() {
+				for range pipe.Out() {
+				}
+			}()
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- filesystem.Glob(filepath.Join(badDir, "*.json")).Ordered(true).Start(context.Background(), pipe)
+			}()
+
+			select {
+			case err := <-errCh:
+				assert.ErrorIs(t, err, filesystem.ErrCodecParse)
+			case <-time.After(5 * time.Second):
+				t.Fatal("ordered glob hung after a failing file")
+			}
+		}
+	})
+}
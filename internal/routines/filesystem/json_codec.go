@@ -5,18 +5,53 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"github.com/caiorcferreira/goscript/internal/pipeline"
-	"github.com/google/uuid"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// ArrayMode selects how a JSON document holding a top-level array is turned into messages.
+type ArrayMode int
+
+const (
+	// ArrayAuto emits each element of a top-level array as a message, and any other
+	// document as a single message. It is the default.
+	ArrayAuto ArrayMode = iota
+	// ArrayElement emits each element of a top-level array as a message, and fails on
+	// documents that are not arrays.
+	ArrayElement
+	// ArrayWhole emits the whole document as a single message, so a top-level array
+	// becomes one []any message.
+	ArrayWhole
 )
 
 // JSONCodec parses JSON file content
 // Supports both single JSON objects and JSON arrays
+//
+// JSONLines takes precedence over JSONArray, which takes precedence over Concatenated and
+// ArrayMode: in JSON lines mode every line is one message, arrays included, in JSON array
+// mode the content is streamed element by element and in concatenated mode every document
+// is one message. ArrayMode only applies to single documents.
+//
+// Empty or blank content parses as zero messages in every mode.
 type JSONCodec struct {
 	//todo: create an enum for modes
 	// JSONLines when true, treats each line as a separate JSON object (JSONL format)
 	JSONLines bool
 	JSONArray bool
+	// Concatenated when true, reads a sequence of JSON documents, such as {"a":1}{"a":2},
+	// emitting each as a message
+	Concatenated bool
+	// ArrayMode selects whether a top-level array is split into elements or kept whole
+	ArrayMode ArrayMode
+	// SkipErrors when true, logs and skips records that fail to decode instead of aborting
+	SkipErrors bool
+
+	// decode converts a single raw JSON value into message data; nil decodes into any
+	decode func(raw []byte) (any, error)
 }
 
 // Ensure JSONCodec implements all interfaces
@@ -40,6 +75,92 @@ func (c *JSONCodec) WithJSONArrayMode() *JSONCodec {
 	return c
 }
 
+// WithConcatenatedMode reads back-to-back JSON documents, optionally separated by
+// whitespace, as the JSON write codec produces with an empty separator
+func (c *JSONCodec) WithConcatenatedMode() *JSONCodec {
+	c.Concatenated = true
+	return c
+}
+
+// WithArrayMode sets how a top-level array is turned into messages
+func (c *JSONCodec) WithArrayMode(mode ArrayMode) *JSONCodec {
+	c.ArrayMode = mode
+	return c
+}
+
+// WithWholeArray keeps a top-level array as a single []any message instead of emitting
+// each element, see ArrayWhole
+func (c *JSONCodec) WithWholeArray() *JSONCodec {
+	c.ArrayMode = ArrayWhole
+	return c
+}
+
+func (c *JSONCodec) WithSkipErrors() *JSONCodec {
+	c.SkipErrors = true
+	return c
+}
+
+// WithJSONType makes the codec decode each object, line or array element into a T
+// instead of map[string]any. It is a function rather than a method because Go
+// methods cannot declare type parameters.
+//
+// Example:
+//
+//	codec := filesystem.WithJSONType[Person](filesystem.NewJSONCodec().WithJSONLinesMode())
+func WithJSONType[T any](c *JSONCodec) *JSONCodec {
+	c.decode = func(raw []byte) (any, error) {
+		var v T
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+
+		return v, nil
+	}
+
+	return c
+}
+
+// decodeRecord decodes a single record. The boolean result reports that the record failed
+// and must be skipped under the skip-errors policy. A panic while decoding fails only that
+// record, so the skip-errors policy applies to it like to any decode error.
+func (c *JSONCodec) decodeRecord(raw []byte) (any, bool, error) {
+	item, err := c.recoverDecode(raw)
+	if err == nil {
+		return item, false, nil
+	}
+
+	if c.SkipErrors {
+		slog.Warn("skipping invalid json record", "error", err)
+		return nil, true, nil
+	}
+
+	return nil, false, err
+}
+
+// recoverDecode runs decodeValue and converts a panic into an error wrapping ErrRecordPanic.
+func (c *JSONCodec) recoverDecode(raw []byte) (item any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrRecordPanic, r)
+		}
+	}()
+
+	return c.decodeValue(raw)
+}
+
+func (c *JSONCodec) decodeValue(raw []byte) (any, error) {
+	if c.decode != nil {
+		return c.decode(raw)
+	}
+
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
 func (c *JSONCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
 	defer pipe.Close()
 
@@ -51,45 +172,67 @@ func (c *JSONCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.P
 		return c.parseJSONArray(ctx, reader, pipe)
 	}
 
+	if c.Concatenated {
+		return c.parseConcatenated(ctx, reader, pipe)
+	}
+
 	return c.parseJSON(ctx, reader, pipe)
 }
 
 func (c *JSONCodec) parseJSON(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
 	decoder := json.NewDecoder(reader)
 
-	var objectData any
-	if err := decoder.Decode(&objectData); err != nil {
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
 		return err
 	}
 
+	trimmed := bytes.TrimSpace(raw)
+	isArray := len(trimmed) > 0 && trimmed[0] == '['
+
+	if c.ArrayMode == ArrayElement && !isArray {
+		return errors.New("expected a top-level json array in element mode")
+	}
+
 	// Auto-detect arrays and process them as individual elements for backward compatibility
-	if arrayData, ok := objectData.([]any); ok {
-		for _, item := range arrayData {
+	if isArray && c.ArrayMode != ArrayWhole {
+		var arrayData []json.RawMessage
+		if err := json.Unmarshal(raw, &arrayData); err != nil {
+			return err
+		}
+
+		for _, rawItem := range arrayData {
 			select {
 			case <-ctx.Done():
 				return nil
 			default:
-				msg := pipeline.Msg{
-					ID:   uuid.NewString(),
-					Data: item,
+				item, skip, err := c.decodeRecord(rawItem)
+				if err != nil {
+					return err
 				}
+				if skip {
+					continue
+				}
+
+				msg := pipeline.NewMsg(pipeline.NewID(ctx), item)
 
-				select {
-				case pipe.Out() <- msg:
-				case <-ctx.Done():
+				if err := pipe.Send(ctx, msg); err != nil {
 					return nil
 				}
 			}
 		}
 	} else {
-		msg := pipeline.Msg{
-			ID:   uuid.NewString(),
-			Data: objectData,
+		objectData, skip, err := c.decodeRecord(raw)
+		if err != nil || skip {
+			return err
 		}
 
-		select {
-		case pipe.Out() <- msg:
-		case <-ctx.Done():
+		msg := pipeline.NewMsg(pipeline.NewID(ctx), objectData)
+
+		if err := pipe.Send(ctx, msg); err != nil {
 			return nil
 		}
 	}
@@ -110,60 +253,111 @@ func (c *JSONCodec) parseJSONLines(ctx context.Context, reader io.Reader, pipe p
 				continue
 			}
 
-			var data any
-			if err := json.Unmarshal(line, &data); err != nil {
+			data, skip, err := c.decodeRecord(line)
+			if err != nil {
 				return err
 			}
-
-			msg := pipeline.Msg{
-				ID:   uuid.NewString(),
-				Data: data,
+			if skip {
+				continue
 			}
-			select {
-			case pipe.Out() <- msg:
-			case <-ctx.Done():
+
+			msg := pipeline.NewMsg(pipeline.NewID(ctx), data)
+			if err := pipe.Send(ctx, msg); err != nil {
 				return nil
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return err
+		// the scanner keeps its default buffer
+		return scanErr(err, bufio.MaxScanTokenSize)
 	}
 
 	return nil
 }
 
+// parseJSONArray streams the elements of a top-level array, decoding one at a time so
+// arrays larger than memory can be read.
 func (c *JSONCodec) parseJSONArray(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
 	decoder := json.NewDecoder(reader)
 
-	var arrayData []any
-	err := decoder.Decode(&arrayData)
+	token, err := decoder.Token()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("expected a top-level json array, found %v", token)
+	}
 
-	for _, item := range arrayData {
-		select {
-		case <-ctx.Done():
+	for decoder.More() {
+		if ctx.Err() != nil {
 			return nil
-		default:
-			msg := pipeline.Msg{
-				ID:   uuid.NewString(),
-				Data: item,
-			}
+		}
 
-			select {
-			case pipe.Out() <- msg:
-			case <-ctx.Done():
-				return nil
-			}
+		var rawItem json.RawMessage
+		if err := decoder.Decode(&rawItem); err != nil {
+			return err
+		}
+
+		item, skip, err := c.decodeRecord(rawItem)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
 		}
+
+		msg := pipeline.NewMsg(pipeline.NewID(ctx), item)
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	// consume the closing bracket so a truncated array is reported
+	if _, err := decoder.Token(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// parseConcatenated emits every document of a stream of back-to-back JSON documents.
+func (c *JSONCodec) parseConcatenated(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
+	decoder := json.NewDecoder(reader)
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		item, skip, err := c.decodeRecord(raw)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+
+		msg := pipeline.NewMsg(pipeline.NewID(ctx), item)
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+}
+
 // Encode implements WriteCodec interface for JSONCodec
 func (c *JSONCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error {
 	encoder := json.NewEncoder(writer)
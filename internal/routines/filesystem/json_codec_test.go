@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"sync"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/caiorcferreira/goscript/internal/pipeline"
 	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJSONCodec_Parse(t *testing.T) {
@@ -212,6 +214,228 @@ func TestJSONCodec_Parse(t *testing.T) {
 	})
 }
 
+type typedAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type typedPerson struct {
+	Name    string       `json:"name"`
+	Age     int          `json:"age"`
+	Address typedAddress `json:"address"`
+	Tags    []string     `json:"tags"`
+}
+
+func TestJSONCodec_WithJSONType(t *testing.T) {
+	parseAll := func(t *testing.T, codec *filesystem.JSONCodec, content string) ([]typedPerson, error) {
+		pipe := pipeline.NewChanPipe()
+
+		var results []typedPerson
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			for msg := range pipe.Out() {
+				results = append(results, msg.Data.(typedPerson))
+			}
+		}()
+
+		err := codec.Parse(context.Background(), strings.NewReader(content), pipe)
+		wg.Wait()
+
+		return results, err
+	}
+
+	expected := []typedPerson{
+		{Name: "John", Age: 30, Address: typedAddress{City: "Lisbon", Zip: "1000"}, Tags: []string{"a"}},
+		{Name: "Jane", Age: 25, Address: typedAddress{City: "Porto", Zip: "4000"}, Tags: []string{"b", "c"}},
+	}
+
+	t.Run("decodes JSON lines into structs", func(t *testing.T) {
+		content := `{"name": "John", "age": 30, "address": {"city": "Lisbon", "zip": "1000"}, "tags": ["a"]}
+{"name": "Jane", "age": 25, "address": {"city": "Porto", "zip": "4000"}, "tags": ["b", "c"]}`
+
+		codec := filesystem.WithJSONType[typedPerson](filesystem.NewJSONCodec().WithJSONLinesMode())
+		results, err := parseAll(t, codec, content)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, results)
+	})
+
+	t.Run("decodes array elements into structs", func(t *testing.T) {
+		content := `[{"name": "John", "age": 30, "address": {"city": "Lisbon", "zip": "1000"}, "tags": ["a"]},
+{"name": "Jane", "age": 25, "address": {"city": "Porto", "zip": "4000"}, "tags": ["b", "c"]}]`
+
+		for _, codec := range []*filesystem.JSONCodec{
+			filesystem.WithJSONType[typedPerson](filesystem.NewJSONCodec()),
+			filesystem.WithJSONType[typedPerson](filesystem.NewJSONCodec().WithJSONArrayMode()),
+		} {
+			results, err := parseAll(t, codec, content)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expected, results)
+		}
+	})
+
+	t.Run("returns error when a value does not fit the type", func(t *testing.T) {
+		codec := filesystem.WithJSONType[typedPerson](filesystem.NewJSONCodec().WithJSONLinesMode())
+		_, err := parseAll(t, codec, `{"name": "John", "age": "thirty"}`)
+
+		assert.Error(t, err)
+	})
+}
+
+// panickyRecord panics while unmarshaling any record named "boom".
+type panickyRecord struct {
+	Name string
+}
+
+func (p *panickyRecord) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	p.Name = raw["name"].(string)
+	if p.Name == "boom" {
+		var m map[string]int
+		m["boom"]++ // nil map write panics
+	}
+
+	return nil
+}
+
+// parseAll parses content with codec, returning the data of every message emitted.
+func parseAll(t *testing.T, codec *filesystem.JSONCodec, content string) ([]any, error) {
+	t.Helper()
+
+	pipe := pipeline.NewChanPipe()
+
+	var results []any
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		for msg := range pipe.Out() {
+			results = append(results, msg.Data)
+		}
+	}()
+
+	err := codec.Parse(context.Background(), strings.NewReader(content), pipe)
+	wg.Wait()
+
+	return results, err
+}
+
+func TestJSONCodec_PanicBoundary(t *testing.T) {
+	content := `{"name": "first"}
+{"name": "boom"}
+{"name": "second"}
+{"name": "last"}`
+
+	t.Run("skips a record that panics and keeps parsing", func(t *testing.T) {
+		codec := filesystem.WithJSONType[panickyRecord](filesystem.NewJSONCodec().WithJSONLinesMode().WithSkipErrors())
+
+		var results []any
+		var err error
+		assert.NotPanics(t, func() {
+			results, err = parseAll(t, codec, content)
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []any{
+			panickyRecord{Name: "first"},
+			panickyRecord{Name: "second"},
+			panickyRecord{Name: "last"},
+		}, results)
+	})
+
+	t.Run("returns the panic as an error without skip policy", func(t *testing.T) {
+		codec := filesystem.WithJSONType[panickyRecord](filesystem.NewJSONCodec().WithJSONLinesMode())
+
+		var results []any
+		var err error
+		assert.NotPanics(t, func() {
+			results, err = parseAll(t, codec, content)
+		})
+
+		assert.ErrorIs(t, err, filesystem.ErrRecordPanic)
+		assert.Equal(t, []any{panickyRecord{Name: "first"}}, results)
+	})
+}
+
+func TestJSONCodec_ArrayMode(t *testing.T) {
+	array := `[{"id": 1}, {"id": 2}]`
+	object := `{"id": 1}`
+
+	elements := []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}}
+	whole := []any{[]any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}}}
+
+	tests := []struct {
+		name     string
+		codec    *filesystem.JSONCodec
+		content  string
+		expected []any
+		wantErr  bool
+	}{
+		{name: "auto explodes arrays by default", codec: filesystem.NewJSONCodec(), content: array, expected: elements},
+		{name: "auto keeps objects", codec: filesystem.NewJSONCodec(), content: object, expected: []any{map[string]any{"id": 1.0}}},
+		{name: "element explodes arrays", codec: filesystem.NewJSONCodec().WithArrayMode(filesystem.ArrayElement), content: array, expected: elements},
+		{name: "element rejects objects", codec: filesystem.NewJSONCodec().WithArrayMode(filesystem.ArrayElement), content: object, wantErr: true},
+		{name: "whole keeps arrays", codec: filesystem.NewJSONCodec().WithWholeArray(), content: array, expected: whole},
+		{name: "whole keeps objects", codec: filesystem.NewJSONCodec().WithWholeArray(), content: object, expected: []any{map[string]any{"id": 1.0}}},
+		{name: "json array mode takes precedence", codec: filesystem.NewJSONCodec().WithJSONArrayMode().WithWholeArray(), content: array, expected: elements},
+		{name: "json lines mode takes precedence", codec: filesystem.NewJSONCodec().WithJSONLinesMode().WithArrayMode(filesystem.ArrayElement), content: array, expected: whole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := parseAll(t, tt.codec, tt.content)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, results)
+		})
+	}
+}
+
+func TestJSONCodec_Concatenated(t *testing.T) {
+	codec := filesystem.NewJSONCodec().WithConcatenatedMode()
+
+	results, err := parseAll(t, codec, "{\"id\":1}{\"id\":2}\n[3]  \"four\"")
+
+	require.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}, []any{3.0}, "four"}, results)
+
+	_, err = parseAll(t, codec, `{"id":1}{"id":`)
+	assert.Error(t, err, "a truncated document is reported")
+}
+
+func TestJSONCodec_EmptyInput(t *testing.T) {
+	codecs := map[string]func() *filesystem.JSONCodec{
+		"single":       filesystem.NewJSONCodec,
+		"array":        func() *filesystem.JSONCodec { return filesystem.NewJSONCodec().WithJSONArrayMode() },
+		"lines":        func() *filesystem.JSONCodec { return filesystem.NewJSONCodec().WithJSONLinesMode() },
+		"concatenated": func() *filesystem.JSONCodec { return filesystem.NewJSONCodec().WithConcatenatedMode() },
+	}
+
+	for name, newCodec := range codecs {
+		for _, content := range []string{"", " \n\t\n"} {
+			t.Run(fmt.Sprintf("%s %q", name, content), func(t *testing.T) {
+				results, err := parseAll(t, newCodec(), content)
+
+				require.NoError(t, err)
+				assert.Empty(t, results)
+			})
+		}
+	}
+}
+
 func TestJSONCodec_Encode(t *testing.T) {
 	t.Run("encodes messages as JSON lines by default", func(t *testing.T) {
 		codec := filesystem.NewJSONCodec().WithJSONLinesMode()
@@ -0,0 +1,132 @@
+package filesystem
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// JSONWriteCodec encodes a message stream as JSON, either one document per message
+// followed by Separator, or as a single JSON array holding every message.
+//
+// The separator selects the document layout: "\n" (the default) writes JSON lines and
+// "" writes concatenated documents.
+type JSONWriteCodec struct {
+	// JSONArray when true, writes all messages as elements of one JSON array, ignoring Separator
+	JSONArray bool
+	// Separator is written after each document when JSONArray is false
+	Separator string
+}
+
+// Ensure JSONWriteCodec implements all interfaces
+var _ WriteCodec = (*JSONWriteCodec)(nil)
+var _ StreamWriteCodec = (*JSONWriteCodec)(nil)
+
+func NewJSONWriteCodec() *JSONWriteCodec {
+	return &JSONWriteCodec{Separator: "\n"}
+}
+
+func (c *JSONWriteCodec) WithJSONArrayMode() *JSONWriteCodec {
+	c.JSONArray = true
+	return c
+}
+
+// WithJSONLinesMode writes one document per line, the default layout.
+func (c *JSONWriteCodec) WithJSONLinesMode() *JSONWriteCodec {
+	c.JSONArray = false
+	c.Separator = "\n"
+	return c
+}
+
+// WithSeparator writes sep after each document instead of a newline, e.g. "" for
+// concatenated JSON or "\x1e" for record separator framing. It disables array mode.
+func (c *JSONWriteCodec) WithSeparator(sep string) *JSONWriteCodec {
+	c.JSONArray = false
+	c.Separator = sep
+	return c
+}
+
+// Encode writes a single message as a JSON document followed by the separator
+func (c *JSONWriteCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error {
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return fmt.Errorf("%w json document: %w", ErrCodecEncode, err)
+	}
+
+	if _, err := writer.Write(append(data, c.Separator...)); err != nil {
+		return fmt.Errorf("failed to write json document: %w", err)
+	}
+
+	return nil
+}
+
+// EncodeStream implements StreamWriteCodec interface for JSONWriteCodec
+func (c *JSONWriteCodec) EncodeStream(ctx context.Context, msgs <-chan pipeline.Msg, writer io.Writer) error {
+	if c.JSONArray {
+		return c.encodeJSONArray(ctx, msgs, writer)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil
+			}
+
+			if err := c.Encode(ctx, msg, writer); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// encodeJSONArray streams every message as an element of one array, holding a single
+// element in memory at a time. The array is closed even when ctx is cancelled mid-stream,
+// so the output is always valid JSON holding whatever was written; write and encode
+// errors are returned to the caller.
+func (c *JSONWriteCodec) encodeJSONArray(ctx context.Context, msgs <-chan pipeline.Msg, writer io.Writer) (err error) {
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteByte('[')
+	_, flushElements := writer.(flusher)
+
+	defer func() {
+		buffered.WriteString("]\n")
+		if flushErr := buffered.Flush(); flushErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to write json array: %w", flushErr))
+		}
+	}()
+
+	for first := true; ; first = false {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil
+			}
+
+			data, marshalErr := json.Marshal(msg.Data)
+			if marshalErr != nil {
+				return fmt.Errorf("%w json array element: %w", ErrCodecEncode, marshalErr)
+			}
+
+			if !first {
+				buffered.WriteByte(',')
+			}
+			buffered.Write(data)
+
+			if flushElements {
+				if err := buffered.Flush(); err != nil {
+					return fmt.Errorf("failed to write json array: %w", err)
+				}
+			}
+		}
+	}
+}
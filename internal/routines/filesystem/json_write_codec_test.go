@@ -0,0 +1,182 @@
+package filesystem_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// feedMsgs returns a closed channel holding data as messages.
+func feedMsgs(data ...any) <-chan pipeline.Msg {
+	msgs := make(chan pipeline.Msg, len(data))
+	for _, d := range data {
+		msgs <- pipeline.Msg{Data: d}
+	}
+	close(msgs)
+
+	return msgs
+}
+
+func TestJSONWriteCodec_EncodeStream(t *testing.T) {
+	t.Run("writes JSON lines by default", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		err := filesystem.NewJSONWriteCodec().EncodeStream(context.Background(), feedMsgs(1, "two"), &buffer)
+
+		require.NoError(t, err)
+		assert.Equal(t, "1\n\"two\"\n", buffer.String())
+	})
+
+	t.Run("writes a single JSON array in array mode", func(t *testing.T) {
+		var buffer bytes.Buffer
+		codec := filesystem.NewJSONWriteCodec().WithJSONArrayMode()
+
+		err := codec.EncodeStream(context.Background(), feedMsgs(map[string]any{"name": "John"}, 2), &buffer)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"name": "John"}, 2]`, buffer.String())
+	})
+
+	t.Run("writes an empty array for an empty stream", func(t *testing.T) {
+		var buffer bytes.Buffer
+		codec := filesystem.NewJSONWriteCodec().WithJSONArrayMode()
+
+		require.NoError(t, codec.EncodeStream(context.Background(), feedMsgs(), &buffer))
+		assert.JSONEq(t, `[]`, buffer.String())
+	})
+
+	t.Run("flushes a valid partial array on cancellation", func(t *testing.T) {
+		var buffer bytes.Buffer
+		codec := filesystem.NewJSONWriteCodec().WithJSONArrayMode()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		msgs := make(chan pipeline.Msg)
+		done := make(chan error, 1)
+		go func() {
+			done <- codec.EncodeStream(ctx, msgs, &buffer)
+		}()
+
+		msgs <- pipeline.Msg{Data: "a"}
+		msgs <- pipeline.Msg{Data: "b"}
+		cancel()
+
+		require.NoError(t, <-done)
+
+		var items []string
+		require.NoError(t, json.Unmarshal(buffer.Bytes(), &items))
+		assert.Equal(t, []string{"a", "b"}, items)
+	})
+
+	t.Run("returns write errors from the flush", func(t *testing.T) {
+		codec := filesystem.NewJSONWriteCodec().WithJSONArrayMode()
+
+		err := codec.EncodeStream(context.Background(), feedMsgs("a"), failingWriter{})
+
+		assert.ErrorContains(t, err, "disk full")
+	})
+
+	t.Run("returns encode errors from the flush", func(t *testing.T) {
+		var buffer bytes.Buffer
+		codec := filesystem.NewJSONWriteCodec().WithJSONArrayMode()
+
+		err := codec.EncodeStream(context.Background(), feedMsgs(func() {}), &buffer)
+
+		assert.ErrorContains(t, err, "failed to encode json array")
+	})
+}
+
+func TestJSONWriteCodec_Layouts(t *testing.T) {
+	stream := func() <-chan pipeline.Msg {
+		return feedMsgs(map[string]any{"id": 1}, map[string]any{"id": 2}, "three")
+	}
+
+	tests := []struct {
+		name     string
+		codec    *filesystem.JSONWriteCodec
+		expected string
+	}{
+		{
+			name:     "json lines",
+			codec:    filesystem.NewJSONWriteCodec().WithJSONLinesMode(),
+			expected: "{\"id\":1}\n{\"id\":2}\n\"three\"\n",
+		},
+		{
+			name:     "concatenated",
+			codec:    filesystem.NewJSONWriteCodec().WithSeparator(""),
+			expected: `{"id":1}{"id":2}"three"`,
+		},
+		{
+			name:     "custom separator",
+			codec:    filesystem.NewJSONWriteCodec().WithSeparator("\x1e"),
+			expected: "{\"id\":1}\x1e{\"id\":2}\x1e\"three\"\x1e",
+		},
+		{
+			name:     "array",
+			codec:    filesystem.NewJSONWriteCodec().WithJSONArrayMode(),
+			expected: "[{\"id\":1},{\"id\":2},\"three\"]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+
+			require.NoError(t, tt.codec.EncodeStream(context.Background(), stream(), &buffer))
+
+			assert.Equal(t, tt.expected, buffer.String())
+		})
+	}
+
+	t.Run("concatenated output decodes back to the stream", func(t *testing.T) {
+		var buffer bytes.Buffer
+		require.NoError(t, filesystem.NewJSONWriteCodec().WithSeparator("").EncodeStream(context.Background(), stream(), &buffer))
+
+		decoder := json.NewDecoder(&buffer)
+		var docs []any
+		for decoder.More() {
+			var doc any
+			require.NoError(t, decoder.Decode(&doc))
+			docs = append(docs, doc)
+		}
+
+		assert.Equal(t, []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}, "three"}, docs)
+	})
+}
+
+func TestWriteFileRoutine_WithJSONArrayCodec(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "out.json")
+	require.NoError(t, os.WriteFile(testFile, []byte("stale content"), 0644))
+
+	pipe := pipeline.NewChanPipe()
+	go func() {
+		for _, name := range []string{"John", "Jane"} {
+			pipe.In() <- pipeline.Msg{Data: map[string]any{"name": name}}
+		}
+		close(pipe.In())
+	}()
+
+	err := filesystem.File(testFile).Write().WithJSONArrayCodec().Start(context.Background(), pipe)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name": "John"}, {"name": "Jane"}]`, strings.TrimSpace(string(content)))
+}
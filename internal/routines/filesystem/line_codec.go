@@ -2,17 +2,28 @@ package filesystem
 
 import (
 	"bufio"
+	"bytes"
 	"context"
-	"fmt"
 	"io"
 	"log/slog"
+	"strings"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
-	"github.com/google/uuid"
 )
 
 // LineCodec parses file content line by line
-type LineCodec struct{}
+type LineCodec struct {
+	// KeepLineEndings when true, records each line's terminator ("\n", "\r\n" or none for
+	// an unterminated last line) in the LineEndingMeta meta entry and ends written lines
+	// with it, so reading and writing a file reproduces it byte for byte
+	KeepLineEndings bool
+	// Numbers controls how non-text values are rendered
+	Numbers NumberFormat
+}
+
+// LineEndingMeta is the meta entry holding the terminator a line had in its source, set by
+// a LineCodec keeping line endings: "\n", "\r\n", or "\r" or "" for the last line.
+const LineEndingMeta = "line.ending"
 
 // Ensure LineCodec implements all interfaces
 var _ ReadCodec = (*LineCodec)(nil)
@@ -22,9 +33,27 @@ func NewLineCodec() *LineCodec {
 	return &LineCodec{}
 }
 
+// WithNumberFormat sets how numbers and other non-text values are rendered
+func (c *LineCodec) WithNumberFormat(format NumberFormat) *LineCodec {
+	c.Numbers = format
+	return c
+}
+
+// WithLineEndings records each line's terminator in the LineEndingMeta meta entry, leaving
+// the text itself without it, and ends written lines with the recorded terminator, or
+// "\n" for messages without one. Use it on both the read and write side for exact round
+// trips; the default codec of text files and unregistered extensions keeps them.
+func (c *LineCodec) WithLineEndings() *LineCodec {
+	c.KeepLineEndings = true
+	return c
+}
+
 func (c *LineCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.Pipe) error {
 	defer pipe.Close()
 	scanner := bufio.NewScanner(reader)
+	if c.KeepLineEndings {
+		scanner.Split(scanLinesWithEndings)
+	}
 
 	for scanner.Scan() {
 		select {
@@ -32,23 +61,25 @@ func (c *LineCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.P
 			return nil
 		default:
 			text := scanner.Text()
-			msg := pipeline.Msg{
-				ID:   uuid.NewString(),
-				Data: text,
+			msg := pipeline.NewMsg(pipeline.NewID(ctx), text)
+
+			if c.KeepLineEndings {
+				var ending string
+				text, ending = splitLineEnding(text)
+				msg = msg.WithData(text).WithMeta(LineEndingMeta, ending)
 			}
 
 			slog.Debug("parsed line", "line", text, "msg_id", msg.ID)
 
-			select {
-			case pipe.Out() <- msg:
-			case <-ctx.Done():
+			if err := pipe.Send(ctx, msg); err != nil {
 				return nil
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return err
+		// the scanner keeps its default buffer
+		return scanErr(err, bufio.MaxScanTokenSize)
 	}
 
 	return nil
@@ -56,7 +87,13 @@ func (c *LineCodec) Parse(ctx context.Context, reader io.Reader, pipe pipeline.P
 
 // Encode implements WriteCodec interface for LineCodec
 func (c *LineCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writer) error {
-	line := castDataToLine(msg.Data)
+	line := castDataToLine(msg.Data, c.Numbers)
+
+	if ending, ok := msg.MetaValue(LineEndingMeta); ok && c.KeepLineEndings {
+		if ending, ok := ending.(string); ok {
+			line = append(line[:len(line)-1], ending...)
+		}
+	}
 
 	slog.Debug("encoded line", "line", line, "msg_id", msg.ID)
 
@@ -67,13 +104,42 @@ func (c *LineCodec) Encode(ctx context.Context, msg pipeline.Msg, writer io.Writ
 	return nil
 }
 
-func castDataToLine(data any) []byte {
+func castDataToLine(data any, numbers NumberFormat) []byte {
 	switch v := data.(type) {
 	case string:
 		return []byte(v + "\n")
 	case []byte:
 		return append(v, '\n')
 	default:
-		return []byte(fmt.Sprintf("%v\n", v))
+		return []byte(numbers.Format(v) + "\n")
 	}
 }
+
+// splitLineEnding splits the terminator off a line read with scanLinesWithEndings.
+func splitLineEnding(line string) (text, ending string) {
+	// a lone "\r" can only end the last line, which bufio.ScanLines would drop
+	for _, terminator := range []string{"\r\n", "\n", "\r"} {
+		if before, ok := strings.CutSuffix(line, terminator); ok {
+			return before, terminator
+		}
+	}
+
+	return line, ""
+}
+
+// scanLinesWithEndings is bufio.ScanLines keeping the line terminator in the token.
+func scanLinesWithEndings(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
@@ -352,3 +352,34 @@ func TestLineCodec_Interfaces(t *testing.T) {
 		assert.NotNil(t, codec)
 	})
 }
+
+func TestLineCodec_WithLineEndings(t *testing.T) {
+	codec := filesystem.NewLineCodec().WithLineEndings()
+	pipe := pipeline.NewChanPipe()
+
+	go func() {
+		err := codec.Parse(context.Background(), strings.NewReader("a\r\nb\nc"), pipe)
+		assert.NoError(t, err)
+	}()
+
+	var (
+		msgs    []pipeline.Msg
+		lines   []string
+		endings []any
+	)
+	for msg := range pipe.Out() {
+		msgs = append(msgs, msg)
+		lines = append(lines, msg.Data.(string))
+		ending, _ := msg.MetaValue(filesystem.LineEndingMeta)
+		endings = append(endings, ending)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, lines, "data holds the text without its terminator")
+	assert.Equal(t, []any{"\r\n", "\n", ""}, endings)
+
+	var buf bytes.Buffer
+	for _, msg := range msgs {
+		require.NoError(t, codec.Encode(context.Background(), msg, &buf))
+	}
+	require.NoError(t, codec.Encode(context.Background(), pipeline.Msg{Data: 42}, &buf))
+	assert.Equal(t, "a\r\nb\nc42\n", buf.String())
+}
@@ -0,0 +1,47 @@
+package filesystem
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// NumberFormat controls how text codecs render values, in particular the float64 numbers
+// produced by decoding JSON. The zero value renders everything with %v, so 1000000.0
+// becomes "1e+06".
+type NumberFormat struct {
+	// IntegerDetection when true, renders floats without a fractional part as integers,
+	// so 1000000.0 becomes "1000000" and 30.0 becomes "30"
+	IntegerDetection bool
+	// Precision when positive, is the fixed number of decimals used for other floats,
+	// otherwise the shortest representation that reads back to the same value is used
+	Precision int
+}
+
+// Format renders v as text according to the format.
+func (f NumberFormat) Format(v any) string {
+	switch n := v.(type) {
+	case float64:
+		return f.formatFloat(n, 64)
+	case float32:
+		return f.formatFloat(float64(n), 32)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func (f NumberFormat) formatFloat(n float64, bitSize int) string {
+	if math.IsInf(n, 0) || math.IsNaN(n) {
+		return fmt.Sprintf("%v", n)
+	}
+
+	if f.IntegerDetection && n == math.Trunc(n) {
+		return strconv.FormatFloat(n, 'f', 0, bitSize)
+	}
+
+	if f.Precision > 0 {
+		return strconv.FormatFloat(n, 'f', f.Precision, bitSize)
+	}
+
+	return strconv.FormatFloat(n, 'g', -1, bitSize)
+}
@@ -0,0 +1,73 @@
+package filesystem_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumberFormat_Format(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   filesystem.NumberFormat
+		value    any
+		expected string
+	}{
+		{name: "default keeps %v", value: 1000000.0, expected: "1e+06"},
+		{name: "default fraction", value: 0.1, expected: "0.1"},
+		{name: "integer detection", format: filesystem.NumberFormat{IntegerDetection: true}, value: 1000000.0, expected: "1000000"},
+		{name: "integer detection small", format: filesystem.NumberFormat{IntegerDetection: true}, value: 30.0, expected: "30"},
+		{name: "integer detection negative", format: filesystem.NumberFormat{IntegerDetection: true}, value: -2.0e9, expected: "-2000000000"},
+		{name: "integer detection keeps fractions", format: filesystem.NumberFormat{IntegerDetection: true}, value: 1.5, expected: "1.5"},
+		{name: "integer detection float32", format: filesystem.NumberFormat{IntegerDetection: true}, value: float32(1e6), expected: "1000000"},
+		{name: "precision", format: filesystem.NumberFormat{Precision: 2}, value: 3.14159, expected: "3.14"},
+		{name: "precision with integer detection", format: filesystem.NumberFormat{IntegerDetection: true, Precision: 2}, value: 42.0, expected: "42"},
+		{name: "infinity", format: filesystem.NumberFormat{IntegerDetection: true}, value: math.Inf(1), expected: "+Inf"},
+		{name: "non float", format: filesystem.NumberFormat{Precision: 2}, value: 7, expected: "7"},
+		{name: "bool", value: true, expected: "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.format.Format(tt.value))
+		})
+	}
+}
+
+func TestNumberFormat_Codecs(t *testing.T) {
+	format := filesystem.NumberFormat{IntegerDetection: true}
+	csvCodec := filesystem.NewCSVCodec().WithNumberFormat(format)
+	csvCodec.Headers = []string{"name", "total"}
+	msg := pipeline.Msg{Data: 1000000.0}
+
+	tests := []struct {
+		name     string
+		codec    filesystem.WriteCodec
+		msg      pipeline.Msg
+		expected string
+	}{
+		{name: "line", codec: filesystem.NewLineCodec().WithNumberFormat(format), msg: msg, expected: "1000000\n"},
+		{name: "blob", codec: filesystem.NewBlobCodec().WithNumberFormat(format), msg: msg, expected: "1000000"},
+		{
+			name:     "csv",
+			codec:    csvCodec,
+			msg:      pipeline.Msg{Data: map[string]any{"name": "a", "total": 1000000.0}},
+			expected: "a,1000000\n",
+		},
+		{name: "line without format", codec: filesystem.NewLineCodec(), msg: msg, expected: "1e+06\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, tt.codec.Encode(context.Background(), tt.msg, &buf))
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
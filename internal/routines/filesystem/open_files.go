@@ -0,0 +1,105 @@
+package filesystem
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// fallbackMaxOpenFiles is the open file cap where the descriptor limit cannot be read.
+const fallbackMaxOpenFiles = 256
+
+// defaultMaxOpenFiles caps the files a routine keeps open at half the soft descriptor
+// limit, leaving the rest to the other routines of the script.
+func defaultMaxOpenFiles() int {
+	limit := openFileLimit()
+	if limit <= 0 {
+		return fallbackMaxOpenFiles
+	}
+
+	return max(limit/2, 1)
+}
+
+// openFiles keeps at most size buffered files open, closing the least recently used one
+// to make room for another. Files are opened in append mode, so a closed file can be
+// reopened later without losing what was written to it.
+type openFiles struct {
+	size       int
+	bufferSize int
+	closeFile  func(file *os.File)
+
+	order *list.List // of *bufferedFile, most recently used first
+	files map[string]*list.Element
+}
+
+func newOpenFiles(size, bufferSize int, closeFile func(file *os.File)) *openFiles {
+	return &openFiles{
+		size:       max(size, 1),
+		bufferSize: bufferSize,
+		closeFile:  closeFile,
+		order:      list.New(),
+		files:      make(map[string]*list.Element),
+	}
+}
+
+// get returns the open file for path, opening it and closing the least recently used
+// file first if the cap is reached.
+func (o *openFiles) get(path string) (*bufferedFile, error) {
+	if elem, ok := o.files[path]; ok {
+		o.order.MoveToFront(elem)
+		return elem.Value.(*bufferedFile), nil
+	}
+
+	if o.order.Len() >= o.size {
+		if err := o.evict(o.order.Back()); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := openWritingFile(path, modeWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	bf := &bufferedFile{path: path, file: file, writer: bufio.NewWriterSize(file, o.bufferSize)}
+	o.files[path] = o.order.PushFront(bf)
+
+	return bf, nil
+}
+
+func (o *openFiles) evict(elem *list.Element) error {
+	bf := o.order.Remove(elem).(*bufferedFile)
+	delete(o.files, bf.path)
+
+	err := bf.writer.Flush()
+	o.closeFile(bf.file)
+	if err != nil {
+		return fmt.Errorf("failed to flush file %s: %w", bf.path, err)
+	}
+
+	return nil
+}
+
+// each calls fn with every open file.
+func (o *openFiles) each(fn func(bf *bufferedFile)) {
+	for elem := o.order.Front(); elem != nil; elem = elem.Next() {
+		fn(elem.Value.(*bufferedFile))
+	}
+}
+
+// closeAll flushes and closes every open file.
+func (o *openFiles) closeAll() error {
+	var errs []error
+	for o.order.Len() > 0 {
+		errs = append(errs, o.evict(o.order.Front()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Len reports how many files are open.
+func (o *openFiles) Len() int {
+	return o.order.Len()
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package filesystem
+
+// openFileLimit reports no limit, leaving the fallback cap in place.
+func openFileLimit() int {
+	return 0
+}
@@ -0,0 +1,72 @@
+package filesystem_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenFiles(t *testing.T) {
+	t.Run("recycles handles across many partitions without losing writes", func(t *testing.T) {
+		dir := t.TempDir()
+		files := filesystem.NewOpenFiles(3)
+
+		const partitions, rounds = 40, 5
+		for round := range rounds {
+			for p := range partitions {
+				path := filepath.Join(dir, fmt.Sprintf("part-%02d.txt", p))
+				require.NoError(t, files.WriteString(path, fmt.Sprintf("round-%d\n", round)))
+				assert.LessOrEqual(t, files.Len(), 3)
+			}
+		}
+		require.NoError(t, files.CloseAll())
+		assert.Zero(t, files.Len())
+
+		var want strings.Builder
+		for round := range rounds {
+			fmt.Fprintf(&want, "round-%d\n", round)
+		}
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, partitions)
+		for _, entry := range entries {
+			content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			require.NoError(t, err)
+			assert.Equal(t, want.String(), string(content), entry.Name())
+		}
+	})
+
+	t.Run("keeps the most recently used files open", func(t *testing.T) {
+		dir := t.TempDir()
+		files := filesystem.NewOpenFiles(2)
+		path := func(name string) string { return filepath.Join(dir, name) }
+
+		require.NoError(t, files.WriteString(path("a"), "a1\n"))
+		require.NoError(t, files.WriteString(path("b"), "b1\n"))
+		require.NoError(t, files.WriteString(path("a"), "a2\n"))
+		// b is the least recently used, so it is flushed and closed to make room
+		require.NoError(t, files.WriteString(path("c"), "c1\n"))
+
+		content, err := os.ReadFile(path("b"))
+		require.NoError(t, err)
+		assert.Equal(t, "b1\n", string(content))
+
+		// a is still buffered
+		content, err = os.ReadFile(path("a"))
+		require.NoError(t, err)
+		assert.Empty(t, content)
+
+		require.NoError(t, files.CloseAll())
+
+		content, err = os.ReadFile(path("a"))
+		require.NoError(t, err)
+		assert.Equal(t, "a1\na2\n", string(content))
+	})
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package filesystem
+
+import "syscall"
+
+// openFileLimit returns the soft limit on open file descriptors, as shown by ulimit -n.
+func openFileLimit() int {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0
+	}
+
+	return int(min(limit.Cur, 1<<20))
+}
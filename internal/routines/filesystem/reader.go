@@ -0,0 +1,37 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// ReadReaderRoutine parses an in-memory or otherwise already open reader with a codec,
+// the same way ReadFileRoutine parses a file.
+type ReadReaderRoutine struct {
+	reader    io.Reader
+	readCodec ReadCodec
+}
+
+// Reader creates a source that parses reader line by line unless another codec is set.
+func Reader(reader io.Reader) *ReadReaderRoutine {
+	return &ReadReaderRoutine{reader: reader, readCodec: NewLineCodec()}
+}
+
+func (r *ReadReaderRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if err := Parse(ctx, r.readCodec, r.reader, pipe); err != nil {
+		return fmt.Errorf("%w reader with codec: %w", ErrCodecParse, err)
+	}
+
+	return nil
+}
+
+// WithCodec sets the codec for parsing the reader
+func (r *ReadReaderRoutine) WithCodec(codec ReadCodec) *ReadReaderRoutine {
+	r.readCodec = codec
+	return r
+}
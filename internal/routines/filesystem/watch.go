@@ -0,0 +1,193 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchDir creates a source that ingests every file matching pattern in dir, first the
+// files already present and then each new file as it is created (drop-folder pattern).
+// Files are parsed with the codec chosen from their extension unless one is set with WithCodec.
+//
+// Producers should move complete files into dir (e.g. write to a temporary location and
+// rename), since files are read as soon as their creation is observed.
+func WatchDir(dir string, pattern string) *WatchDirRoutine {
+	return &WatchDirRoutine{dir: dir, pattern: pattern}
+}
+
+// WatchDirRoutine emits the parsed content of files dropped into a directory
+type WatchDirRoutine struct {
+	dir       string
+	pattern   string
+	readCodec ReadCodec
+
+	deleteProcessed bool
+	moveProcessedTo string
+}
+
+// WithCodec sets the codec used to parse every watched file
+func (w *WatchDirRoutine) WithCodec(codec ReadCodec) *WatchDirRoutine {
+	w.readCodec = codec
+	return w
+}
+
+// DeleteProcessed removes each file once it has been fully processed
+func (w *WatchDirRoutine) DeleteProcessed() *WatchDirRoutine {
+	w.deleteProcessed = true
+	return w
+}
+
+// MoveProcessed moves each file into dir once it has been fully processed
+func (w *WatchDirRoutine) MoveProcessed(dir string) *WatchDirRoutine {
+	w.moveProcessedTo = dir
+	return w
+}
+
+func (w *WatchDirRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if _, err := filepath.Match(w.pattern, ""); err != nil {
+		return fmt.Errorf("invalid watch pattern %q: %w", w.pattern, err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create directory watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// watch before listing so no file created in between is missed
+	if err := watcher.Add(w.dir); err != nil {
+		return fmt.Errorf("failed to watch directory %s: %w", w.dir, err)
+	}
+
+	slog.Info("watching directory", "dir", w.dir, "pattern", w.pattern)
+
+	// processed holds the files read and still in dir, since a file can be both listed and
+	// reported as created; a file removed or renamed away is forgotten, so a new file
+	// dropped under the same name is read too
+	processed := make(map[string]struct{})
+
+	backlog, err := filepath.Glob(filepath.Join(w.dir, w.pattern))
+	if err != nil {
+		return fmt.Errorf("failed to list directory %s: %w", w.dir, err)
+	}
+	sort.Strings(backlog)
+
+	for _, path := range backlog {
+		if !w.processOnce(ctx, path, pipe, processed) {
+			return nil
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				delete(processed, event.Name)
+				continue
+			}
+
+			if event.Op&fsnotify.Create == 0 {
+				continue
+			}
+
+			if !w.processOnce(ctx, event.Name, pipe, processed) {
+				return nil
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			slog.Error("directory watcher error", "dir", w.dir, "error", err)
+		}
+	}
+}
+
+// processOnce parses path if it matches and was not seen yet. It returns false once ctx is done.
+func (w *WatchDirRoutine) processOnce(
+	ctx context.Context,
+	path string,
+	pipe pipeline.Pipe,
+	processed map[string]struct{},
+) bool {
+	if _, seen := processed[path]; seen {
+		return true
+	}
+
+	matched, _ := filepath.Match(w.pattern, filepath.Base(path))
+	if !matched {
+		return true
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return true
+	}
+
+	processed[path] = struct{}{}
+
+	if err := w.processFile(ctx, path, pipe); err != nil {
+		slog.Error("failed to process watched file", "path", path, "error", err)
+		return ctx.Err() == nil
+	}
+
+	if err := w.finalize(path); err != nil {
+		slog.Error("failed to finalize watched file", "path", path, "error", err)
+	} else if w.deleteProcessed || w.moveProcessedTo != "" {
+		delete(processed, path)
+	}
+
+	return ctx.Err() == nil
+}
+
+func (w *WatchDirRoutine) processFile(ctx context.Context, path string, pipe pipeline.Pipe) error {
+	slog.Info("reading watched file", "path", path)
+
+	file, err := os.OpenFile(path, modeRead, 0)
+	if err != nil {
+		return fmt.Errorf("%w for read: %w", ErrFileOpen, err)
+	}
+	defer file.Close()
+
+	readCodec := w.readCodec
+	if readCodec == nil {
+		readCodec = buildReadCodec(path)
+	}
+
+	// codecs close the pipe they write to, so each file is parsed on its own sub-pipe
+	if err := parseInto(ctx, readCodec, file, pipe, 0); err != nil {
+		return fmt.Errorf("%w file with codec: %w", ErrCodecParse, err)
+	}
+
+	return nil
+}
+
+func (w *WatchDirRoutine) finalize(path string) error {
+	switch {
+	case w.moveProcessedTo != "":
+		if err := os.MkdirAll(w.moveProcessedTo, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", w.moveProcessedTo, err)
+		}
+
+		return os.Rename(path, filepath.Join(w.moveProcessedTo, filepath.Base(path)))
+	case w.deleteProcessed:
+		return os.Remove(path)
+	default:
+		return nil
+	}
+}
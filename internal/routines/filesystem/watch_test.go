@@ -0,0 +1,181 @@
+package filesystem_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dropFile writes content outside dir and renames it in, as a drop-folder producer would.
+func dropFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	staging := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(staging, []byte(content), 0644))
+	require.NoError(t, os.Rename(staging, filepath.Join(dir, name)))
+}
+
+// receiveN reads exactly n messages from pipe or fails after timeout.
+func receiveN(t *testing.T, pipe pipeline.Pipe, n int) []string {
+	t.Helper()
+
+	var results []string
+	for len(results) < n {
+		select {
+		case msg := <-pipe.Out():
+			results = append(results, msg.Data.(string))
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after receiving %d of %d messages: %v", len(results), n, results)
+		}
+	}
+
+	return results
+}
+
+func TestWatchDirRoutine_Start(t *testing.T) {
+	t.Run("processes backlog and new files exactly once", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a1\na2"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "ignored.csv"), []byte("x,y"), 0644))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- filesystem.WatchDir(dir, "*.txt").Start(ctx, pipe)
+		}()
+
+		assert.Equal(t, []string{"a1", "a2"}, receiveN(t, pipe, 2))
+
+		dropFile(t, dir, "b.txt", "b1")
+		assert.Equal(t, []string{"b1"}, receiveN(t, pipe, 1))
+
+		dropFile(t, dir, "c.txt", "c1\nc2")
+		assert.Equal(t, []string{"c1", "c2"}, receiveN(t, pipe, 2))
+
+		// no file is processed twice
+		select {
+		case msg := <-pipe.Out():
+			t.Fatalf("unexpected extra message: %v", msg)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("deletes processed files", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "a.txt")
+		require.NoError(t, os.WriteFile(path, []byte("a1"), 0644))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- filesystem.WatchDir(dir, "*.txt").DeleteProcessed().Start(ctx, pipe)
+		}()
+
+		receiveN(t, pipe, 1)
+		cancel()
+		require.NoError(t, <-done)
+
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("moves processed files", func(t *testing.T) {
+		dir := t.TempDir()
+		archive := filepath.Join(t.TempDir(), "archive")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- filesystem.WatchDir(dir, "*.txt").MoveProcessed(archive).Start(ctx, pipe)
+		}()
+
+		// give the watcher time to register before dropping
+		time.Sleep(50 * time.Millisecond)
+		dropFile(t, dir, "a.txt", "a1")
+
+		receiveN(t, pipe, 1)
+		cancel()
+		require.NoError(t, <-done)
+
+		assert.NoFileExists(t, filepath.Join(dir, "a.txt"))
+		assert.FileExists(t, filepath.Join(archive, "a.txt"))
+	})
+
+	t.Run("reads a file dropped again under the same name", func(t *testing.T) {
+		dir := t.TempDir()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- filesystem.WatchDir(dir, "*.txt").Start(ctx, pipe)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		dropFile(t, dir, "a.txt", "first")
+		assert.Equal(t, []string{"first"}, receiveN(t, pipe, 1))
+
+		require.NoError(t, os.Remove(filepath.Join(dir, "a.txt")))
+		time.Sleep(50 * time.Millisecond)
+
+		dropFile(t, dir, "a.txt", "second")
+		assert.Equal(t, []string{"second"}, receiveN(t, pipe, 1))
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("reads a new file under the name of a processed one", func(t *testing.T) {
+		dir := t.TempDir()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- filesystem.WatchDir(dir, "*.txt").DeleteProcessed().Start(ctx, pipe)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		for _, content := range []string{"one", "two", "three"} {
+			dropFile(t, dir, "a.txt", content)
+			assert.Equal(t, []string{content}, receiveN(t, pipe, 1))
+
+			require.Eventually(t, func() bool {
+				_, err := os.Stat(filepath.Join(dir, "a.txt"))
+				return os.IsNotExist(err)
+			}, 2*time.Second, 5*time.Millisecond)
+		}
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("returns error for missing directory", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		err := filesystem.WatchDir(filepath.Join(t.TempDir(), "missing"), "*").Start(context.Background(), pipe)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,40 @@
+package routines
+
+import (
+	"context"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// GenerateRoutine is a source emitting synthetic messages, e.g. to benchmark downstream
+// stages without real input. Paired with Discard it measures processing alone.
+type GenerateRoutine struct {
+	n   int
+	gen func(i int) any
+}
+
+// Generate emits n messages whose data is gen(i), for i counting from 0. A negative n
+// generates until ctx is done.
+//
+// Example:
+//
+//	script.In(routines.Generate(1_000_000, func(i int) any { return map[string]any{"id": i} }))
+func Generate(n int, gen func(i int) any) *GenerateRoutine {
+	return &GenerateRoutine{n: n, gen: gen}
+}
+
+func (g *GenerateRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for i := 0; g.n < 0 || i < g.n; i++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err := pipe.Send(ctx, pipeline.NewMsg(pipeline.NewID(ctx), g.gen(i))); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRoutine_Start(t *testing.T) {
+	square := func(i int) any { return i * i }
+
+	t.Run("emits n generated messages", func(t *testing.T) {
+		results := runRoutine(t, routines.Generate(5, square), nil)
+
+		data := make([]any, len(results))
+		for i, msg := range results {
+			data[i] = msg.Data
+		}
+		assert.Equal(t, []any{0, 1, 4, 9, 16}, data)
+	})
+
+	t.Run("emits nothing for zero", func(t *testing.T) {
+		assert.Empty(t, runRoutine(t, routines.Generate(0, square), nil))
+	})
+
+	t.Run("generates until cancelled when n is negative", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Generate(-1, square).Start(ctx, pipe)
+		}()
+
+		for i := range 1000 {
+			msg := <-pipe.Out()
+			require.Equal(t, i*i, msg.Data)
+		}
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("generator did not stop after cancellation")
+		}
+
+		for range pipe.Out() {
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// GrepContextRoutine works like grep -B before -A after over a stream of lines: it emits
+// each line matching pattern along with up to before lines preceding it and after lines
+// following it. Overlapping windows are merged, so every line is emitted at most once and
+// in input order. Unlike grep, no "--" separator is emitted between groups.
+//
+// Lines must be string or []byte messages; other messages never match but still count as
+// context lines.
+type GrepContextRoutine struct {
+	pattern string
+	before  int
+	after   int
+}
+
+func GrepContext(pattern string, before, after int) *GrepContextRoutine {
+	return &GrepContextRoutine{pattern: pattern, before: before, after: after}
+}
+
+// Stateful marks GrepContextRoutine as a StatefulRoutine, since the context lines of a
+// match are its neighbours in the stream.
+func (g *GrepContextRoutine) Stateful() {}
+
+func (g *GrepContextRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	re, err := regexp.Compile(g.pattern)
+	if err != nil {
+		return fmt.Errorf("invalid grep pattern %q: %w", g.pattern, err)
+	}
+
+	if g.before < 0 || g.after < 0 {
+		return fmt.Errorf("grep context must not be negative, got before %d and after %d", g.before, g.after)
+	}
+
+	// recent holds the lines seen since the last emitted one, up to before of them
+	recent := newMsgRing(g.before)
+	// pending counts the lines still owed to the after window of the last match
+	pending := 0
+
+	emit := func(msg pipeline.Msg) bool {
+		return pipe.Send(ctx, msg) == nil
+	}
+
+	for msg := range pipe.In() {
+		switch {
+		case matchLine(re, msg.Data):
+			for _, prev := range recent.drain() {
+				if !emit(prev) {
+					return nil
+				}
+			}
+
+			if !emit(msg) {
+				return nil
+			}
+
+			pending = g.after
+		case pending > 0:
+			if !emit(msg) {
+				return nil
+			}
+
+			pending--
+		default:
+			recent.push(msg)
+		}
+	}
+
+	return nil
+}
+
+func matchLine(re *regexp.Regexp, data any) bool {
+	switch v := data.(type) {
+	case string:
+		return re.MatchString(v)
+	case []byte:
+		return re.Match(v)
+	default:
+		return false
+	}
+}
+
+// msgRing keeps the last size messages pushed, overwriting the oldest.
+type msgRing struct {
+	msgs  []pipeline.Msg
+	start int
+	len   int
+}
+
+func newMsgRing(size int) *msgRing {
+	return &msgRing{msgs: make([]pipeline.Msg, size)}
+}
+
+func (r *msgRing) push(msg pipeline.Msg) {
+	if len(r.msgs) == 0 {
+		return
+	}
+
+	if r.len < len(r.msgs) {
+		r.msgs[(r.start+r.len)%len(r.msgs)] = msg
+		r.len++
+		return
+	}
+
+	r.msgs[r.start] = msg
+	r.start = (r.start + 1) % len(r.msgs)
+}
+
+// drain returns the kept messages oldest first and empties the ring.
+func (r *msgRing) drain() []pipeline.Msg {
+	out := make([]pipeline.Msg, r.len)
+	for i := range r.len {
+		out[i] = r.msgs[(r.start+i)%len(r.msgs)]
+	}
+
+	r.start, r.len = 0, 0
+
+	return out
+}
@@ -0,0 +1,80 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrepContextRoutine_Start(t *testing.T) {
+	text := []string{
+		"alpha", "beta", "error: disk", "gamma", "delta", "epsilon",
+		"error: net", "zeta", "eta", "theta", "iota", "error: cpu",
+	}
+
+	input := make([]pipeline.Msg, len(text))
+	for i, line := range text {
+		input[i] = pipeline.Msg{Data: line}
+	}
+
+	tests := []struct {
+		name     string
+		before   int
+		after    int
+		expected []string
+	}{
+		{
+			name:     "matches only",
+			expected: []string{"error: disk", "error: net", "error: cpu"},
+		},
+		{
+			name:     "grep -B 1 -A 1",
+			before:   1,
+			after:    1,
+			expected: []string{"beta", "error: disk", "gamma", "epsilon", "error: net", "zeta", "iota", "error: cpu"},
+		},
+		{
+			name:     "grep -B 2 -A 2 merges overlapping windows",
+			before:   2,
+			after:    2,
+			expected: text,
+		},
+		{
+			name:     "grep -A 3 at end of stream",
+			after:    3,
+			expected: []string{"error: disk", "gamma", "delta", "epsilon", "error: net", "zeta", "eta", "theta", "error: cpu"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := runRoutine(t, routines.GrepContext("^error:", tt.before, tt.after), input)
+
+			var lines []string
+			for _, msg := range results {
+				lines = append(lines, msg.Data.(string))
+			}
+
+			assert.Equal(t, tt.expected, lines)
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.GrepContext("(", 1, 1).Start(context.Background(), pipe)
+		assert.ErrorContains(t, err, "invalid grep pattern")
+	})
+
+	t.Run("byte lines", func(t *testing.T) {
+		byteInput := []pipeline.Msg{{Data: []byte("ok")}, {Data: []byte("error: io")}}
+
+		results := runRoutine(t, routines.GrepContext("error", 0, 0), byteInput)
+
+		assert.Equal(t, []pipeline.Msg{byteInput[1]}, results)
+	})
+}
@@ -0,0 +1,60 @@
+package routines
+
+import (
+	"context"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// GroupByRoutine collects the data of messages into groups sharing a key and emits one
+// message per group once the input closes.
+//
+// Every message is buffered until then, so memory grows with the input.
+type GroupByRoutine struct {
+	key func(pipeline.Msg) string
+}
+
+// GroupBy groups messages by keyFn. Each group is emitted as a Keyed[string, []any]
+// holding the key and the data of its messages in input order, and groups are emitted in
+// the order their keys were first seen.
+//
+// Example:
+//
+//	byCustomer := func(msg pipeline.Msg) string { return msg.Data.(map[string]any)["customer"].(string) }
+//	script.Chain(routines.GroupBy(byCustomer))
+func GroupBy(keyFn func(pipeline.Msg) string) *GroupByRoutine {
+	return &GroupByRoutine{key: keyFn}
+}
+
+// Stateful marks GroupByRoutine as a StatefulRoutine, since a group collects every
+// message of its key.
+func (g *GroupByRoutine) Stateful() {}
+
+func (g *GroupByRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	groups := make(map[string][]any)
+	var keys []string
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-pipe.In():
+			if !ok {
+				for _, key := range keys {
+					if err := pipe.Send(ctx, pipeline.Msg{ID: pipeline.NewID(ctx), Data: Keyed[string, []any]{Key: key, Value: groups[key]}}); err != nil {
+						return nil
+					}
+				}
+				return nil
+			}
+
+			key := g.key(msg)
+			if _, seen := groups[key]; !seen {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], msg.Data)
+		}
+	}
+}
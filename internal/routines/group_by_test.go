@@ -0,0 +1,70 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupByRoutine_Start(t *testing.T) {
+	byInitial := func(msg pipeline.Msg) string { return msg.Data.(string)[:1] }
+
+	words := func(data ...string) []pipeline.Msg {
+		msgs := make([]pipeline.Msg, len(data))
+		for i, d := range data {
+			msgs[i] = pipeline.Msg{Data: d}
+		}
+		return msgs
+	}
+
+	t.Run("collects a single group", func(t *testing.T) {
+		results := runRoutine(t, routines.GroupBy(byInitial), words("apple", "avocado", "apricot"))
+
+		require.Len(t, results, 1)
+		assert.Equal(t, routines.Keyed[string, []any]{Key: "a", Value: []any{"apple", "avocado", "apricot"}}, results[0].Data)
+		assert.NotEmpty(t, results[0].ID)
+	})
+
+	t.Run("emits many groups in first-seen key order", func(t *testing.T) {
+		results := runRoutine(t, routines.GroupBy(byInitial), words("pear", "apple", "fig", "plum", "avocado", "peach"))
+
+		assert.Equal(t, []any{
+			routines.Keyed[string, []any]{Key: "p", Value: []any{"pear", "plum", "peach"}},
+			routines.Keyed[string, []any]{Key: "a", Value: []any{"apple", "avocado"}},
+			routines.Keyed[string, []any]{Key: "f", Value: []any{"fig"}},
+		}, msgData(results))
+	})
+
+	t.Run("emits nothing without input", func(t *testing.T) {
+		assert.Empty(t, runRoutine(t, routines.GroupBy(byInitial), nil))
+	})
+
+	t.Run("stops without emitting when cancelled mid-stream", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.GroupBy(byInitial).Start(ctx, pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: "a"}
+		pipe.In() <- pipeline.Msg{Data: "b"}
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("group by did not stop after cancellation")
+		}
+
+		_, open := <-pipe.Out()
+		assert.False(t, open, "no group is emitted for an incomplete input")
+	})
+}
@@ -0,0 +1,235 @@
+package routines
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+)
+
+// HTTPRoutine configures HTTP sources: the client used for requests, where a page keeps
+// its items and how they are parsed, how fast pages may be requested and how failed
+// requests are retried.
+type HTTPRoutine struct {
+	client     *http.Client
+	itemsField string
+	itemsCodec filesystem.ReadCodec
+	interval   time.Duration
+
+	maxRetries int
+	backoff    BackoffPolicy
+}
+
+func HTTP() *HTTPRoutine {
+	return &HTTPRoutine{
+		client:     http.DefaultClient,
+		itemsField: "items",
+		itemsCodec: filesystem.NewJSONCodec(),
+	}
+}
+
+func (h *HTTPRoutine) WithClient(client *http.Client) *HTTPRoutine {
+	h.client = client
+	return h
+}
+
+// WithItemsField sets the JSON field holding each page's items. Defaults to "items".
+func (h *HTTPRoutine) WithItemsField(field string) *HTTPRoutine {
+	h.itemsField = field
+	return h
+}
+
+// WithCodec parses the items of each page with codec. Defaults to the JSON codec, which
+// emits each element of an items array as a message; filesystem.WithJSONType decodes them
+// into a struct instead.
+func (h *HTTPRoutine) WithCodec(codec filesystem.ReadCodec) *HTTPRoutine {
+	h.itemsCodec = codec
+	return h
+}
+
+// WithRateLimit waits at least interval between consecutive requests.
+func (h *HTTPRoutine) WithRateLimit(interval time.Duration) *HTTPRoutine {
+	h.interval = interval
+	return h
+}
+
+// WithRetry retries a failed request up to maxRetries times, waiting policy.Next between
+// attempts, or per DefaultBackoff with a nil policy. Network errors, 429 and 5xx responses
+// are retried; other responses fail at once.
+func (h *HTTPRoutine) WithRetry(maxRetries int, policy BackoffPolicy) *HTTPRoutine {
+	h.maxRetries = maxRetries
+	h.backoff = policy
+	return h
+}
+
+// Paginate returns a source that fetches JSON pages sequentially, starting at startURL.
+// The page's items field is parsed with the configured codec, by default emitting each of
+// its elements as a message; a page without that field is parsed whole. After each page
+// nextFn returns the next URL, which may be relative to the current one, and whether to
+// continue.
+//
+// Example:
+//
+//	next := func(page map[string]any) (string, bool) {
+//		link, ok := page["next"].(string)
+//		return link, ok && link != ""
+//	}
+//	script.In(routines.HTTP().WithRateLimit(time.Second).Paginate("https://api.example.com/users", next))
+func (h *HTTPRoutine) Paginate(startURL string, nextFn func(resp map[string]any) (string, bool)) *HTTPPaginateRoutine {
+	return &HTTPPaginateRoutine{http: h, startURL: startURL, nextFn: nextFn}
+}
+
+type HTTPPaginateRoutine struct {
+	http     *HTTPRoutine
+	startURL string
+	nextFn   func(resp map[string]any) (string, bool)
+}
+
+func (p *HTTPPaginateRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	pageURL, err := url.Parse(p.startURL)
+	if err != nil {
+		return fmt.Errorf("invalid pagination url %q: %w", p.startURL, err)
+	}
+
+	var lastRequest time.Time
+
+	for {
+		if wait := p.http.interval - time.Since(lastRequest); !lastRequest.IsZero() && wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+		}
+
+		lastRequest = time.Now()
+
+		var body json.RawMessage
+		if err := p.http.fetchJSON(ctx, pageURL.String(), &body); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var page map[string]any
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to decode page %s: %w", pageURL, err)
+		}
+
+		slog.Debug("fetched page", "url", pageURL.String())
+
+		sent, err := p.emitItems(ctx, p.items(body), pipe)
+		if err != nil {
+			return fmt.Errorf("failed to parse items of page %s: %w", pageURL, err)
+		}
+		if !sent {
+			return nil
+		}
+
+		next, ok := p.nextFn(page)
+		if !ok {
+			return nil
+		}
+
+		nextURL, err := url.Parse(next)
+		if err != nil {
+			return fmt.Errorf("invalid next page url %q: %w", next, err)
+		}
+
+		pageURL = pageURL.ResolveReference(nextURL)
+	}
+}
+
+// fetchJSON requests url and decodes its JSON body into v, retrying per the configured
+// backoff policy.
+func (h *HTTPRoutine) fetchJSON(ctx context.Context, url string, v any) error {
+	return retryWithBackoff(ctx, h.maxRetries, h.backoff, func() (bool, error) {
+		return h.fetchJSONOnce(ctx, url, v)
+	}, "url", url)
+}
+
+// fetchJSONOnce requests url and decodes its JSON body into v, reporting whether a failure
+// is worth retrying.
+func (h *HTTPRoutine) fetchJSONOnce(ctx context.Context, url string, v any) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retryable, fmt.Errorf("failed to fetch %s: unexpected status %s", url, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("failed to decode response of %s: %w", url, err)
+	}
+
+	return false, nil
+}
+
+// items returns the raw items field of a page, or the whole page without one.
+func (p *HTTPPaginateRoutine) items(body json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+
+	items, ok := fields[p.http.itemsField]
+	if !ok || string(items) == "null" {
+		return body
+	}
+
+	return items
+}
+
+// emitItems parses items with the configured codec and sends them downstream, reporting
+// false if the pipe stopped taking them.
+func (p *HTTPPaginateRoutine) emitItems(ctx context.Context, items []byte, pipe pipeline.Pipe) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	itemPipe := pipeline.NewChanPipe()
+
+	errCh := make(chan error, 1)
+	pipeline.Go(ctx, func() {
+		// a recovered panic leaves no error to read
+		defer close(errCh)
+
+		errCh <- filesystem.Parse(ctx, p.http.itemsCodec, bytes.NewReader(items), itemPipe)
+	})
+
+	for msg := range itemPipe.Out() {
+		if err := pipe.Send(ctx, msg); err != nil {
+			cancel()
+			for range itemPipe.Out() {
+			}
+			<-errCh
+			return false, nil
+		}
+	}
+
+	if err := <-errCh; err != nil {
+		return false, err
+	}
+
+	return ctx.Err() == nil, nil
+}
@@ -0,0 +1,248 @@
+package routines
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+const (
+	// LookupKeyPlaceholder is replaced by the path-escaped lookup key in a lookup URL.
+	LookupKeyPlaceholder = "{key}"
+
+	defaultLookupCacheSize = 1024
+	defaultLookupTTL       = 5 * time.Minute
+)
+
+// HTTPLookup returns a routine enriching each message with the JSON document fetched from
+// urlTemplate, whose {key} placeholder is replaced by keyFn(msg). It uses the defaults of
+// HTTP(); see HTTPRoutine.Lookup to configure the client or retries.
+//
+// Example:
+//
+//	user := func(msg pipeline.Msg) string { return msg.Data.(map[string]any)["user_id"].(string) }
+//	script.Chain(routines.HTTPLookup("https://api.example.com/users/{key}", user).WithField("user"))
+func HTTPLookup(urlTemplate string, keyFn func(msg pipeline.Msg) string) *HTTPLookupRoutine {
+	return HTTP().Lookup(urlTemplate, keyFn)
+}
+
+// Lookup returns a routine enriching each message with the JSON document fetched from
+// urlTemplate, whose {key} placeholder is replaced by keyFn(msg).
+//
+// Responses are kept in an LRU cache keyed by URL, so repeated keys are served without a
+// request until their entry expires. When the routine runs under Parallel, messages with
+// the same URL share a single in-flight request.
+func (h *HTTPRoutine) Lookup(urlTemplate string, keyFn func(msg pipeline.Msg) string) *HTTPLookupRoutine {
+	return &HTTPLookupRoutine{
+		http:        h,
+		urlTemplate: urlTemplate,
+		keyFn:       keyFn,
+		cache:       newLookupCache(defaultLookupCacheSize, defaultLookupTTL),
+		inflight:    make(map[string]*lookupCall),
+	}
+}
+
+// HTTPLookupRoutine merges the response of a per-message HTTP lookup into the message.
+//
+// By default the response must be a JSON object, whose fields are set on a copy of the
+// message's map[string]any data, replacing fields of the same name. WithField stores the
+// whole response under one field instead. Messages whose data is not a map, and messages
+// whose lookup fails, are logged and passed through unchanged. Failed lookups are not
+// cached.
+//
+// Cached responses are shared by every message they enrich, so nested values must be
+// treated as read-only downstream.
+type HTTPLookupRoutine struct {
+	http        *HTTPRoutine
+	urlTemplate string
+	keyFn       func(msg pipeline.Msg) string
+	field       string
+
+	mu       sync.Mutex
+	cache    *lookupCache
+	inflight map[string]*lookupCall
+}
+
+// WithField stores the response under field rather than merging its fields into the message.
+func (l *HTTPLookupRoutine) WithField(field string) *HTTPLookupRoutine {
+	l.field = field
+	return l
+}
+
+// WithCache keeps up to size responses for ttl each. A size below 1 disables caching,
+// though concurrent lookups of the same URL are still coalesced. Defaults to 1024
+// responses for 5 minutes.
+func (l *HTTPLookupRoutine) WithCache(size int, ttl time.Duration) *HTTPLookupRoutine {
+	l.cache = newLookupCache(size, ttl)
+	return l
+}
+
+func (l *HTTPLookupRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		msg = l.enrich(ctx, msg)
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// enrich returns msg merged with its lookup response, or msg itself if it cannot be enriched.
+func (l *HTTPLookupRoutine) enrich(ctx context.Context, msg pipeline.Msg) pipeline.Msg {
+	data, isMap := msg.Data.(map[string]any)
+	if !isMap {
+		slog.Error("lookup expects map[string]any messages", "msg_id", msg.ID, "type", fmt.Sprintf("%T", msg.Data))
+		return msg
+	}
+
+	lookupURL := l.render(l.keyFn(msg))
+
+	resp, err := l.fetch(ctx, lookupURL)
+	if err != nil {
+		slog.Error("lookup failed", "url", lookupURL, "msg_id", msg.ID, "error", err)
+		return msg
+	}
+
+	enriched := maps.Clone(data)
+
+	if l.field != "" {
+		enriched[l.field] = resp
+		return msg.WithData(enriched)
+	}
+
+	fields, isObject := resp.(map[string]any)
+	if !isObject {
+		slog.Error("lookup response is not a JSON object", "url", lookupURL, "msg_id", msg.ID)
+		return msg
+	}
+
+	maps.Copy(enriched, fields)
+
+	return msg.WithData(enriched)
+}
+
+func (l *HTTPLookupRoutine) render(key string) string {
+	return strings.ReplaceAll(l.urlTemplate, LookupKeyPlaceholder, url.PathEscape(key))
+}
+
+// lookupCall is a request in flight, shared by every lookup of its URL until it finishes.
+type lookupCall struct {
+	done chan struct{}
+	resp any
+	err  error
+}
+
+// fetch returns the response for lookupURL from the cache, from a request already in
+// flight, or from a new request whose successful response is then cached.
+func (l *HTTPLookupRoutine) fetch(ctx context.Context, lookupURL string) (any, error) {
+	l.mu.Lock()
+
+	if resp, ok := l.cache.get(lookupURL, time.Now()); ok {
+		l.mu.Unlock()
+		return resp, nil
+	}
+
+	if call, ok := l.inflight[lookupURL]; ok {
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-call.done:
+			return call.resp, call.err
+		}
+	}
+
+	call := &lookupCall{done: make(chan struct{})}
+	l.inflight[lookupURL] = call
+	l.mu.Unlock()
+
+	call.err = l.http.fetchJSON(ctx, lookupURL, &call.resp)
+
+	l.mu.Lock()
+	delete(l.inflight, lookupURL)
+	if call.err == nil {
+		l.cache.put(lookupURL, call.resp, time.Now())
+	}
+	l.mu.Unlock()
+
+	close(call.done)
+
+	return call.resp, call.err
+}
+
+// lookupCache is a least recently used cache whose entries expire ttl after being stored.
+// It is not safe for concurrent use.
+type lookupCache struct {
+	size    int
+	ttl     time.Duration
+	order   *list.List // of *lookupEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type lookupEntry struct {
+	url       string
+	resp      any
+	expiresAt time.Time
+}
+
+func newLookupCache(size int, ttl time.Duration) *lookupCache {
+	return &lookupCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *lookupCache) get(url string, now time.Time) (any, bool) {
+	elem, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*lookupEntry)
+	if !now.Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, url)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return entry.resp, true
+}
+
+func (c *lookupCache) put(url string, resp any, now time.Time) {
+	if c.size < 1 {
+		return
+	}
+
+	entry := &lookupEntry{url: url, resp: resp, expiresAt: now.Add(c.ttl)}
+
+	if elem, ok := c.entries[url]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[url] = c.order.PushFront(entry)
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lookupEntry).url)
+	}
+}
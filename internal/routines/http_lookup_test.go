@@ -0,0 +1,153 @@
+package routines_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUserServer serves {"name": <id>} at /users/<id>, waiting delay before answering.
+func newUserServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(delay)
+
+		id := strings.TrimPrefix(r.URL.Path, "/users/")
+		if id == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"name": id})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func userKey(msg pipeline.Msg) string {
+	return msg.Data.(map[string]any)["user"].(string)
+}
+
+func userMsgs(users ...string) []pipeline.Msg {
+	msgs := make([]pipeline.Msg, len(users))
+	for i, user := range users {
+		msgs[i] = pipeline.Msg{ID: user, Data: map[string]any{"user": user}}
+	}
+	return msgs
+}
+
+func TestHTTPLookupRoutine_Start(t *testing.T) {
+	t.Run("merges the response into the message", func(t *testing.T) {
+		server, _ := newUserServer(t, 0)
+
+		results := runRoutine(t, routines.HTTPLookup(server.URL+"/users/{key}", userKey), userMsgs("ana"))
+
+		require.Len(t, results, 1)
+		assert.Equal(t, map[string]any{"user": "ana", "name": "ana"}, results[0].Data)
+	})
+
+	t.Run("stores the response under a field", func(t *testing.T) {
+		server, _ := newUserServer(t, 0)
+
+		lookup := routines.HTTPLookup(server.URL+"/users/{key}", userKey).WithField("profile")
+		results := runRoutine(t, lookup, userMsgs("ana"))
+
+		require.Len(t, results, 1)
+		assert.Equal(t, map[string]any{"user": "ana", "profile": map[string]any{"name": "ana"}}, results[0].Data)
+	})
+
+	t.Run("path-escapes the key", func(t *testing.T) {
+		server, _ := newUserServer(t, 0)
+
+		results := runRoutine(t, routines.HTTPLookup(server.URL+"/users/{key}", userKey), userMsgs("a b"))
+
+		require.Len(t, results, 1)
+		assert.Equal(t, "a b", results[0].Data.(map[string]any)["name"])
+	})
+
+	t.Run("serves repeated keys from the cache", func(t *testing.T) {
+		server, requests := newUserServer(t, 0)
+
+		results := runRoutine(t, routines.HTTPLookup(server.URL+"/users/{key}", userKey), userMsgs("ana", "bob", "ana", "ana", "bob"))
+
+		require.Len(t, results, 5)
+		for _, msg := range results {
+			data := msg.Data.(map[string]any)
+			assert.Equal(t, data["user"], data["name"])
+		}
+		assert.EqualValues(t, 2, requests.Load())
+	})
+
+	t.Run("fetches again once an entry expires", func(t *testing.T) {
+		server, requests := newUserServer(t, 0)
+
+		lookup := routines.HTTPLookup(server.URL+"/users/{key}", userKey).WithCache(10, 20*time.Millisecond)
+
+		runRoutine(t, lookup, userMsgs("ana", "ana"))
+		assert.EqualValues(t, 1, requests.Load())
+
+		time.Sleep(30 * time.Millisecond)
+
+		runRoutine(t, lookup, userMsgs("ana"))
+		assert.EqualValues(t, 2, requests.Load())
+	})
+
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		server, requests := newUserServer(t, 0)
+
+		lookup := routines.HTTPLookup(server.URL+"/users/{key}", userKey).WithCache(2, time.Minute)
+
+		// ana is used again before carl is stored, so bob is evicted
+		runRoutine(t, lookup, userMsgs("ana", "bob", "ana", "carl", "ana"))
+		assert.EqualValues(t, 3, requests.Load())
+
+		runRoutine(t, lookup, userMsgs("bob"))
+		assert.EqualValues(t, 4, requests.Load())
+	})
+
+	t.Run("coalesces concurrent lookups of the same key", func(t *testing.T) {
+		server, requests := newUserServer(t, 50*time.Millisecond)
+
+		lookup := routines.HTTPLookup(server.URL+"/users/{key}", userKey).WithCache(0, 0)
+		results := runRoutine(t, routines.Parallel(lookup, 8), userMsgs("ana", "ana", "ana", "ana", "ana", "ana", "ana", "ana"))
+
+		require.Len(t, results, 8)
+		for _, msg := range results {
+			assert.Equal(t, "ana", msg.Data.(map[string]any)["name"])
+		}
+		assert.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("passes the message through when the lookup fails", func(t *testing.T) {
+		server, requests := newUserServer(t, 0)
+
+		results := runRoutine(t, routines.HTTPLookup(server.URL+"/users/{key}", userKey), userMsgs("missing", "missing"))
+
+		require.Len(t, results, 2)
+		assert.Equal(t, map[string]any{"user": "missing"}, results[0].Data)
+		assert.EqualValues(t, 2, requests.Load(), "failed lookups are not cached")
+	})
+
+	t.Run("passes non-map messages through", func(t *testing.T) {
+		server, requests := newUserServer(t, 0)
+
+		results := runRoutine(t, routines.HTTPLookup(server.URL+"/users/{key}", userKey), []pipeline.Msg{{ID: "1", Data: "ana"}})
+
+		require.Len(t, results, 1)
+		assert.Equal(t, "ana", results[0].Data)
+		assert.EqualValues(t, 0, requests.Load())
+	})
+}
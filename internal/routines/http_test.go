@@ -0,0 +1,189 @@
+package routines_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nextLink(page map[string]any) (string, bool) {
+	link, ok := page["next"].(string)
+	return link, ok && link != ""
+}
+
+func newPagedServer(t *testing.T, pages [][]any) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if n >= len(pages) {
+			http.NotFound(w, r)
+			return
+		}
+
+		body := map[string]any{"items": pages[n]}
+		if n+1 < len(pages) {
+			body["next"] = "/items?page=" + strconv.Itoa(n+1)
+		}
+
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestHTTPPaginateRoutine_Start(t *testing.T) {
+	t.Run("emits items from every page following next links", func(t *testing.T) {
+		server, requests := newPagedServer(t, [][]any{{"a", "b"}, {"c"}, {"d", "e"}})
+
+		results := runRoutine(t, routines.HTTP().Paginate(server.URL+"/items?page=0", nextLink), nil)
+
+		data := make([]any, len(results))
+		for i, msg := range results {
+			data[i] = msg.Data
+		}
+		assert.Equal(t, []any{"a", "b", "c", "d", "e"}, data)
+		assert.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("parses items with the configured codec", func(t *testing.T) {
+		type user struct {
+			Name string `json:"name"`
+		}
+
+		server, _ := newPagedServer(t, [][]any{{map[string]any{"name": "ana"}}, {map[string]any{"name": "bia"}}})
+		codec := filesystem.WithJSONType[user](filesystem.NewJSONCodec())
+
+		results := runRoutine(t, routines.HTTP().WithCodec(codec).Paginate(server.URL+"/items?page=0", nextLink), nil)
+
+		data := make([]any, len(results))
+		for i, msg := range results {
+			data[i] = msg.Data
+		}
+		assert.Equal(t, []any{user{Name: "ana"}, user{Name: "bia"}}, data)
+	})
+
+	t.Run("fails when the codec cannot parse the items", func(t *testing.T) {
+		server, _ := newPagedServer(t, [][]any{{"not a number"}})
+		codec := filesystem.WithJSONType[int](filesystem.NewJSONCodec())
+
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		err := routines.HTTP().WithCodec(codec).Paginate(server.URL+"/items?page=0", nextLink).Start(t.Context(), pipe)
+
+		assert.ErrorContains(t, err, "failed to parse items of page")
+	})
+
+	t.Run("stops when nextFn returns false", func(t *testing.T) {
+		server, requests := newPagedServer(t, [][]any{{"a"}, {"b"}, {"c"}})
+		stopAfterFirst := func(map[string]any) (string, bool) { return "", false }
+
+		results := runRoutine(t, routines.HTTP().Paginate(server.URL+"/items?page=0", stopAfterFirst), nil)
+
+		require.Len(t, results, 1)
+		assert.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("waits between requests with a rate limit", func(t *testing.T) {
+		server, _ := newPagedServer(t, [][]any{{"a"}, {"b"}, {"c"}})
+
+		start := time.Now()
+		results := runRoutine(t, routines.HTTP().WithRateLimit(30*time.Millisecond).Paginate(server.URL+"/items?page=0", nextLink), nil)
+
+		assert.Len(t, results, 3)
+		assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+	})
+
+	t.Run("returns an error on non-2xx responses", func(t *testing.T) {
+		server, _ := newPagedServer(t, [][]any{{"a"}})
+
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		err := routines.HTTP().Paginate(server.URL+"/items?page=5", nextLink).Start(t.Context(), pipe)
+
+		assert.ErrorContains(t, err, "unexpected status 404")
+	})
+
+	t.Run("retries server errors with the backoff policy", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"items": []any{"ok"}})
+		}))
+		t.Cleanup(server.Close)
+
+		routine := routines.HTTP().
+			WithRetry(3, routines.NewConstantBackoff(time.Millisecond)).
+			Paginate(server.URL, nextLink)
+
+		results := runRoutine(t, routine, nil)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, "ok", results[0].Data)
+		assert.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("retries with the default policy when none is given", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"items": []any{"ok"}})
+		}))
+		t.Cleanup(server.Close)
+
+		results := runRoutine(t, routines.HTTP().WithRetry(1, nil).Paginate(server.URL, nextLink), nil)
+
+		require.Len(t, results, 1)
+		assert.EqualValues(t, 2, requests.Load())
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(server.Close)
+
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.HTTP().
+			WithRetry(2, routines.NewConstantBackoff(time.Millisecond)).
+			Paginate(server.URL, nextLink).
+			Start(t.Context(), pipe)
+
+		assert.ErrorContains(t, err, "unexpected status 500")
+		assert.EqualValues(t, 3, requests.Load())
+	})
+}
@@ -0,0 +1,162 @@
+package routines
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/itchyny/gojq"
+)
+
+// JQRoutine reshapes decoded JSON messages with a jq expression, e.g. ".user.name" or
+// ".items[] | {id, total: .price * .qty}". The expression is compiled once, when the
+// routine starts, and run against the data of every map[string]any or []any message.
+// Each value it yields becomes a message of its own, with a new ID and the input's meta, so
+// an expression may also drop a message (empty, select) or fan it out (.[]). Messages of
+// other types pass through unchanged, as in Transform.
+//
+// Values other than the ones jq works on, such as json.Number, time.Time, typed maps or
+// structs nested in the data, are converted to their JSON form before the expression runs.
+type JQRoutine struct {
+	expr string
+	errs chan<- error
+}
+
+func JQ(expr string) *JQRoutine {
+	return &JQRoutine{expr: expr}
+}
+
+// WithErrors sends the errors raised by the expression, such as indexing a string, to
+// errs instead of logging them. The routine waits for errs to be read. A message stops
+// yielding values at its first error, but the results before it are still emitted.
+func (j *JQRoutine) WithErrors(errs chan<- error) *JQRoutine {
+	j.errs = errs
+	return j
+}
+
+func (j *JQRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	query, err := gojq.Parse(j.expr)
+	if err != nil {
+		return fmt.Errorf("invalid jq expression %q: %w", j.expr, err)
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return fmt.Errorf("failed to compile jq expression %q: %w", j.expr, err)
+	}
+
+	for msg := range pipe.In() {
+		switch msg.Data.(type) {
+		case map[string]any, []any:
+		default:
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		input, err := jqValue(msg.Data)
+		if err != nil {
+			if !j.report(ctx, fmt.Errorf("jq cannot read message %s: %w", msg.ID, err)) {
+				return nil
+			}
+			continue
+		}
+
+		iter := code.RunWithContext(ctx, input)
+		for {
+			value, ok := iter.Next()
+			if !ok {
+				break
+			}
+
+			if err, isErr := value.(error); isErr {
+				if !j.report(ctx, fmt.Errorf("jq expression failed on message %s: %w", msg.ID, err)) {
+					return nil
+				}
+				break
+			}
+
+			if err := pipe.Send(ctx, msg.WithData(value).WithID(pipeline.NewID(ctx))); err != nil {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// jqValue converts v into the values gojq accepts: nil, bool, int, float64, *big.Int,
+// string, []any and map[string]any. Anything else is converted through its JSON encoding,
+// since gojq panics on types it does not know.
+func jqValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, bool, int, float64, *big.Int, string:
+		return v, nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n), nil
+		}
+		if n, ok := new(big.Int).SetString(v.String(), 10); ok {
+			return n, nil
+		}
+		return v.Float64()
+	case []any:
+		values := make([]any, len(v))
+		for i, item := range v {
+			value, err := jqValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for key, item := range v {
+			value, err := jqValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+		}
+		return values, nil
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %T to JSON: %w", v, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to convert %T to JSON: %w", v, err)
+	}
+
+	return jqValue(decoded)
+}
+
+// report hands err to the error channel, or logs it without one. It returns false if the
+// context ended first.
+func (j *JQRoutine) report(ctx context.Context, err error) bool {
+	if j.errs == nil {
+		slog.Error("jq expression failed", "error", err)
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case j.errs <- err:
+		return true
+	}
+}
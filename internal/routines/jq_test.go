@@ -0,0 +1,119 @@
+package routines_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func msgData(msgs []pipeline.Msg) []any {
+	data := make([]any, len(msgs))
+	for i, msg := range msgs {
+		data[i] = msg.Data
+	}
+	return data
+}
+
+func TestJQRoutine_Start(t *testing.T) {
+	orders := []pipeline.Msg{
+		{ID: "1", Data: map[string]any{"id": "a", "customer": map[string]any{"name": "ana"}, "total": 10.0}},
+		{ID: "2", Data: map[string]any{"id": "b", "customer": map[string]any{"name": "bia"}, "total": 25.5}},
+	}
+
+	t.Run("applies a selector to each message", func(t *testing.T) {
+		results := runRoutine(t, routines.JQ(".customer.name"), orders)
+
+		assert.Equal(t, []any{"ana", "bia"}, msgData(results))
+	})
+
+	t.Run("builds objects and filters", func(t *testing.T) {
+		results := runRoutine(t, routines.JQ("select(.total > 20) | {id, total}"), orders)
+
+		assert.Equal(t, []any{map[string]any{"id": "b", "total": 25.5}}, msgData(results))
+	})
+
+	t.Run("emits every output of an expression", func(t *testing.T) {
+		input := []pipeline.Msg{
+			{ID: "1", Data: map[string]any{"tags": []any{"x", "y"}}},
+			{ID: "2", Data: []any{map[string]any{"tags": []any{"z"}}}},
+		}
+
+		results := runRoutine(t, routines.JQ(".. | .tags? // empty | .[]"), input)
+
+		assert.Equal(t, []any{"x", "y", "z"}, msgData(results))
+		assert.NotEqual(t, results[0].ID, results[1].ID)
+	})
+
+	t.Run("keeps the meta and ingestion time of the input", func(t *testing.T) {
+		ingestedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		input := []pipeline.Msg{{ID: "1", Data: []any{1.0, 2.0}, IngestedAt: ingestedAt, Meta: map[string]any{"file": "in.json"}}}
+
+		results := runRoutine(t, routines.JQ(".[]"), input)
+
+		require.Len(t, results, 2)
+		for _, msg := range results {
+			assert.NotEqual(t, "1", msg.ID)
+			assert.Equal(t, ingestedAt, msg.IngestedAt)
+			assert.Equal(t, map[string]any{"file": "in.json"}, msg.Meta)
+		}
+	})
+
+	t.Run("passes through messages of another type", func(t *testing.T) {
+		input := []pipeline.Msg{{ID: "1", Data: "not json"}}
+
+		results := runRoutine(t, routines.JQ(".id"), input)
+
+		assert.Equal(t, input, results)
+	})
+
+	t.Run("sends expression errors to the error channel", func(t *testing.T) {
+		errs := make(chan error, 1)
+		input := []pipeline.Msg{
+			{ID: "1", Data: map[string]any{"name": "ana"}},
+			{ID: "2", Data: map[string]any{"name": 7.0}},
+		}
+
+		results := runRoutine(t, routines.JQ(".name | ascii_upcase").WithErrors(errs), input)
+
+		assert.Equal(t, []any{"ANA"}, msgData(results))
+		require.Len(t, errs, 1)
+		assert.ErrorContains(t, <-errs, "message 2")
+	})
+
+	t.Run("converts values jq does not know instead of panicking", func(t *testing.T) {
+		type point struct {
+			X int `json:"x"`
+		}
+
+		input := []pipeline.Msg{{ID: "1", Data: map[string]any{
+			"count":   json.Number("1"),
+			"big":     json.Number("9007199254740993"),
+			"ratio":   json.Number("0.5"),
+			"labels":  map[string]string{"env": "prod"},
+			"at":      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			"point":   point{X: 3},
+			"pair":    routines.Keyed[string, int]{Key: "a", Value: 1},
+			"numbers": []int{1, 2},
+		}}}
+
+		results := runRoutine(t, routines.JQ(`[(.count | type), .count + 1, (.big | tostring), .ratio * 2, .labels.env, .at, .point.x, .pair.Key, (.numbers | add)]`), input)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, []any{"number", 2, "9007199254740993", 1.0, "prod", "2024-05-01T12:00:00Z", 3, "a", 3}, results[0].Data)
+	})
+
+	t.Run("rejects an invalid expression", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.JQ(".name |").Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "invalid jq expression")
+	})
+}
@@ -0,0 +1,279 @@
+package routines
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// JSONAsRoutine decodes each message holding a JSON document (string or []byte) into a T.
+type JSONAsRoutine[T any] struct{}
+
+func JSONAs[T any]() *JSONAsRoutine[T] {
+	return &JSONAsRoutine[T]{}
+}
+
+func (j *JSONAsRoutine[T]) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	slog.Debug("starting json decode routine")
+
+	for msg := range pipe.In() {
+		var raw []byte
+		switch v := msg.Data.(type) {
+		case string:
+			raw = []byte(v)
+		case []byte:
+			raw = v
+		default:
+			slog.Error("json decode received message with invalid type", "type", reflect.TypeOf(msg.Data))
+			continue
+		}
+
+		var value T
+		if err := json.Unmarshal(raw, &value); err != nil {
+			slog.Error("failed to decode json message", "msg_id", msg.ID, "error", err)
+			continue
+		}
+
+		if err := pipe.Send(ctx, msg.WithData(value)); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// CanonicalJSONRoutine re-serializes each message as canonical JSON text, so logically
+// equal documents produce identical bytes, e.g. before hashing or deduplicating them.
+// Object keys are sorted, insignificant whitespace is dropped, HTML characters are left
+// unescaped and numbers are normalized: integers that fit an int64 keep every digit, others
+// are written in the shortest form of their float64 value, without fraction or exponent
+// below 1e21 ("1.0" and "1e2" become "1" and "100") and with one from it ("1e21" becomes
+// "1e+21").
+//
+// String and []byte messages are parsed as JSON documents; other messages, such as maps,
+// are marshaled first. The output is always a string. Messages that are not a single valid
+// JSON value, including ones with trailing data after it, are logged and dropped.
+type CanonicalJSONRoutine struct{}
+
+func CanonicalJSON() *CanonicalJSONRoutine {
+	return &CanonicalJSONRoutine{}
+}
+
+func (c *CanonicalJSONRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		canonical, err := canonicalJSON(msg.Data)
+		if err != nil {
+			slog.Error("failed to canonicalize json message", "msg_id", msg.ID, "error", err)
+			continue
+		}
+
+		if err := pipe.Send(ctx, msg.WithData(canonical)); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func canonicalJSON(data any) (string, error) {
+	var raw []byte
+	switch v := data.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		marshaled, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		raw = marshaled
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("unexpected data after the json value at offset %d", decoder.InputOffset())
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+
+	// maps are encoded with sorted keys, so only numbers need normalizing
+	if err := encoder.Encode(normalizeJSONNumbers(value)); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+// normalizeJSONNumbers rewrites every number in a decoded document to its canonical text.
+func normalizeJSONNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeJSONNumbers(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeJSONNumbers(item)
+		}
+		return v
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(i, 10))
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			return v
+		}
+
+		// encoding/json writes floats in their shortest form, without exponent below 1e21
+		return f
+	default:
+		return v
+	}
+}
+
+// KeyStyle names a key naming convention used by RekeyJSON.
+type KeyStyle string
+
+const (
+	SnakeCase KeyStyle = "snake_case"
+	CamelCase KeyStyle = "camelCase"
+)
+
+// RekeyJSONRoutine renames every key of map[string]any messages to a naming style,
+// recursing into nested maps and arrays. Other messages pass through unchanged.
+type RekeyJSONRoutine struct {
+	style KeyStyle
+}
+
+// RekeyJSON converts keys to style, e.g. "userID" and "user-id" both become "user_id"
+// in SnakeCase and "userId" in CamelCase. When keys of the same object convert to the same
+// name, the value of the key first in sorted order is kept and the others are dropped
+// with a warning, so the output does not depend on map iteration order.
+func RekeyJSON(style KeyStyle) *RekeyJSONRoutine {
+	return &RekeyJSONRoutine{style: style}
+}
+
+func (r *RekeyJSONRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if r.style != SnakeCase && r.style != CamelCase {
+		return fmt.Errorf("rekey json needs the %s or %s key style, got %q", SnakeCase, CamelCase, r.style)
+	}
+
+	for msg := range pipe.In() {
+		if err := pipe.Send(ctx, msg.WithData(r.rekey(msg.Data))); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// rekey returns a copy of value with every map key converted; the input is not modified.
+func (r *RekeyJSONRoutine) rekey(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		rekeyed := make(map[string]any, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			converted := r.convert(key)
+			if _, ok := rekeyed[converted]; ok {
+				slog.Warn("dropping key converting to an existing key", "key", key, "converted", converted)
+				continue
+			}
+
+			rekeyed[converted] = r.rekey(v[key])
+		}
+		return rekeyed
+	case []any:
+		rekeyed := make([]any, len(v))
+		for i, item := range v {
+			rekeyed[i] = r.rekey(item)
+		}
+		return rekeyed
+	default:
+		return value
+	}
+}
+
+func (r *RekeyJSONRoutine) convert(key string) string {
+	words := splitKeyWords(key)
+
+	switch r.style {
+	case SnakeCase:
+		return strings.Join(words, "_")
+	case CamelCase:
+		for i := 1; i < len(words); i++ {
+			first, size := utf8.DecodeRuneInString(words[i])
+			words[i] = string(unicode.ToUpper(first)) + words[i][size:]
+		}
+		return strings.Join(words, "")
+	default:
+		return key
+	}
+}
+
+// splitKeyWords splits a key into lowercase words on separators and case changes,
+// keeping acronyms together: "HTTPServer_id" gives "http", "server", "id".
+func splitKeyWords(key string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(key)
+	for i, c := range runes {
+		switch {
+		case c == '_' || c == '-' || c == ' ' || c == '.':
+			flush()
+			continue
+		case unicode.IsUpper(c) && i > 0:
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+
+		word = append(word, c)
+	}
+	flush()
+
+	return words
+}
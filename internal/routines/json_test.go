@@ -0,0 +1,199 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonAddress struct {
+	City string `json:"city"`
+}
+
+type jsonPerson struct {
+	Name    string      `json:"name"`
+	Address jsonAddress `json:"address"`
+}
+
+func TestJSONAsRoutine_Start(t *testing.T) {
+	t.Run("decodes strings and bytes into the target type", func(t *testing.T) {
+		input := []pipeline.Msg{
+			{ID: "1", Data: `{"name": "John", "address": {"city": "Lisbon"}}`},
+			{ID: "2", Data: []byte(`{"name": "Jane", "address": {"city": "Porto"}}`)},
+		}
+
+		results := runRoutine(t, routines.JSONAs[jsonPerson](), input)
+
+		require.Len(t, results, 2)
+		assert.Equal(t, "1", results[0].ID)
+		assert.Equal(t, jsonPerson{Name: "John", Address: jsonAddress{City: "Lisbon"}}, results[0].Data)
+		assert.Equal(t, jsonPerson{Name: "Jane", Address: jsonAddress{City: "Porto"}}, results[1].Data)
+	})
+
+	t.Run("skips invalid documents and unsupported types", func(t *testing.T) {
+		input := []pipeline.Msg{
+			{ID: "1", Data: `{invalid`},
+			{ID: "2", Data: 42},
+			{ID: "3", Data: `{"name": "Ok"}`},
+		}
+
+		results := runRoutine(t, routines.JSONAs[jsonPerson](), input)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, "3", results[0].ID)
+	})
+}
+
+// runRoutine feeds input into routine and returns everything it emits.
+func runRoutine(t *testing.T, routine pipeline.Routine, input []pipeline.Msg) []pipeline.Msg {
+	t.Helper()
+
+	pipe := pipeline.NewChanPipe()
+
+	go func() {
+		for _, msg := range input {
+			pipe.In() <- msg
+		}
+		close(pipe.In())
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- routine.Start(ctx, pipe)
+	}()
+
+	var results []pipeline.Msg
+	for msg := range pipe.Out() {
+		results = append(results, msg)
+	}
+
+	assert.NoError(t, <-errCh)
+
+	return results
+}
+
+func TestRekeyJSONRoutine_Start(t *testing.T) {
+	camel := map[string]any{
+		"userId":    1,
+		"firstName": "Ada",
+		"homeAddress": map[string]any{
+			"zipCode": "12345",
+		},
+		"phoneNumbers": []any{
+			map[string]any{"countryCode": "+1", "isPrimary": true},
+			"raw",
+		},
+	}
+	snake := map[string]any{
+		"user_id":    1,
+		"first_name": "Ada",
+		"home_address": map[string]any{
+			"zip_code": "12345",
+		},
+		"phone_numbers": []any{
+			map[string]any{"country_code": "+1", "is_primary": true},
+			"raw",
+		},
+	}
+
+	t.Run("converts nested camelCase keys to snake_case", func(t *testing.T) {
+		results := runRoutine(t, routines.RekeyJSON(routines.SnakeCase), []pipeline.Msg{{ID: "1", Data: camel}})
+
+		require.Len(t, results, 1)
+		assert.Equal(t, snake, results[0].Data)
+		assert.Equal(t, "1", results[0].ID)
+	})
+
+	t.Run("converts nested snake_case keys to camelCase", func(t *testing.T) {
+		results := runRoutine(t, routines.RekeyJSON(routines.CamelCase), []pipeline.Msg{{Data: snake}})
+
+		require.Len(t, results, 1)
+		assert.Equal(t, camel, results[0].Data)
+	})
+
+	t.Run("splits acronyms and separators", func(t *testing.T) {
+		input := map[string]any{"HTTPServer": 1, "userID": 2, "created-at": 3, "v2Name": 4}
+
+		results := runRoutine(t, routines.RekeyJSON(routines.SnakeCase), []pipeline.Msg{{Data: input}})
+
+		assert.Equal(t, map[string]any{"http_server": 1, "user_id": 2, "created_at": 3, "v2_name": 4}, results[0].Data)
+	})
+
+	t.Run("capitalizes non-ASCII words whole", func(t *testing.T) {
+		input := map[string]any{"user_élan": 1, "city_ñandú": 2}
+
+		results := runRoutine(t, routines.RekeyJSON(routines.CamelCase), []pipeline.Msg{{Data: input}})
+
+		assert.Equal(t, map[string]any{"userÉlan": 1, "cityÑandú": 2}, results[0].Data)
+	})
+
+	t.Run("passes through non-object messages", func(t *testing.T) {
+		input := []pipeline.Msg{{Data: "text"}, {Data: 42}}
+
+		results := runRoutine(t, routines.RekeyJSON(routines.CamelCase), input)
+
+		assert.Equal(t, input, results)
+	})
+
+	t.Run("keeps the first key in sorted order on collisions", func(t *testing.T) {
+		input := map[string]any{"userID": 1, "user_id": 2, "user-id": 3}
+
+		// map iteration order varies between runs, so repeat to catch a random winner
+		for range 20 {
+			results := runRoutine(t, routines.RekeyJSON(routines.SnakeCase), []pipeline.Msg{{Data: input}})
+
+			assert.Equal(t, map[string]any{"user_id": 3}, results[0].Data)
+		}
+	})
+
+	t.Run("rejects an unknown key style", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.RekeyJSON("kebab-case").Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, `got "kebab-case"`)
+	})
+}
+
+func TestCanonicalJSONRoutine_Start(t *testing.T) {
+	t.Run("differently ordered objects canonicalize to the same text", func(t *testing.T) {
+		results := runRoutine(t, routines.CanonicalJSON(), []pipeline.Msg{
+			{ID: "1", Data: `{"b": 2, "a": {"y": [1.0, 2.50], "x": "<tag>"}}`},
+			{ID: "2", Data: []byte(`{ "a" : { "x" : "<tag>", "y" : [1, 2.5] }, "b" : 2e0 }`)},
+			{ID: "3", Data: map[string]any{"a": map[string]any{"x": "<tag>", "y": []any{1, 2.5}}, "b": 2.0}},
+		})
+
+		require.Len(t, results, 3)
+		for _, msg := range results {
+			assert.Equal(t, `{"a":{"x":"<tag>","y":[1,2.5]},"b":2}`, msg.Data)
+		}
+	})
+
+	t.Run("normalizes numbers", func(t *testing.T) {
+		results := runRoutine(t, routines.CanonicalJSON(), []pipeline.Msg{
+			{ID: "1", Data: `[1e2, -0, 0.10, 1.5e-7, 1e21, 9007199254740993]`},
+		})
+
+		assert.Equal(t, []pipeline.Msg{{ID: "1", Data: `[100,0,0.1,1.5e-7,1e+21,9007199254740993]`}}, results)
+	})
+
+	t.Run("drops invalid json and trailing data", func(t *testing.T) {
+		results := runRoutine(t, routines.CanonicalJSON(), []pipeline.Msg{
+			{ID: "1", Data: `{"broken": `},
+			{ID: "2", Data: `"ok"`},
+			{ID: "3", Data: `{"a": 1} {"b": 2}`},
+			{ID: "4", Data: `[1] trailing`},
+			{ID: "5", Data: " {\"a\": 1}\n"},
+		})
+
+		assert.Equal(t, []pipeline.Msg{{ID: "2", Data: `"ok"`}, {ID: "5", Data: `{"a":1}`}}, results)
+	})
+}
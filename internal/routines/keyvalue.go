@@ -0,0 +1,129 @@
+package routines
+
+import (
+	"context"
+	"strings"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// ParseKeyValueRoutine parses key=value lines, such as logfmt logs or env dumps, into
+// map[string]any messages with string values.
+//
+// Values may be double quoted to hold separators, e.g. msg="disk full" or path="a=b".
+// Inside quotes a backslash escapes the next character, so "said \"no\"" holds quotes;
+// \n and \t stand for newline and tab. Outside quotes backslashes are literal, so
+// path=C:\new keeps its backslash. A key without a value is set to true, as in logfmt.
+// Unquoted keys and values are trimmed of surrounding spaces.
+//
+// Lines must be string or []byte messages; other messages pass through unchanged.
+type ParseKeyValueRoutine struct {
+	pairSep rune
+	kvSep   rune
+}
+
+// ParseKeyValue parses lines whose pairs are separated by pairSep and whose keys and
+// values are separated by kvSep. Zero runes select the defaults: a space between pairs
+// and '=' between key and value.
+func ParseKeyValue(pairSep, kvSep rune) *ParseKeyValueRoutine {
+	if pairSep == 0 {
+		pairSep = ' '
+	}
+	if kvSep == 0 {
+		kvSep = '='
+	}
+
+	return &ParseKeyValueRoutine{pairSep: pairSep, kvSep: kvSep}
+}
+
+func (p *ParseKeyValueRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		switch v := msg.Data.(type) {
+		case string:
+			msg = msg.WithData(p.parse(v))
+		case []byte:
+			msg = msg.WithData(p.parse(string(v)))
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (p *ParseKeyValueRoutine) parse(line string) map[string]any {
+	result := make(map[string]any)
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); {
+		if runes[i] == p.pairSep {
+			i++
+			continue
+		}
+
+		var key string
+		key, i = p.token(runes, i, true)
+
+		var value any = true
+		if i < len(runes) && runes[i] == p.kvSep {
+			value, i = p.token(runes, i+1, false)
+		}
+
+		if key != "" {
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+// token reads a key or value starting at i, returning it and the index of the first rune
+// after it. Keys end at either separator, values at the pair separator.
+func (p *ParseKeyValueRoutine) token(runes []rune, i int, isKey bool) (string, int) {
+	var b strings.Builder
+
+	quoted := !isKey && i < len(runes) && runes[i] == '"'
+	if quoted {
+		i++
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+
+		switch {
+		case quoted && r == '\\' && i+1 < len(runes):
+			b.WriteRune(unescapeRune(runes[i+1]))
+			i += 2
+			continue
+		case quoted && r == '"':
+			return b.String(), i + 1
+		case !quoted && r == p.pairSep, !quoted && isKey && r == p.kvSep:
+			return strings.TrimSpace(b.String()), i
+		}
+
+		b.WriteRune(r)
+		i++
+	}
+
+	if quoted {
+		// an unterminated quote runs to the end of the line
+		return b.String(), i
+	}
+
+	return strings.TrimSpace(b.String()), i
+}
+
+func unescapeRune(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	default:
+		return r
+	}
+}
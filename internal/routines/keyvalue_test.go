@@ -0,0 +1,69 @@
+package routines_test
+
+import (
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyValueRoutine_Start(t *testing.T) {
+	tests := []struct {
+		name     string
+		routine  *routines.ParseKeyValueRoutine
+		input    any
+		expected any
+	}{
+		{
+			name:     "logfmt line",
+			routine:  routines.ParseKeyValue(0, 0),
+			input:    `level=info ts=2024-01-02T10:00:00Z msg="disk almost full" usage=93`,
+			expected: map[string]any{"level": "info", "ts": "2024-01-02T10:00:00Z", "msg": "disk almost full", "usage": "93"},
+		},
+		{
+			name:     "quoted separators and escaped quotes",
+			routine:  routines.ParseKeyValue(0, 0),
+			input:    `query="a=1 b=2" err="said \"no\""`,
+			expected: map[string]any{"query": "a=1 b=2", "err": `said "no"`},
+		},
+		{
+			name:     "backslashes outside quotes are literal",
+			routine:  routines.ParseKeyValue(0, 0),
+			input:    `path=C:\new dir=C:\tmp\ note="tab\there" eq=x=y`,
+			expected: map[string]any{"path": `C:\new`, "dir": `C:\tmp\`, "note": "tab\there", "eq": "x=y"},
+		},
+		{
+			name:     "bare keys, empty values and extra spaces",
+			routine:  routines.ParseKeyValue(0, 0),
+			input:    "  debug   user= retries=3 ",
+			expected: map[string]any{"debug": true, "user": "", "retries": "3"},
+		},
+		{
+			name:     "custom separators",
+			routine:  routines.ParseKeyValue(';', ':'),
+			input:    []byte(`host: db1; port: 5432; note:"a; b"`),
+			expected: map[string]any{"host": "db1", "port": "5432", "note": "a; b"},
+		},
+		{
+			name:     "unterminated quote runs to end of line",
+			routine:  routines.ParseKeyValue(0, 0),
+			input:    `msg="broken line`,
+			expected: map[string]any{"msg": "broken line"},
+		},
+		{
+			name:     "other types pass through",
+			routine:  routines.ParseKeyValue(0, 0),
+			input:    42,
+			expected: 42,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := runRoutine(t, tt.routine, []pipeline.Msg{{ID: "1", Data: tt.input}})
+
+			assert.Equal(t, []pipeline.Msg{{ID: "1", Data: tt.expected}}, results)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package routines
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+)
+
+// KVStore is a key-value store results can be written to, e.g. an adapter over Redis,
+// BoltDB or a map. Set must insert or overwrite the key.
+type KVStore interface {
+	Set(key string, value []byte) error
+}
+
+// KVRoutine configures sinks writing messages to a key-value store: the store and the
+// codec encoding each message into the stored value.
+type KVRoutine struct {
+	store KVStore
+	codec filesystem.WriteCodec
+}
+
+// KV writes to store, encoding values as compact JSON documents unless WithCodec is set.
+func KV(store KVStore) *KVRoutine {
+	return &KVRoutine{
+		store: store,
+		codec: filesystem.NewJSONWriteCodec().WithSeparator(""),
+	}
+}
+
+// WithCodec encodes stored values with codec, e.g. a blob codec to store strings as is
+func (k *KVRoutine) WithCodec(codec filesystem.WriteCodec) *KVRoutine {
+	k.codec = codec
+	return k
+}
+
+// Put returns a sink that upserts every message under the key keyFn returns for it. A
+// later message with the same key overwrites the earlier one.
+//
+// Example:
+//
+//	byID := func(msg pipeline.Msg) string { return "user:" + msg.Data.(map[string]any)["id"].(string) }
+//	script.Out(routines.KV(cache).Put(byID))
+func (k *KVRoutine) Put(keyFn func(pipeline.Msg) string) *KVPutRoutine {
+	return &KVPutRoutine{kv: k, keyFn: keyFn}
+}
+
+type KVPutRoutine struct {
+	kv    *KVRoutine
+	keyFn func(pipeline.Msg) string
+}
+
+func (p *KVPutRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	var value bytes.Buffer
+
+	for msg := range pipe.In() {
+		key := p.keyFn(msg)
+
+		value.Reset()
+		if err := p.kv.codec.Encode(ctx, msg, &value); err != nil {
+			return fmt.Errorf("failed to encode value for key %q: %w", key, err)
+		}
+
+		// the store may keep the slice, so hand it a copy of the reused buffer
+		if err := p.kv.store.Set(key, bytes.Clone(value.Bytes())); err != nil {
+			return fmt.Errorf("failed to set key %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package routines_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory KVStore failing on the key fail, if set.
+type memoryStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	fail string
+}
+
+func (m *memoryStore) Set(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.fail != "" && key == m.fail {
+		return errors.New("store unavailable")
+	}
+
+	if m.data == nil {
+		m.data = make(map[string][]byte)
+	}
+	m.data[key] = value
+
+	return nil
+}
+
+func startKVPut(routine pipeline.Routine, input []pipeline.Msg) error {
+	pipe := pipeline.NewChanPipe()
+
+	go func() {
+		defer close(pipe.In())
+		for _, msg := range input {
+			pipe.In() <- msg
+		}
+	}()
+
+	return routine.Start(context.Background(), pipe)
+}
+
+func TestKVPutRoutine_Start(t *testing.T) {
+	byName := func(msg pipeline.Msg) string {
+		return "user:" + msg.Data.(map[string]any)["name"].(string)
+	}
+
+	t.Run("upserts JSON encoded values by key", func(t *testing.T) {
+		store := &memoryStore{}
+
+		err := startKVPut(routines.KV(store).Put(byName), []pipeline.Msg{
+			{ID: "1", Data: map[string]any{"name": "ana", "age": 30}},
+			{ID: "2", Data: map[string]any{"name": "bob", "age": 25}},
+			{ID: "3", Data: map[string]any{"name": "ana", "age": 31}},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{
+			"user:ana": []byte(`{"age":31,"name":"ana"}`),
+			"user:bob": []byte(`{"age":25,"name":"bob"}`),
+		}, store.data)
+	})
+
+	t.Run("encodes values with the configured codec", func(t *testing.T) {
+		store := &memoryStore{}
+
+		err := startKVPut(
+			routines.KV(store).WithCodec(filesystem.NewBlobCodec()).Put(func(msg pipeline.Msg) string { return msg.ID }),
+			[]pipeline.Msg{{ID: "greeting", Data: "hello"}, {ID: "count", Data: 42}},
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{
+			"greeting": []byte("hello"),
+			"count":    []byte("42"),
+		}, store.data)
+	})
+
+	t.Run("returns store errors", func(t *testing.T) {
+		store := &memoryStore{fail: "user:bob"}
+
+		err := startKVPut(routines.KV(store).Put(byName), []pipeline.Msg{
+			{ID: "1", Data: map[string]any{"name": "bob"}},
+		})
+
+		assert.ErrorContains(t, err, `failed to set key "user:bob": store unavailable`)
+	})
+}
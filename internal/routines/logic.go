@@ -2,21 +2,55 @@ package routines
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"reflect"
+	"sync"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
-	"github.com/google/uuid"
 )
 
+// TransformRoutine maps the data of each message with transform. Messages whose data is not
+// a T pass through unchanged, unless Strict routes them to an error channel.
 type TransformRoutine[T, V any] struct {
 	transform func(T) V
+
+	strict bool
+	errs   chan<- error
 }
 
 func Transform[T, V any](f func(T) V) *TransformRoutine[T, V] {
 	return &TransformRoutine[T, V]{transform: f}
 }
 
+// TypeMismatchError reports a message whose data is not of the type a routine expects.
+type TypeMismatchError struct {
+	MsgID    string
+	Expected string
+	Actual   string
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("message %s has data of type %s, expected %s", e.MsgID, e.Actual, e.Expected)
+}
+
+// ErrTypeMismatch matches every *TypeMismatchError with errors.Is.
+var ErrTypeMismatch = errors.New("message data has an unexpected type")
+
+func (e *TypeMismatchError) Is(target error) bool {
+	return target == ErrTypeMismatch
+}
+
+// Strict drops messages whose data is not a T and sends a *TypeMismatchError naming the
+// actual type to errs, so wrong assumptions about the stream are noticed. The routine
+// waits for errs to be read. With a nil errs, Start returns the first mismatch instead.
+func (t *TransformRoutine[T, V]) Strict(errs chan<- error) *TransformRoutine[T, V] {
+	t.strict = true
+	t.errs = errs
+	return t
+}
+
 func (t *TransformRoutine[T, V]) Start(ctx context.Context, pipe pipeline.Pipe) error {
 	defer pipe.Close()
 
@@ -28,30 +62,219 @@ func (t *TransformRoutine[T, V]) Start(ctx context.Context, pipe pipeline.Pipe)
 		// type assertion to T
 		val, ok := msg.Data.(T)
 		if !ok {
-			//todo: log error
-			pipe.Out() <- msg
+			if t.strict {
+				mismatch := &TypeMismatchError{
+					MsgID:    msg.ID,
+					Expected: reflect.TypeFor[T]().String(),
+					Actual:   fmt.Sprintf("%T", msg.Data),
+				}
+
+				if t.errs == nil {
+					return mismatch
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case t.errs <- mismatch:
+				}
+				continue
+			}
+
+			slog.Debug("transform passing through message of unexpected type", "msg_id", msg.ID, "type", fmt.Sprintf("%T", msg.Data))
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
 			continue
 		}
 
-		transformedMsg := pipeline.Msg{
-			ID:   msg.ID,
-			Data: t.transform(val),
-		}
+		transformedMsg := msg.WithData(t.transform(val))
 
 		slog.Debug("transformed message", "msg", transformedMsg)
 
-		select {
-		case <-ctx.Done():
+		if err := pipe.Send(ctx, transformedMsg); err != nil {
 			return nil
-		case pipe.Out() <- transformedMsg:
 		}
 	}
 
 	return nil
 }
 
+// FlatMapRoutine maps each message to zero or more messages, e.g. splitting a line into
+// words. An empty result drops the input, and every result becomes a message with a new ID
+// and the input's meta, in the order returned.
+type FlatMapRoutine[T, V any] struct {
+	flatMap func(T) []V
+}
+
+func FlatMap[T, V any](f func(T) []V) *FlatMapRoutine[T, V] {
+	return &FlatMapRoutine[T, V]{flatMap: f}
+}
+
+func (f *FlatMapRoutine[T, V]) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		// messages of another type pass through unchanged, as in Transform
+		val, ok := msg.Data.(T)
+		if !ok {
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		for _, item := range f.flatMap(val) {
+			if err := pipe.Send(ctx, msg.WithData(item).WithID(pipeline.NewID(ctx))); err != nil {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// FilterRoutine forwards only the messages whose data matches a predicate.
+type FilterRoutine[T any] struct {
+	predicate func(T) bool
+}
+
+// Filter drops messages whose data is a T rejected by predicate. Messages of another type
+// pass through unchanged, as in Transform.
+func Filter[T any](predicate func(T) bool) *FilterRoutine[T] {
+	return &FilterRoutine[T]{predicate: predicate}
+}
+
+func (f *FilterRoutine[T]) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		if val, ok := msg.Data.(T); ok && !f.predicate(val) {
+			slog.Debug("filter dropped message", "msg_id", msg.ID)
+			continue
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// StopWhenRoutine forwards messages until one matches a predicate, then ends the stream.
+type StopWhenRoutine struct {
+	pred func(pipeline.Msg) bool
+}
+
+// StopWhen forwards messages until pred matches one, e.g. a sentinel record meaning "stop",
+// then closes its output without emitting the match. Later stages finish as if the input
+// had ended, and once the script output is done the stages before are cancelled, so a
+// never-ending source stops too.
+func StopWhen(pred func(pipeline.Msg) bool) *StopWhenRoutine {
+	return &StopWhenRoutine{pred: pred}
+}
+
+// Stateful marks StopWhenRoutine as a StatefulRoutine, since the stream must end for
+// every worker at once.
+func (s *StopWhenRoutine) Stateful() {}
+
+func (s *StopWhenRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		if s.pred(msg) {
+			slog.Debug("stop condition matched, ending stream", "msg_id", msg.ID)
+			return nil
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// TakeRoutine forwards the first messages of a stream and ends it.
+type TakeRoutine struct {
+	n int
+}
+
+// Take forwards the first n messages, e.g. to sample the head of a huge file, then closes
+// its output and returns without reading the rest. As with StopWhen, later stages finish
+// as if the input had ended and the stages before are cancelled once the script output is
+// done, so the source stops reading early. Take(0) forwards nothing.
+func Take(n int) *TakeRoutine {
+	return &TakeRoutine{n: n}
+}
+
+// Stateful marks TakeRoutine as a StatefulRoutine, since the limit counts messages across
+// the whole stream.
+func (t *TakeRoutine) Stateful() {}
+
+func (t *TakeRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if t.n <= 0 {
+		return nil
+	}
+
+	taken := 0
+	for msg := range pipe.In() {
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+
+		if taken++; taken == t.n {
+			slog.Debug("take limit reached, ending stream", "limit", t.n)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// SkipRoutine discards the first messages of a stream and forwards the rest.
+type SkipRoutine struct {
+	n int
+}
+
+// Skip drops the first n messages, e.g. header rows of a CSV file read without a
+// header-aware codec, and forwards the rest unchanged and in order.
+func Skip(n int) *SkipRoutine {
+	return &SkipRoutine{n: n}
+}
+
+// Stateful marks SkipRoutine as a StatefulRoutine, since only the first messages of the
+// stream are skipped.
+func (s *SkipRoutine) Stateful() {}
+
+func (s *SkipRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	skipped := 0
+	for msg := range pipe.In() {
+		if skipped < s.n {
+			skipped++
+			continue
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// ReduceRoutine folds every message into a single value and emits it once the input closes.
+// The terminal emit happens before the pipe is closed, so downstream stages such as a file
+// writer always receive the reduced value before observing Done.
 type ReduceRoutine[T, V any] struct {
-	reduce       func(V, T) V
+	reduce func(V, T) V
+
+	mu           sync.Mutex
 	currentValue V
 }
 
@@ -59,6 +282,20 @@ func Reduce[T, V any](f func(V, T) V, initialValue V) *ReduceRoutine[T, V] {
 	return &ReduceRoutine[T, V]{reduce: f, currentValue: initialValue}
 }
 
+// Stateful marks ReduceRoutine as a StatefulRoutine, since its accumulator lives on the
+// instance.
+func (t *ReduceRoutine[T, V]) Stateful() {}
+
+// Snapshot returns the value reduced so far as a message without an ID. It is safe to
+// call while the routine runs, e.g. from PeriodicSnapshot, as long as the reducer returns
+// a new value rather than mutating a map or slice accumulator in place.
+func (t *ReduceRoutine[T, V]) Snapshot() pipeline.Msg {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return pipeline.Msg{Data: t.currentValue}
+}
+
 func (t *ReduceRoutine[T, V]) Start(ctx context.Context, pipe pipeline.Pipe) error {
 	defer pipe.Close()
 
@@ -75,21 +312,79 @@ func (t *ReduceRoutine[T, V]) Start(ctx context.Context, pipe pipeline.Pipe) err
 			continue
 		}
 
+		t.mu.Lock()
 		t.currentValue = t.reduce(t.currentValue, val)
+		t.mu.Unlock()
 
-		slog.Debug("reduced message", "msg", msg, "currentValue", t.currentValue)
+		slog.Debug("reduced message", "msg", msg)
 	}
 
-	reducedMsg := pipeline.Msg{
-		ID:   uuid.NewString(),
-		Data: t.currentValue,
-	}
+	reducedMsg := t.Snapshot()
+	reducedMsg.ID = pipeline.NewID(ctx)
 
-	select {
-	case pipe.Out() <- reducedMsg:
-	case <-ctx.Done():
+	if err := pipe.Send(ctx, reducedMsg); err != nil {
 		return nil
 	}
 
 	return nil
 }
+
+// Keyed pairs a value with the key it was grouped by.
+type Keyed[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// RunningReduceByKeyRoutine keeps one accumulator per key and emits the updated
+// accumulator as a Keyed message every time a message for that key arrives.
+// It holds an accumulator for every distinct key seen, so memory grows with key cardinality.
+type RunningReduceByKeyRoutine[T any, K comparable, V any] struct {
+	key     func(T) K
+	reduce  func(V, T) V
+	initial V
+}
+
+func RunningReduceByKey[T any, K comparable, V any](
+	keyFn func(T) K,
+	f func(V, T) V,
+	initialValue V,
+) *RunningReduceByKeyRoutine[T, K, V] {
+	return &RunningReduceByKeyRoutine[T, K, V]{key: keyFn, reduce: f, initial: initialValue}
+}
+
+// Stateful marks RunningReduceByKeyRoutine as a StatefulRoutine, since the accumulator of
+// a key must see all its messages.
+func (r *RunningReduceByKeyRoutine[T, K, V]) Stateful() {}
+
+func (r *RunningReduceByKeyRoutine[T, K, V]) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	slog.Debug("starting running reduce by key routine")
+
+	accumulators := make(map[K]V)
+
+	for msg := range pipe.In() {
+		val, ok := msg.Data.(T)
+		if !ok {
+			slog.Error("running reduce received message with invalid type", "type", reflect.TypeOf(msg.Data))
+
+			continue
+		}
+
+		key := r.key(val)
+
+		acc, found := accumulators[key]
+		if !found {
+			acc = r.initial
+		}
+
+		acc = r.reduce(acc, val)
+		accumulators[key] = acc
+
+		if err := pipe.Send(ctx, msg.WithData(Keyed[K, V]{Key: key, Value: acc})); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -3,9 +3,13 @@ package routines_test
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
 	"github.com/caiorcferreira/goscript/internal/routines"
@@ -577,3 +581,400 @@ func TestTransformRoutine_Run(t *testing.T) {
 		assert.Equal(t, expectedSum, actualSum)
 	})
 }
+
+func TestTransformRoutine_Strict(t *testing.T) {
+	mixed := []pipeline.Msg{
+		{ID: "1", Data: 1},
+		{ID: "2", Data: "two"},
+		{ID: "3", Data: 3},
+		{ID: "4", Data: 4.5},
+	}
+
+	t.Run("sends mismatches to the error channel and drops them", func(t *testing.T) {
+		errs := make(chan error, len(mixed))
+		double := routines.Transform(func(x int) int { return x * 2 }).Strict(errs)
+
+		results := runRoutine(t, double, mixed)
+		close(errs)
+
+		data := make([]any, len(results))
+		for i, msg := range results {
+			data[i] = msg.Data
+		}
+		assert.Equal(t, []any{2, 6}, data)
+
+		var mismatches []*routines.TypeMismatchError
+		for err := range errs {
+			var mismatch *routines.TypeMismatchError
+			require.ErrorAs(t, err, &mismatch)
+			mismatches = append(mismatches, mismatch)
+		}
+		assert.Equal(t, []*routines.TypeMismatchError{
+			{MsgID: "2", Expected: "int", Actual: "string"},
+			{MsgID: "4", Expected: "int", Actual: "float64"},
+		}, mismatches)
+		assert.EqualError(t, mismatches[0], "message 2 has data of type string, expected int")
+	})
+
+	t.Run("returns the first mismatch without an error channel", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			defer close(pipe.In())
+			for _, msg := range mixed {
+				pipe.In() <- msg
+			}
+		}()
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		err := routines.Transform(func(x int) int { return x * 2 }).Strict(nil).Start(context.Background(), pipe)
+
+		var mismatch *routines.TypeMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, "2", mismatch.MsgID)
+		assert.ErrorIs(t, err, routines.ErrTypeMismatch)
+	})
+
+	t.Run("reports nothing by default", func(t *testing.T) {
+		double := routines.Transform(func(x int) int { return x * 2 })
+
+		results := runRoutine(t, double, mixed)
+
+		assert.Len(t, results, len(mixed))
+	})
+}
+
+func TestRunningReduceByKeyRoutine_Run(t *testing.T) {
+	type sale struct {
+		Region string
+		Amount int
+	}
+
+	t.Run("emits running aggregate per key over interleaved input", func(t *testing.T) {
+		sum := routines.RunningReduceByKey(
+			func(s sale) string { return s.Region },
+			func(acc int, s sale) int { return acc + s.Amount },
+			0,
+		)
+
+		input := []pipeline.Msg{
+			{ID: "1", Data: sale{Region: "north", Amount: 10}},
+			{ID: "2", Data: sale{Region: "south", Amount: 5}},
+			{ID: "3", Data: sale{Region: "north", Amount: 1}},
+			{ID: "4", Data: "invalid"},
+			{ID: "5", Data: sale{Region: "south", Amount: 7}},
+			{ID: "6", Data: sale{Region: "north", Amount: 100}},
+		}
+
+		results := runRoutine(t, sum, input)
+
+		expected := []routines.Keyed[string, int]{
+			{Key: "north", Value: 10},
+			{Key: "south", Value: 5},
+			{Key: "north", Value: 11},
+			{Key: "south", Value: 12},
+			{Key: "north", Value: 111},
+		}
+
+		require.Len(t, results, len(expected))
+		for i, msg := range results {
+			assert.Equal(t, expected[i], msg.Data)
+		}
+		assert.Equal(t, "6", results[4].ID)
+	})
+
+	t.Run("starts every key from the initial value", func(t *testing.T) {
+		collect := routines.RunningReduceByKey(
+			func(s string) byte { return s[0] },
+			func(acc []string, s string) []string { return append(slices.Clone(acc), s) },
+			[]string{"start"},
+		)
+
+		results := runRoutine(t, collect, []pipeline.Msg{{Data: "apple"}, {Data: "banana"}, {Data: "avocado"}})
+
+		require.Len(t, results, 3)
+		assert.Equal(t, routines.Keyed[byte, []string]{Key: 'b', Value: []string{"start", "banana"}}, results[1].Data)
+		assert.Equal(t, routines.Keyed[byte, []string]{Key: 'a', Value: []string{"start", "apple", "avocado"}}, results[2].Data)
+	})
+}
+
+func TestFlatMapRoutine_Run(t *testing.T) {
+	words := routines.FlatMap(strings.Fields)
+
+	t.Run("splits lines into words", func(t *testing.T) {
+		input := []pipeline.Msg{{Data: "the quick fox"}, {Data: "jumps"}, {Data: "over  the dog"}}
+
+		results := runRoutine(t, words, input)
+
+		data := make([]any, len(results))
+		for i, msg := range results {
+			data[i] = msg.Data
+		}
+		assert.Equal(t, []any{"the", "quick", "fox", "jumps", "over", "the", "dog"}, data)
+	})
+
+	t.Run("drops inputs mapped to an empty slice", func(t *testing.T) {
+		input := []pipeline.Msg{{Data: "   "}, {Data: "word"}, {Data: ""}}
+
+		results := runRoutine(t, words, input)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, "word", results[0].Data)
+	})
+
+	t.Run("passes through messages of another type", func(t *testing.T) {
+		input := []pipeline.Msg{{ID: "1", Data: 42}}
+
+		results := runRoutine(t, words, input)
+
+		assert.Equal(t, input, results)
+	})
+
+	t.Run("preserves input and result order with new IDs", func(t *testing.T) {
+		repeat := routines.FlatMap(func(x int) []int { return []int{x * 10, x*10 + 1} })
+
+		results := runRoutine(t, repeat, generateTestMsgs(1, 50))
+
+		require.Len(t, results, 100)
+		ids := make(map[string]bool)
+		for i, msg := range results {
+			assert.Equal(t, (i/2+1)*10+i%2, msg.Data)
+			ids[msg.ID] = true
+		}
+		assert.Len(t, ids, 100)
+	})
+
+	t.Run("keeps the meta and ingestion time of the input", func(t *testing.T) {
+		ingestedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		input := []pipeline.Msg{{ID: "1", Data: "a b", IngestedAt: ingestedAt, Meta: map[string]any{"file": "in.txt"}}}
+
+		results := runRoutine(t, words, input)
+
+		require.Len(t, results, 2)
+		for _, msg := range results {
+			assert.NotEqual(t, "1", msg.ID)
+			assert.Equal(t, ingestedAt, msg.IngestedAt)
+			assert.Equal(t, map[string]any{"file": "in.txt"}, msg.Meta)
+		}
+	})
+}
+
+func TestFilterRoutine_Run(t *testing.T) {
+	isEven := routines.Filter(func(x int) bool { return x%2 == 0 })
+
+	t.Run("forwards matching messages in order", func(t *testing.T) {
+		results := runRoutine(t, isEven, generateTestMsgs(1, 6))
+
+		data := make([]int, len(results))
+		for i, msg := range results {
+			data[i] = msg.Data.(int)
+		}
+		assert.Equal(t, []int{2, 4, 6}, data)
+	})
+
+	t.Run("handles empty input", func(t *testing.T) {
+		assert.Empty(t, runRoutine(t, isEven, nil))
+	})
+
+	t.Run("drops everything when nothing matches", func(t *testing.T) {
+		input := []pipeline.Msg{{ID: "1", Data: 1}, {ID: "3", Data: 3}}
+
+		assert.Empty(t, runRoutine(t, isEven, input))
+	})
+
+	t.Run("passes through messages of another type", func(t *testing.T) {
+		input := []pipeline.Msg{{ID: "1", Data: 1}, {ID: "2", Data: "two"}, {ID: "3", Data: 4}, {ID: "4", Data: 3.5}}
+
+		results := runRoutine(t, isEven, input)
+
+		assert.Equal(t, []pipeline.Msg{input[1], input[2], input[3]}, results)
+	})
+
+	t.Run("handles context cancellation", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Filter(func(int) bool { return true }).Start(ctx, pipe)
+		}()
+
+		// nobody reads the output, so once its buffer is full the routine blocks sending
+		// until cancelled
+		pipe.In() <- pipeline.Msg{ID: "1", Data: 1}
+		pipe.In() <- pipeline.Msg{ID: "2", Data: 2}
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("filter did not stop after cancellation")
+		}
+
+		var results []pipeline.Msg
+		for msg := range pipe.Out() {
+			results = append(results, msg)
+		}
+		assert.LessOrEqual(t, len(results), 1)
+	})
+}
+
+func TestStopWhenRoutine_Run(t *testing.T) {
+	isSentinel := func(msg pipeline.Msg) bool { return msg.Data == "EOF" }
+
+	t.Run("forwards messages until the sentinel", func(t *testing.T) {
+		results := runRoutine(t, routines.StopWhen(isSentinel), []pipeline.Msg{
+			{ID: "1", Data: "a"},
+			{ID: "2", Data: "b"},
+			{ID: "3", Data: "EOF"},
+			{ID: "4", Data: "c"},
+		})
+
+		assert.Equal(t, []pipeline.Msg{{ID: "1", Data: "a"}, {ID: "2", Data: "b"}}, results)
+	})
+
+	t.Run("forwards everything without a sentinel", func(t *testing.T) {
+		input := []pipeline.Msg{{ID: "1", Data: "a"}, {ID: "2", Data: "b"}}
+
+		results := runRoutine(t, routines.StopWhen(isSentinel), input)
+
+		assert.Equal(t, input, results)
+	})
+}
+
+func TestTakeRoutine_Run(t *testing.T) {
+	t.Run("forwards the first n messages", func(t *testing.T) {
+		input := generateTestMsgs(1, 10)
+
+		results := runRoutine(t, routines.Take(3), input)
+
+		assert.Equal(t, input[:3], results)
+	})
+
+	t.Run("forwards nothing for zero", func(t *testing.T) {
+		assert.Empty(t, runRoutine(t, routines.Take(0), generateTestMsgs(1, 3)))
+	})
+
+	t.Run("forwards everything when n exceeds the input", func(t *testing.T) {
+		input := generateTestMsgs(1, 3)
+
+		assert.Equal(t, input, runRoutine(t, routines.Take(10), input))
+	})
+
+	t.Run("returns without reading past the limit", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Take(2).Start(context.Background(), pipe)
+		}()
+
+		// the input is never closed, so only reaching the limit ends the routine
+		pipe.In() <- pipeline.Msg{Data: 1}
+		pipe.In() <- pipeline.Msg{Data: 2}
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("take did not return at its limit")
+		}
+	})
+}
+
+func TestSkipRoutine_Run(t *testing.T) {
+	t.Run("forwards the messages after the first n", func(t *testing.T) {
+		input := []pipeline.Msg{{ID: "h", Data: "id,name"}, {ID: "1", Data: "1,ana"}, {ID: "2", Data: "2,bia"}}
+
+		results := runRoutine(t, routines.Skip(1), input)
+
+		assert.Equal(t, input[1:], results)
+	})
+
+	t.Run("forwards everything for zero", func(t *testing.T) {
+		input := generateTestMsgs(1, 3)
+
+		assert.Equal(t, input, runRoutine(t, routines.Skip(0), input))
+	})
+
+	t.Run("forwards nothing when n exceeds the input", func(t *testing.T) {
+		assert.Empty(t, runRoutine(t, routines.Skip(5), generateTestMsgs(1, 3)))
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		pipe := pipeline.NewChanPipe()
+		pipe.In() <- pipeline.Msg{Data: 1}
+		pipe.Out() <- pipeline.Msg{Data: "fills the output"}
+		close(pipe.In())
+
+		assert.NoError(t, routines.Skip(0).Start(ctx, pipe))
+
+		assert.Equal(t, "fills the output", (<-pipe.Out()).Data)
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+	})
+}
+
+func TestPipeline_CancellationNeverPanics(t *testing.T) {
+	var panics atomic.Int64
+
+	for round := range 200 {
+		p := pipeline.New().
+			Chain(routines.Transform(func(x int) int { return x + 1 })).
+			Chain(routines.FlatMap(func(x int) []any { return []any{x, x} })).
+			Chain(routines.Parallel(routines.Transform(func(x int) int { return x * 2 }), 4)).
+			Chain(routines.Batch(3)).
+			OnPanic(func(any, []byte) { panics.Add(1) })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		pipe := pipeline.NewChanPipe()
+
+		go func() {
+			defer close(pipe.In())
+			for i := 0; ctx.Err() == nil; i++ {
+				select {
+				case <-ctx.Done():
+				case pipe.In() <- pipeline.Msg{Data: i}:
+				}
+			}
+		}()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- p.Start(ctx, pipe)
+		}()
+
+		// stop reading at an arbitrary point, sometimes before cancelling
+		stopAfter := rand.IntN(50)
+		go func() {
+			for range pipe.Out() {
+				if stopAfter--; stopAfter == 0 && round%2 == 0 {
+					return
+				}
+			}
+		}()
+
+		time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("round %d: pipeline did not stop after cancellation", round)
+		}
+	}
+
+	assert.Zero(t, panics.Load())
+}
@@ -0,0 +1,236 @@
+package routines
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// MergeSortedRoutine is a source performing a k-way merge of individually sorted sources,
+// always emitting the globally smallest head next.
+type MergeSortedRoutine struct {
+	less    func(a, b pipeline.Msg) bool
+	sources []pipeline.Routine
+}
+
+// MergeSorted merges sources, each of which must already be sorted by less, into one
+// globally sorted stream. Equal messages keep the order of the sources they came from.
+//
+// Example:
+//
+//	byTime := func(a, b pipeline.Msg) bool { return ts(a).Before(ts(b)) }
+//	script.In(routines.MergeSorted(byTime, filesystem.File("a.log").Read(), filesystem.File("b.log").Read()))
+func MergeSorted(less func(a, b pipeline.Msg) bool, sources ...pipeline.Routine) *MergeSortedRoutine {
+	return &MergeSortedRoutine{less: less, sources: sources}
+}
+
+// PreservesOrder reports false: the merged stream interleaves several sources, so it has
+// no single input order to keep.
+func (m *MergeSortedRoutine) PreservesOrder() bool {
+	return false
+}
+
+func (m *MergeSortedRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	// stop every source once the merge ends
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cursors := make([]*mergeCursor, len(m.sources))
+	for i, source := range m.sources {
+		cursors[i] = startMergeSource(ctx, i, source)
+	}
+
+	return mergeCursors(cursors, m.less, func(msg pipeline.Msg) bool {
+		return pipe.Send(ctx, msg) == nil
+	})
+}
+
+// RoundRobinMergeRoutine is a source interleaving other sources one message at a time.
+type RoundRobinMergeRoutine struct {
+	sources []pipeline.Routine
+}
+
+// RoundRobinMerge emits one message from each source in turn, in the order given, and
+// skips a source once it is exhausted, until all of them are. The interleaving is
+// predictable, which suits tests and fair sampling, but a slow source holds the others
+// back while it is waited on.
+//
+// Example:
+//
+//	script.In(routines.RoundRobinMerge(filesystem.File("a.txt").Read(), filesystem.File("b.txt").Read()))
+func RoundRobinMerge(sources ...pipeline.Routine) *RoundRobinMergeRoutine {
+	return &RoundRobinMergeRoutine{sources: sources}
+}
+
+// PreservesOrder reports false: the merged stream interleaves several sources, so it has
+// no single input order to keep.
+func (r *RoundRobinMergeRoutine) PreservesOrder() bool {
+	return false
+}
+
+func (r *RoundRobinMergeRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	// stop every source once the merge ends
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	active := make([]*mergeCursor, len(r.sources))
+	for i, source := range r.sources {
+		active[i] = startMergeSource(ctx, i, source)
+	}
+
+	for len(active) > 0 {
+		for i := 0; i < len(active); {
+			ok, err := active[i].advance()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				active = slices.Delete(active, i, i+1)
+				continue
+			}
+
+			if err := pipe.Send(ctx, active[i].head); err != nil {
+				return nil
+			}
+			i++
+		}
+	}
+
+	return nil
+}
+
+// startMergeSource runs source in the background and returns a cursor over its output.
+// Once the output is exhausted the cursor reports the error the source returned, if any,
+// so a failing source fails the merge.
+func startMergeSource(ctx context.Context, index int, source pipeline.Routine) *mergeCursor {
+	sourcePipe := pipeline.NewChanPipe()
+
+	errCh := make(chan error, 1)
+	pipeline.Go(ctx, func() {
+		// a recovered panic leaves no error to read
+		defer close(errCh)
+
+		errCh <- source.Start(ctx, sourcePipe)
+	})
+
+	return &mergeCursor{
+		index: index,
+		pull: func() (pipeline.Msg, bool, error) {
+			select {
+			case <-ctx.Done():
+				return pipeline.Msg{}, false, nil
+			case msg, ok := <-sourcePipe.Out():
+				if ok {
+					return msg, true, nil
+				}
+			}
+
+			// the source closes its pipe before returning, so its error is on the way
+			select {
+			case <-ctx.Done():
+				return pipeline.Msg{}, false, nil
+			case err := <-errCh:
+				if err != nil {
+					return pipeline.Msg{}, false, fmt.Errorf("merge source %d failed: %w", index, err)
+				}
+				return pipeline.Msg{}, false, nil
+			}
+		},
+	}
+}
+
+// mergeCursor is one input of a merge, positioned on its current head.
+type mergeCursor struct {
+	index int
+	head  pipeline.Msg
+	pull  func() (pipeline.Msg, bool, error)
+}
+
+// advance loads the next message into head, reporting false once the input is exhausted.
+func (c *mergeCursor) advance() (bool, error) {
+	msg, ok, err := c.pull()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	c.head = msg
+
+	return true, nil
+}
+
+// mergeCursors emits the smallest head among cursors until all of them are exhausted or
+// emit reports false.
+func mergeCursors(cursors []*mergeCursor, less func(a, b pipeline.Msg) bool, emit func(pipeline.Msg) bool) error {
+	heads := &mergeHeap{less: less}
+
+	for _, cursor := range cursors {
+		ok, err := cursor.advance()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heads.items = append(heads.items, cursor)
+		}
+	}
+
+	heap.Init(heads)
+
+	for heads.Len() > 0 {
+		cursor := heads.items[0]
+
+		if !emit(cursor.head) {
+			return nil
+		}
+
+		ok, err := cursor.advance()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			heap.Fix(heads, 0)
+		} else {
+			heap.Pop(heads)
+		}
+	}
+
+	return nil
+}
+
+// mergeHeap orders cursors by their head; ties go to the earlier cursor to keep merges stable.
+type mergeHeap struct {
+	items []*mergeCursor
+	less  func(a, b pipeline.Msg) bool
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.head, b.head) {
+		return true
+	}
+	if h.less(b.head, a.head) {
+		return false
+	}
+
+	return a.index < b.index
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x any) { h.items = append(h.items, x.(*mergeCursor)) }
+
+func (h *mergeHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+
+	return last
+}
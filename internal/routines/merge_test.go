@@ -0,0 +1,147 @@
+package routines_test
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceSource emits msgs and closes its pipe.
+type sliceSource []pipeline.Msg
+
+func (s sliceSource) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for _, msg := range s {
+		select {
+		case <-ctx.Done():
+			return nil
+		case pipe.Out() <- msg:
+		}
+	}
+
+	return nil
+}
+
+// startMerge runs a merge, discarding its output, and returns its error.
+func startMerge(merge pipeline.Routine) error {
+	pipe := pipeline.NewChanPipe()
+	go func() {
+		for range pipe.Out() {
+		}
+	}()
+
+	return merge.Start(context.Background(), pipe)
+}
+
+func TestMergeSortedRoutine_Start(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(id string, minute int) pipeline.Msg {
+		return pipeline.Msg{ID: id, Data: base.Add(time.Duration(minute) * time.Minute)}
+	}
+	byTime := func(a, b pipeline.Msg) bool {
+		return a.Data.(time.Time).Before(b.Data.(time.Time))
+	}
+
+	t.Run("merges timestamp sorted streams into one sorted stream", func(t *testing.T) {
+		first := sliceSource{at("a1", 1), at("a2", 4), at("a3", 7), at("a4", 10)}
+		second := sliceSource{at("b1", 2), at("b2", 3), at("b3", 9)}
+		third := sliceSource{at("c1", 0), at("c2", 5), at("c3", 6), at("c4", 8), at("c5", 11)}
+
+		results := runRoutine(t, routines.MergeSorted(byTime, first, second, third), nil)
+
+		require.Len(t, results, 12)
+		for i := 1; i < len(results); i++ {
+			assert.False(t, byTime(results[i], results[i-1]), "out of order at %d", i)
+		}
+
+		ids := make([]string, len(results))
+		for i, msg := range results {
+			ids[i] = msg.ID
+		}
+		assert.Equal(t, []string{"c1", "a1", "b1", "b2", "a2", "c2", "c3", "a3", "c4", "b3", "a4", "c5"}, ids)
+	})
+
+	t.Run("emits ties in source order", func(t *testing.T) {
+		first := sliceSource{at("a", 1)}
+		second := sliceSource{at("b", 1)}
+
+		results := runRoutine(t, routines.MergeSorted(byTime, second, first), nil)
+
+		require.Len(t, results, 2)
+		assert.Equal(t, "b", results[0].ID)
+		assert.Equal(t, "a", results[1].ID)
+	})
+
+	t.Run("fails when a source fails", func(t *testing.T) {
+		missing := filesystem.File(filepath.Join(t.TempDir(), "missing.txt")).Read()
+
+		err := startMerge(routines.MergeSorted(byTime, sliceSource{at("a", 1)}, missing))
+
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		assert.ErrorContains(t, err, "merge source 1 failed")
+	})
+
+	t.Run("handles empty sources", func(t *testing.T) {
+		results := runRoutine(t, routines.MergeSorted(byTime, sliceSource{}, sliceSource{at("a", 1)}), nil)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, "a", results[0].ID)
+	})
+}
+
+func TestRoundRobinMergeRoutine_Start(t *testing.T) {
+	source := func(ids ...string) sliceSource {
+		msgs := make(sliceSource, len(ids))
+		for i, id := range ids {
+			msgs[i] = pipeline.Msg{ID: id, Data: id}
+		}
+		return msgs
+	}
+	ids := func(msgs []pipeline.Msg) []string {
+		out := make([]string, len(msgs))
+		for i, msg := range msgs {
+			out[i] = msg.ID
+		}
+		return out
+	}
+
+	t.Run("takes one message from each source in turn", func(t *testing.T) {
+		merge := routines.RoundRobinMerge(source("a1", "a2", "a3"), source("b1", "b2", "b3"), source("c1", "c2", "c3"))
+
+		results := runRoutine(t, merge, nil)
+
+		assert.Equal(t, []string{"a1", "b1", "c1", "a2", "b2", "c2", "a3", "b3", "c3"}, ids(results))
+	})
+
+	t.Run("skips exhausted sources", func(t *testing.T) {
+		merge := routines.RoundRobinMerge(source("a1"), source("b1", "b2", "b3", "b4"), source("c1", "c2"))
+
+		results := runRoutine(t, merge, nil)
+
+		assert.Equal(t, []string{"a1", "b1", "c1", "b2", "c2", "b3", "b4"}, ids(results))
+	})
+
+	t.Run("fails when a source fails", func(t *testing.T) {
+		missing := filesystem.File(filepath.Join(t.TempDir(), "missing.txt")).Read()
+
+		err := startMerge(routines.RoundRobinMerge(source("a1", "a2"), missing))
+
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		assert.ErrorContains(t, err, "merge source 1 failed")
+	})
+
+	t.Run("handles empty sources", func(t *testing.T) {
+		results := runRoutine(t, routines.RoundRobinMerge(source(), source("b1"), source()), nil)
+
+		assert.Equal(t, []string{"b1"}, ids(results))
+	})
+}
@@ -0,0 +1,112 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// MovingAverageRoutine replaces each numeric message with the average of the last window
+// numeric values, the current one included. Until the window fills, the average covers
+// every value seen so far.
+type MovingAverageRoutine struct {
+	window           int
+	failOnNonNumeric bool
+}
+
+func MovingAverage(window int) *MovingAverageRoutine {
+	return &MovingAverageRoutine{window: window}
+}
+
+// FailOnNonNumeric makes the routine stop with an error on a non-numeric message instead
+// of passing it through unchanged.
+func (m *MovingAverageRoutine) FailOnNonNumeric() *MovingAverageRoutine {
+	m.failOnNonNumeric = true
+	return m
+}
+
+// Stateful marks MovingAverageRoutine as a StatefulRoutine, since the window holds the
+// latest values of the stream.
+func (m *MovingAverageRoutine) Stateful() {}
+
+func (m *MovingAverageRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if m.window <= 0 {
+		return fmt.Errorf("moving average window must be positive, got %d", m.window)
+	}
+
+	ring := make([]float64, m.window)
+	var (
+		next, filled int
+		sum          float64
+	)
+
+	for msg := range pipe.In() {
+		value, ok := toFloat64(msg.Data)
+		if !ok {
+			if m.failOnNonNumeric {
+				return fmt.Errorf("moving average received non-numeric message %s of type %T", msg.ID, msg.Data)
+			}
+
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		sum += value - ring[next]
+		ring[next] = value
+		next = (next + 1) % m.window
+		filled = min(filled+1, m.window)
+
+		// the running sum drifts with rounding errors and stays NaN once an infinity has
+		// left the window, so it is recomputed once per window and whenever it is not finite
+		if next == 0 || math.IsNaN(sum) || math.IsInf(sum, 0) {
+			sum = 0
+			for _, v := range ring {
+				sum += v
+			}
+		}
+
+		if err := pipe.Send(ctx, msg.WithData(sum/float64(filled))); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// toFloat64 converts any Go numeric value to float64.
+func toFloat64(data any) (float64, bool) {
+	switch v := data.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,58 @@
+package routines_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovingAverageRoutine_Start(t *testing.T) {
+	t.Run("averages the last window values including warm-up", func(t *testing.T) {
+		input := []pipeline.Msg{{Data: 2}, {Data: 4}, {Data: 6.0}, {Data: int64(8)}, {Data: float32(10)}}
+
+		results := runRoutine(t, routines.MovingAverage(3), input)
+
+		require.Len(t, results, 5)
+		expected := []float64{2, 3, 4, 6, 8}
+		for i, msg := range results {
+			assert.InDelta(t, expected[i], msg.Data, 1e-9, "message %d", i)
+		}
+	})
+
+	t.Run("recovers once an infinity leaves the window", func(t *testing.T) {
+		input := []pipeline.Msg{{Data: 1}, {Data: math.Inf(1)}, {Data: 1}, {Data: 3}, {Data: 5}}
+
+		results := runRoutine(t, routines.MovingAverage(2), input)
+
+		require.Len(t, results, 5)
+		assert.Equal(t, []any{1.0, math.Inf(1), math.Inf(1), 2.0, 4.0}, []any{
+			results[0].Data, results[1].Data, results[2].Data, results[3].Data, results[4].Data,
+		})
+	})
+
+	t.Run("passes non-numeric messages through by default", func(t *testing.T) {
+		input := []pipeline.Msg{{Data: 1}, {ID: "x", Data: "n/a"}, {Data: 3}}
+
+		results := runRoutine(t, routines.MovingAverage(2), input)
+
+		require.Len(t, results, 3)
+		assert.Equal(t, "n/a", results[1].Data)
+		assert.InDelta(t, 2.0, results[2].Data, 1e-9)
+	})
+
+	t.Run("fails on non-numeric messages when configured", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			pipe.In() <- pipeline.Msg{ID: "x", Data: "n/a"}
+			close(pipe.In())
+		}()
+
+		err := routines.MovingAverage(2).FailOnNonNumeric().Start(t.Context(), pipe)
+
+		assert.ErrorContains(t, err, "non-numeric message x")
+	})
+}
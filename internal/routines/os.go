@@ -6,63 +6,123 @@ import (
 	"io"
 	"log/slog"
 	"os"
-	"time"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
 )
 
+// StdInRoutine parses stdin with a read codec, one line per message by default, and
+// finishes once stdin is exhausted, so piped input like `cat data.json | mytool` flows
+// through the same codecs as files.
 type StdInRoutine struct {
-	pipe pipeline.Pipe
+	pipe   pipeline.Pipe
+	reader io.Reader
+	codec  filesystem.ReadCodec
 }
 
 func NewStdInRoutine() *StdInRoutine {
-	return &StdInRoutine{}
+	return &StdInRoutine{reader: os.Stdin, codec: filesystem.NewLineCodec()}
+}
+
+// StdIn is shorthand for NewStdInRoutine.
+func StdIn() *StdInRoutine {
+	return NewStdInRoutine()
 }
 
 func (p *StdInRoutine) Pipe(pipe pipeline.Pipe) {
 	p.pipe = pipe
 }
 
-func (p *StdInRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
-	w := &stdinWriter{pipe: pipe}
+// WithCodec parses stdin with codec instead of line by line
+func (p *StdInRoutine) WithCodec(codec filesystem.ReadCodec) *StdInRoutine {
+	p.codec = codec
+	return p
+}
 
-	for {
-		time.Sleep(1 * time.Second) //todo: avoid busy waiting
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			//todo: handle error
-			io.Copy(w, os.Stdin)
-		}
-	}
+// WithJSONCodec parses stdin as JSON, emitting each element of a top-level array or the
+// whole document
+func (p *StdInRoutine) WithJSONCodec() *StdInRoutine {
+	p.codec = filesystem.NewJSONCodec()
+	return p
 }
 
-type stdinWriter struct {
-	pipe pipeline.Pipe
+// WithReader reads from reader instead of os.Stdin, e.g. to feed input in tests
+func (p *StdInRoutine) WithReader(reader io.Reader) *StdInRoutine {
+	p.reader = reader
+	return p
 }
 
-func (p *stdinWriter) Write(data []byte) (n int, err error) {
-	msg := pipeline.Msg{
-		ID:   "",
-		Data: data,
+func (p *StdInRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if err := filesystem.Parse(ctx, p.codec, p.reader, pipe); err != nil {
+		return fmt.Errorf("failed to parse stdin with codec: %w", err)
 	}
-	p.pipe.Out() <- msg
-	return len(data), nil
+
+	return nil
 }
 
-type StdOutRoutine struct{}
+// StdOutRoutine writes every message to stdout, one line per message by default, so the
+// lines StdIn parses come out as they went in. Set a codec for other formats, e.g. the JSON
+// write codec for structured messages.
+type StdOutRoutine struct {
+	codec filesystem.WriteCodec
+}
 
 func NewStdOutRoutine() *StdOutRoutine {
-	return &StdOutRoutine{}
+	return &StdOutRoutine{codec: filesystem.NewLineCodec()}
+}
+
+// StdOut is shorthand for NewStdOutRoutine.
+func StdOut() *StdOutRoutine {
+	return NewStdOutRoutine()
+}
+
+// WithCodec serializes messages with codec instead of writing one line per message
+func (p *StdOutRoutine) WithCodec(codec filesystem.WriteCodec) *StdOutRoutine {
+	p.codec = codec
+	return p
+}
+
+// WithRawOutput writes strings and byte slices as is, without a trailing newline, for
+// messages that already carry their own line endings
+func (p *StdOutRoutine) WithRawOutput() *StdOutRoutine {
+	p.codec = nil
+	return p
+}
+
+// WithJSONCodec writes one JSON document per line
+func (p *StdOutRoutine) WithJSONCodec() *StdOutRoutine {
+	p.codec = filesystem.NewJSONWriteCodec()
+	return p
 }
 
 func (p *StdOutRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if stream, ok := p.codec.(filesystem.StreamWriteCodec); ok {
+		if err := stream.EncodeStream(ctx, pipe.In(), os.Stdout); err != nil {
+			return fmt.Errorf("failed to encode to stdout: %w", err)
+		}
+		return nil
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case msg := <-pipe.In():
+		case msg, ok := <-pipe.In():
+			if !ok {
+				return nil
+			}
+
+			if p.codec != nil {
+				if err := p.codec.Encode(ctx, msg, os.Stdout); err != nil {
+					return fmt.Errorf("failed to encode to stdout: %w", err)
+				}
+				continue
+			}
+
 			switch v := msg.Data.(type) {
 			case string:
 				os.Stdout.Write([]byte(v))
@@ -74,3 +134,26 @@ func (p *StdOutRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
 		}
 	}
 }
+
+// DiscardRoutine consumes and drops every message, for pipelines run only for the side
+// effects of earlier stages or to benchmark processing without output cost.
+type DiscardRoutine struct{}
+
+func Discard() *DiscardRoutine {
+	return &DiscardRoutine{}
+}
+
+func (d *DiscardRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-pipe.In():
+			if !ok {
+				return nil
+			}
+		}
+	}
+}
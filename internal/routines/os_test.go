@@ -0,0 +1,171 @@
+package routines_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureStdout runs fn with os.Stdout redirected and returns what it wrote.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+
+	original := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = original }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	fn()
+
+	require.NoError(t, writer.Close())
+
+	return <-output
+}
+
+func TestStdInRoutine_Start(t *testing.T) {
+	read := func(t *testing.T, routine *routines.StdInRoutine) []any {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- routine.Start(context.Background(), pipe)
+		}()
+
+		var results []any
+		for msg := range pipe.Out() {
+			results = append(results, msg.Data)
+		}
+		require.NoError(t, <-done)
+
+		return results
+	}
+
+	t.Run("parses lines by default", func(t *testing.T) {
+		results := read(t, routines.StdIn().WithReader(strings.NewReader("first\nsecond\n")))
+
+		assert.Equal(t, []any{"first", "second"}, results)
+	})
+
+	t.Run("parses JSON with the JSON codec", func(t *testing.T) {
+		input := `[{"name": "ana", "age": 30}, {"name": "bob", "age": 25}]`
+
+		results := read(t, routines.StdIn().WithJSONCodec().WithReader(strings.NewReader(input)))
+
+		assert.Equal(t, []any{
+			map[string]any{"name": "ana", "age": float64(30)},
+			map[string]any{"name": "bob", "age": float64(25)},
+		}, results)
+	})
+
+	t.Run("parses with a custom codec", func(t *testing.T) {
+		codec := filesystem.NewJSONCodec().WithJSONLinesMode()
+		input := "{\"id\": 1}\n{\"id\": 2}\n"
+
+		results := read(t, routines.StdIn().WithCodec(codec).WithReader(strings.NewReader(input)))
+
+		assert.Equal(t, []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}}, results)
+	})
+
+	t.Run("returns codec errors", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		err := routines.StdIn().WithJSONCodec().WithReader(strings.NewReader("{broken")).Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "failed to parse stdin")
+	})
+}
+
+func TestStdOutRoutine_Start(t *testing.T) {
+	records := []pipeline.Msg{
+		{Data: map[string]any{"name": "John", "tags": []any{"a", "b"}}},
+		{Data: map[string]any{"name": "Jane", "age": 30}},
+	}
+
+	t.Run("writes one line per message by default", func(t *testing.T) {
+		out := captureStdout(t, func() {
+			runRoutine(t, routines.StdOut(), []pipeline.Msg{{Data: "a"}, {Data: []byte("b")}, {Data: 3}})
+		})
+
+		assert.Equal(t, "a\nb\n3\n", out)
+	})
+
+	t.Run("writes raw strings and bytes with raw output", func(t *testing.T) {
+		out := captureStdout(t, func() {
+			runRoutine(t, routines.StdOut().WithRawOutput(), []pipeline.Msg{{Data: "a\n"}, {Data: []byte("b")}})
+		})
+
+		assert.Equal(t, "a\nb", out)
+	})
+
+	t.Run("writes valid JSON lines with the JSON codec", func(t *testing.T) {
+		out := captureStdout(t, func() {
+			runRoutine(t, routines.StdOut().WithJSONCodec(), records)
+		})
+
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 2)
+		for _, line := range lines {
+			assert.True(t, json.Valid([]byte(line)), line)
+		}
+		assert.JSONEq(t, `{"name": "John", "tags": ["a", "b"]}`, lines[0])
+	})
+
+	t.Run("writes a JSON array with an array codec", func(t *testing.T) {
+		out := captureStdout(t, func() {
+			runRoutine(t, routines.StdOut().WithCodec(filesystem.NewJSONWriteCodec().WithJSONArrayMode()), records)
+		})
+
+		assert.JSONEq(t, `[{"name": "John", "tags": ["a", "b"]}, {"name": "Jane", "age": 30}]`, out)
+	})
+
+	t.Run("uses per message encoding for plain write codecs", func(t *testing.T) {
+		out := captureStdout(t, func() {
+			runRoutine(t, routines.StdOut().WithCodec(filesystem.NewCSVCodec()), []pipeline.Msg{{Data: []string{"a", "b"}}})
+		})
+
+		assert.Equal(t, "a,b\n", out)
+	})
+}
+
+func TestDiscardRoutine_Start(t *testing.T) {
+	t.Run("consumes the full stream without output and closes", func(t *testing.T) {
+		var results []pipeline.Msg
+		out := captureStdout(t, func() {
+			results = runRoutine(t, routines.Discard(), generateTestMsgs(1, 1000))
+		})
+
+		assert.Empty(t, results)
+		assert.Empty(t, out)
+	})
+
+	t.Run("stops on context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := routines.Discard().Start(ctx, pipeline.NewChanPipe())
+
+		assert.NoError(t, err)
+	})
+}
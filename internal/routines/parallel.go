@@ -2,100 +2,576 @@ package routines
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"runtime"
+	"slices"
 	"sync"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
 )
 
+// ioBoundMultiplier is how many workers per CPU are used for IO-bound work.
+const ioBoundMultiplier = 4
+
+// Concurrency is a worker count for Parallel.
+type Concurrency int
+
+// AutoConcurrency sizes Parallel to one worker per CPU, suited to CPU-bound routines.
+//
+// Example:
+//
+//	routines.Parallel(r, routines.AutoConcurrency().IOBound())
+func AutoConcurrency() Concurrency {
+	return Concurrency(runtime.NumCPU())
+}
+
+// IOBound scales the worker count for routines that mostly wait on IO.
+func (c Concurrency) IOBound() Concurrency {
+	return c * ioBoundMultiplier
+}
+
+// defaultReorderBuffer is how many results an ordered Parallel holds back, waiting for an
+// earlier slow result, unless WithMaxBuffer is set.
+const defaultReorderBuffer = 256
+
+// ReorderPolicy selects what an ordered Parallel does when its reorder buffer is full
+// because an earlier message is still being processed.
+type ReorderPolicy int
+
+const (
+	// BlockWhenFull stops taking results, and so new inputs, until the awaited result
+	// arrives. Order is never broken, but a stuck message stalls the stream. It is the default.
+	BlockWhenFull ReorderPolicy = iota
+	// SkipWhenFull gives up on the awaited message, and on the inputs already queued to
+	// the same worker, logging their positions, and moves on to the buffered results. A
+	// skipped result arriving later is dropped. Results behind a stuck message when the
+	// input ends wait until it arrives or ctx is done.
+	SkipWhenFull
+)
+
+// StatefulRoutine is implemented by routines whose result depends on more than one message,
+// such as Reduce's accumulator, Sort or Take. Parallel starts the routine once per worker
+// and gives each a share of the stream, so it refuses stateful routines instead of
+// returning partial results per worker or letting workers race on shared state.
+type StatefulRoutine interface {
+	pipeline.Routine
+	// Stateful marks the routine; it is never called.
+	Stateful()
+}
+
+// ErrStatefulRoutine is returned by Parallel when asked to run a StatefulRoutine.
+var ErrStatefulRoutine = errors.New("stateful routine cannot run in parallel")
+
+// ErrResultCountMismatch is returned by an ordered Parallel whose routine does not emit
+// exactly one message per input.
+var ErrResultCountMismatch = errors.New("ordered parallel routine must emit one message per input")
+
+// OrderAware is implemented by routines that may emit messages in a different order than
+// they receive them, reporting whether they are configured to keep the input order.
+type OrderAware interface {
+	PreservesOrder() bool
+}
+
 type ParallelRoutine struct {
 	routine        pipeline.Routine
 	maxConcurrency int
+	pool           *WorkerPool
+	ordered        bool
+	maxBuffer      int
+	reorderPolicy  ReorderPolicy
 }
 
-func Parallel(r pipeline.Routine, maxConcurrency int) ParallelRoutine {
+func Parallel[C ~int](r pipeline.Routine, maxConcurrency C) ParallelRoutine {
 	return ParallelRoutine{
 		routine:        r,
-		maxConcurrency: maxConcurrency,
+		maxConcurrency: int(maxConcurrency),
+	}
+}
+
+// WithPool runs the fan-in, fan-out and worker goroutines on pool, letting repeated runs
+// reuse goroutines. The pool should hold at least 2*maxConcurrency+1 goroutines, or
+// 3*maxConcurrency+2 when ordered, to avoid spawning any. The pool also reuses the worker
+// pipes of finished runs.
+func (p ParallelRoutine) WithPool(pool *WorkerPool) ParallelRoutine {
+	p.pool = pool
+	return p
+}
+
+// Ordered emits results in input order. Each input goes to the next free worker and
+// results finishing early wait in a reorder buffer for the earlier ones, see WithMaxBuffer.
+// Results are matched to inputs by worker, so the routine must emit exactly one message per
+// input. Start fails with ErrResultCountMismatch as soon as a worker emits more results than
+// it was given inputs, or once its input ends with results missing; results emitted before
+// a missing one is noticed may have been matched to the wrong inputs.
+func (p ParallelRoutine) Ordered() ParallelRoutine {
+	p.ordered = true
+	return p
+}
+
+// PreservesOrder reports whether the routine is Ordered.
+func (p ParallelRoutine) PreservesOrder() bool {
+	return p.ordered
+}
+
+// WithMaxBuffer caps how many results an ordered Parallel holds back while waiting for an
+// earlier one, and sets what happens once the cap is reached. Defaults to 256 results
+// with BlockWhenFull.
+func (p ParallelRoutine) WithMaxBuffer(n int, policy ReorderPolicy) ParallelRoutine {
+	p.maxBuffer = n
+	p.reorderPolicy = policy
+	return p
+}
+
+// spawn runs task on a new goroutine, or on the pool if one is set, reporting a panic to
+// the handler set on ctx.
+func (p ParallelRoutine) spawn(ctx context.Context, task func()) {
+	task = pipeline.Guard(ctx, task)
+
+	if p.pool == nil {
+		go task()
+		return
 	}
+
+	p.pool.Go(task)
+}
+
+// Describe summarizes the concurrency and the routine run in parallel, for Script.Describe.
+func (p ParallelRoutine) Describe() string {
+	mode := "unordered"
+	if p.ordered {
+		mode = "ordered"
+	}
+
+	return fmt.Sprintf("parallel x%d %s: %s", p.maxConcurrency, mode, pipeline.Describe(p.routine))
 }
 
 func (p ParallelRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
 	defer pipe.Close()
 
-	subpipes := make([]*pipeline.ChannelPipe, p.maxConcurrency)
-	for i := range p.maxConcurrency {
-		subpipes[i] = pipeline.NewChanPipe()
+	if _, ok := p.routine.(StatefulRoutine); ok {
+		return fmt.Errorf("%w: %T keeps state across messages and each worker would only see part of them; run it without Parallel", ErrStatefulRoutine, p.routine)
+	}
+
+	if p.maxConcurrency < 1 {
+		return fmt.Errorf("parallel concurrency must be at least 1, got %d", p.maxConcurrency)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// each worker's pipe is used by the worker and its feeding and collecting goroutines,
+	// plus the shared fan-out when unordered
+	users := 2*p.maxConcurrency + 1
+	if p.ordered {
+		users = 3 * p.maxConcurrency
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(p.maxConcurrency)
+	workers := p.pool.workerPipes(p.maxConcurrency, int32(users))
+	subpipes := workers.pipes
 
-	// fan-in from subpipes to output
-	for _, sp := range subpipes {
-		go func() {
-			// we need to wait until all subpipes are drained
-			defer func() {
-				wg.Done()
-			}()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	if p.ordered {
+		maxBuffer := p.maxBuffer
+		if maxBuffer <= 0 {
+			maxBuffer = defaultReorderBuffer
+		}
+
+		reorder := newReorderBuffer(ctx, maxBuffer, p.reorderPolicy, p.maxConcurrency)
+		defer reorder.stop()
+
+		// assigned[i] holds, in order, the sequence numbers of the inputs given to worker i
+		assigned := make([]*seqQueue, p.maxConcurrency)
+		for i := range assigned {
+			assigned[i] = &seqQueue{}
+		}
 
-			for data := range sp.Out() {
-				select {
-				case <-ctx.Done():
-					return
-				case pipe.Out() <- data:
+		fail := func(err error) {
+			errOnce.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+
+		jobs := make(chan sequencedMsg)
+
+		wg.Add(1)
+		p.spawn(ctx, func() {
+			defer wg.Done()
+			reorder.emit(ctx, pipe)
+		})
+		p.spawn(ctx, func() {
+			p.dispatchSequenced(ctx, pipe, jobs)
+		})
+		for i, sp := range subpipes {
+			p.spawn(ctx, func() {
+				defer workers.release()
+				p.feedWorker(ctx, jobs, assigned[i], sp)
+			})
+			p.spawn(ctx, func() {
+				defer workers.release()
+				defer reorder.workerDone()
+				if err := p.collectSequenced(ctx, i, sp, assigned[i], reorder); err != nil {
+					fail(err)
 				}
-			}
-		}()
+			})
+		}
+	} else {
+		wg.Add(p.maxConcurrency)
+		for _, sp := range subpipes {
+			p.spawn(ctx, func() {
+				// we need to wait until all subpipes are drained
+				defer wg.Done()
+				defer workers.release()
+				p.fanIn(ctx, sp, pipe)
+			})
+		}
+		p.spawn(ctx, func() {
+			defer workers.release()
+			p.fanOut(ctx, subpipes, pipe)
+		})
 	}
 
-	// fan-out input to subpipes
-	go func() {
-		defer func() {
-			for _, sp := range subpipes {
-				close(sp.In())
-			}
-		}()
+	// start worker goroutines
+	for i := range p.maxConcurrency {
+		p.spawn(ctx, func() {
+			defer workers.release()
+			p.routine.Start(ctx, subpipes[i])
+		})
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// fanIn forwards every result of one worker to the output.
+func (p ParallelRoutine) fanIn(ctx context.Context, sp *pipeline.ChannelPipe, pipe pipeline.Pipe) {
+	for data := range sp.Out() {
+		if err := pipe.Send(ctx, data); err != nil {
+			return
+		}
+	}
+}
+
+// fanOut sends each input to the first worker able to take it, starting from the one after
+// the last worker used.
+func (p ParallelRoutine) fanOut(ctx context.Context, subpipes []*pipeline.ChannelPipe, pipe pipeline.Pipe) {
+	defer func() {
+		for _, sp := range subpipes {
+			close(sp.In())
+		}
+	}()
 
-		roundRobinIndex := 0
+	roundRobinIndex := 0
 
-		for data := range pipe.In() {
+	for {
+		// waiting on ctx too, so a cancelled run stops even when no input arrives
+		var data pipeline.Msg
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-pipe.In():
+			if !ok {
+				return
+			}
+			data = msg
+		}
+
+		// trie to send msg to subpipe at roundRobinIndex
+		// if it fails, try the next one in round-robin fashion
+		// it will keep trying until it succeeds
+		for {
+			sent := false
 			select {
 			case <-ctx.Done():
 				return
+			case subpipes[roundRobinIndex].In() <- data:
+				// data sent successfully
+				sent = true
 			default:
-				// trie to send msg to subpipe at roundRobinIndex
-				// if it fails, try the next one in round-robin fashion
-				// it will keep trying until it succeeds
-				for {
-					sent := false
-					select {
-					case <-ctx.Done():
-						return
-					case subpipes[roundRobinIndex].In() <- data:
-						// data sent successfully
-						sent = true
-					default:
-						sent = false
-					}
-
-					roundRobinIndex = (roundRobinIndex + 1) % p.maxConcurrency
-
-					if sent {
-						break
-					}
-				}
+				sent = false
+			}
+
+			roundRobinIndex = (roundRobinIndex + 1) % p.maxConcurrency
+
+			if sent {
+				break
 			}
 		}
-	}()
+	}
+}
 
-	// start worker goroutines
-	for i := range p.maxConcurrency {
-		go func() {
-			p.routine.Start(ctx, subpipes[i])
-		}()
+// sequencedMsg is an input of an ordered Parallel tagged with its position in the stream.
+type sequencedMsg struct {
+	seq uint64
+	msg pipeline.Msg
+}
+
+// dispatchSequenced numbers the inputs and queues them for whichever worker is free.
+func (p ParallelRoutine) dispatchSequenced(ctx context.Context, pipe pipeline.Pipe, jobs chan<- sequencedMsg) {
+	defer close(jobs)
+
+	var seq uint64
+	for {
+		var msg pipeline.Msg
+		select {
+		case <-ctx.Done():
+			return
+		case in, ok := <-pipe.In():
+			if !ok {
+				return
+			}
+			msg = in
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case jobs <- sequencedMsg{seq: seq, msg: msg}:
+		}
+
+		seq++
 	}
+}
 
-	wg.Wait()
+// feedWorker hands queued inputs to one worker, recording their sequence numbers so the
+// worker's results, which come out in the same order, can be matched to them.
+func (p ParallelRoutine) feedWorker(ctx context.Context, jobs <-chan sequencedMsg, assigned *seqQueue, sp *pipeline.ChannelPipe) {
+	defer close(sp.In())
+
+	for job := range jobs {
+		// recorded before the worker sees the input, so its result always finds it
+		assigned.push(job.seq)
+
+		select {
+		case <-ctx.Done():
+			return
+		case sp.In() <- job.msg:
+		}
+	}
+}
+
+// collectSequenced matches each result of worker i to the oldest input it was given and
+// adds it to the reorder buffer, failing if the counts of inputs and results differ.
+func (p ParallelRoutine) collectSequenced(ctx context.Context, i int, sp *pipeline.ChannelPipe, assigned *seqQueue, reorder *reorderBuffer) error {
+	for msg := range sp.Out() {
+		seq, ok := assigned.pop()
+		if !ok {
+			return fmt.Errorf("%w: worker %d of %T emitted more results than inputs", ErrResultCountMismatch, i, p.routine)
+		}
+
+		reorder.put(seq, msg)
+	}
+
+	// a cancelled worker stops with inputs left unanswered
+	if missing := assigned.len(); missing > 0 && ctx.Err() == nil {
+		return fmt.Errorf("%w: worker %d of %T emitted %d results fewer than inputs", ErrResultCountMismatch, i, p.routine, missing)
+	}
 
 	return nil
 }
+
+// seqQueue holds the sequence numbers of the inputs given to a worker, oldest first.
+type seqQueue struct {
+	mu   sync.Mutex
+	seqs []uint64
+}
+
+func (q *seqQueue) push(seq uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seqs = append(q.seqs, seq)
+}
+
+func (q *seqQueue) pop() (uint64, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.seqs) == 0 {
+		return 0, false
+	}
+
+	seq := q.seqs[0]
+	q.seqs = q.seqs[1:]
+
+	return seq, true
+}
+
+func (q *seqQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.seqs)
+}
+
+// reorderBuffer holds the results of an ordered Parallel until every earlier result has
+// been emitted, keeping at most max of them.
+type reorderBuffer struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[uint64]pipeline.Msg
+	// next is the sequence number of the next result to emit
+	next    uint64
+	max     int
+	policy  ReorderPolicy
+	running int
+	done    bool
+	stop    func() bool
+}
+
+func newReorderBuffer(ctx context.Context, maxBuffer int, policy ReorderPolicy, workers int) *reorderBuffer {
+	r := &reorderBuffer{
+		pending: make(map[uint64]pipeline.Msg),
+		max:     maxBuffer,
+		policy:  policy,
+		running: workers,
+	}
+	r.cond = sync.NewCond(&r.mu)
+
+	// wake up waiters once ctx is done
+	r.stop = context.AfterFunc(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.done = true
+		r.cond.Broadcast()
+	})
+
+	return r
+}
+
+// put adds the result for seq, waiting for room first under BlockWhenFull.
+func (r *reorderBuffer) put(seq uint64, msg pipeline.Msg) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the awaited result is always let in, so a full buffer can drain
+	for r.policy == BlockWhenFull && len(r.pending) >= r.max && seq != r.next && !r.done {
+		r.cond.Wait()
+	}
+
+	if seq < r.next {
+		slog.Debug("dropping result of skipped message", "position", seq)
+		return
+	}
+
+	r.pending[seq] = msg
+
+	if r.policy == SkipWhenFull {
+		for len(r.pending) > r.max {
+			if _, ok := r.pending[r.next]; ok {
+				break
+			}
+
+			slog.Error("skipping message stuck in ordered parallel", "position", r.next, "buffered", len(r.pending))
+			r.next++
+		}
+	}
+
+	r.cond.Broadcast()
+}
+
+// workerDone records that a worker has no more results.
+func (r *reorderBuffer) workerDone() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running--
+	r.cond.Broadcast()
+}
+
+// emit sends results in order until every worker is done and the buffer is empty.
+func (r *reorderBuffer) emit(ctx context.Context, pipe pipeline.Pipe) {
+	for {
+		msg, ok := r.take()
+		if !ok {
+			return
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return
+		}
+	}
+}
+
+// take waits for the next result to emit. Once all workers are done, results missing
+// from the buffer will never arrive, so the remaining ones are taken in order past the gaps.
+func (r *reorderBuffer) take() (pipeline.Msg, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		if r.done {
+			return pipeline.Msg{}, false
+		}
+
+		if msg, ok := r.pending[r.next]; ok {
+			delete(r.pending, r.next)
+			r.next++
+			r.cond.Broadcast()
+
+			return msg, true
+		}
+
+		if r.running == 0 {
+			if len(r.pending) == 0 {
+				return pipeline.Msg{}, false
+			}
+
+			r.next = slices.Min(slices.Collect(maps.Keys(r.pending)))
+			continue
+		}
+
+		r.cond.Wait()
+	}
+}
+
+// TransformConcurrentRoutine runs a typed transform on several goroutines, like
+// Parallel(Transform(f), workers) without having to compose the two.
+type TransformConcurrentRoutine[T, V any] struct {
+	transform func(T) V
+	workers   int
+	ordered   bool
+}
+
+// TransformConcurrent applies f to messages of type T on up to workers goroutines, for
+// CPU-bound transforms. Messages of other types pass through unchanged. Results arrive in
+// completion order unless Ordered is set.
+func TransformConcurrent[T, V any](f func(T) V, workers int) *TransformConcurrentRoutine[T, V] {
+	return &TransformConcurrentRoutine[T, V]{transform: f, workers: workers}
+}
+
+// Ordered emits results in input order, holding back results that finish early.
+func (t *TransformConcurrentRoutine[T, V]) Ordered() *TransformConcurrentRoutine[T, V] {
+	t.ordered = true
+	return t
+}
+
+// PreservesOrder reports whether the routine is Ordered.
+func (t *TransformConcurrentRoutine[T, V]) PreservesOrder() bool {
+	return t.ordered
+}
+
+func (t *TransformConcurrentRoutine[T, V]) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	if t.workers < 1 {
+		pipe.Close()
+		return fmt.Errorf("transform concurrency must be at least 1, got %d", t.workers)
+	}
+
+	// Transform emits exactly one message per input, as an ordered Parallel requires
+	parallel := Parallel(Transform(t.transform), t.workers)
+	if t.ordered {
+		parallel = parallel.Ordered()
+	}
+
+	return parallel.Start(ctx, pipe)
+}
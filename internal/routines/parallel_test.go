@@ -2,7 +2,10 @@ package routines_test
 
 import (
 	"context"
+	"math/rand/v2"
+	"runtime"
 	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -197,7 +200,7 @@ func TestParallelRoutine_Run(t *testing.T) {
 
 		go func() {
 			for i, data := range testData {
-				if i <= stopAfter {
+				if i < stopAfter {
 					pipe.In() <- data
 				} else {
 					cancel()
@@ -525,6 +528,344 @@ func TestParallelRoutine_Run(t *testing.T) {
 	})
 }
 
+func TestAutoConcurrency(t *testing.T) {
+	cases := []struct {
+		name        string
+		concurrency routines.Concurrency
+		expected    int
+	}{
+		{name: "sizes workers to the number of CPUs", concurrency: routines.AutoConcurrency(), expected: runtime.NumCPU()},
+		{name: "scales workers for IO-bound work", concurrency: routines.AutoConcurrency().IOBound(), expected: runtime.NumCPU() * 4},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockR := &mockRoutine{
+				processFunc: func(ctx context.Context, pipe pipeline.Pipe) error {
+					defer pipe.Close()
+
+					for data := range pipe.In() {
+						pipe.Out() <- data
+					}
+					return nil
+				},
+			}
+
+			testData := generateTestMsgs(1, 10)
+			results := runRoutine(t, routines.Parallel(mockR, tc.concurrency), testData)
+
+			assert.ElementsMatch(t, testData, results)
+			assert.Equal(t, int32(tc.expected), mockR.getCallCount())
+		})
+	}
+}
+
+func TestParallelRoutine_WithPool(t *testing.T) {
+	identity := routines.Transform(func(x int) int { return x })
+
+	t.Run("runs repeatedly on a shared pool", func(t *testing.T) {
+		pool := routines.NewWorkerPool(16)
+		defer pool.Close()
+
+		for range 10 {
+			testData := generateTestMsgs(1, 20)
+
+			results := runRoutine(t, routines.Parallel(identity, 4).WithPool(pool), testData)
+
+			assert.ElementsMatch(t, testData, results)
+		}
+	})
+
+	t.Run("reuses worker pipes across modes and concurrencies", func(t *testing.T) {
+		pool := routines.NewWorkerPool(16)
+		defer pool.Close()
+
+		for i := range 30 {
+			testData := generateTestMsgs(1, 20)
+			parallel := routines.Parallel(identity, routines.Concurrency(1+i%5)).WithPool(pool)
+
+			if i%2 == 0 {
+				assert.Equal(t, testData, runRoutine(t, parallel.Ordered(), testData))
+				continue
+			}
+
+			assert.ElementsMatch(t, testData, runRoutine(t, parallel, testData))
+		}
+	})
+}
+
+func TestParallelRoutine_SequentialIDs(t *testing.T) {
+	const size = 200
+
+	// runWithIDs feeds size messages, identified like a source would, through routine
+	runWithIDs := func(t *testing.T, routine pipeline.Routine) []pipeline.Msg {
+		ctx := pipeline.WithIDGenerator(context.Background(), pipeline.SequentialIDs())
+		pipe := pipeline.NewChanPipe()
+
+		// allocate source ids up front so they are 1..size
+		input := make([]pipeline.Msg, size)
+		for i := range input {
+			input[i] = pipeline.Msg{ID: pipeline.NewID(ctx), Data: i}
+		}
+
+		go func() {
+			for _, msg := range input {
+				pipe.In() <- msg
+			}
+			close(pipe.In())
+		}()
+
+		go func() {
+			assert.NoError(t, routine.Start(ctx, pipe))
+		}()
+
+		var results []pipeline.Msg
+		for msg := range pipe.Out() {
+			results = append(results, msg)
+		}
+
+		return results
+	}
+
+	t.Run("workers allocate unique ids", func(t *testing.T) {
+		split := routines.FlatMap(func(x int) []any { return []any{x, x} })
+
+		results := runWithIDs(t, routines.Parallel(split, 8))
+		require.Len(t, results, 2*size)
+
+		var ids []string
+		for _, msg := range results {
+			ids = append(ids, msg.ID)
+		}
+
+		// sources took 1..size, workers took the rest without gaps or duplicates
+		var expected []string
+		for i := 1; i <= 3*size; i++ {
+			expected = append(expected, strconv.Itoa(i))
+		}
+		assert.ElementsMatch(t, expected[size:], ids)
+	})
+
+	t.Run("ordered parallel keeps id order", func(t *testing.T) {
+		double := routines.Transform(func(x int) int {
+			time.Sleep(time.Duration(rand.IntN(100)) * time.Microsecond)
+			return x * 2
+		})
+
+		results := runWithIDs(t, routines.Parallel(double, 8).Ordered())
+		require.Len(t, results, size)
+
+		for i, msg := range results {
+			assert.Equal(t, strconv.Itoa(i+1), msg.ID)
+			assert.Equal(t, i*2, msg.Data)
+		}
+	})
+}
+
+func TestParallelRoutine_Ordered_ResultCount(t *testing.T) {
+	run := func(t *testing.T, routine pipeline.Routine) error {
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			defer close(pipe.In())
+			for _, msg := range generateTestMsgs(0, 100) {
+				pipe.In() <- msg
+			}
+		}()
+		go func() {
+			for range pipe.Out() {
+			}
+		}()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Parallel(routine, 4).Ordered().Start(context.Background(), pipe)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(2 * time.Second):
+			t.Fatal("ordered parallel hung")
+			return nil
+		}
+	}
+
+	t.Run("fails on a routine dropping messages", func(t *testing.T) {
+		evens := routines.Filter(func(x int) bool { return x%2 == 0 })
+
+		assert.ErrorIs(t, run(t, evens), routines.ErrResultCountMismatch)
+	})
+
+	t.Run("fails on a routine splitting messages", func(t *testing.T) {
+		twice := routines.FlatMap(func(x int) []int { return []int{x, x} })
+
+		err := run(t, twice)
+		assert.ErrorIs(t, err, routines.ErrResultCountMismatch)
+		assert.ErrorContains(t, err, "more results than inputs")
+	})
+
+	t.Run("accepts a one to one routine", func(t *testing.T) {
+		assert.NoError(t, run(t, routines.Transform(func(x int) int { return x + 1 })))
+	})
+}
+
+func TestParallelRoutine_WithMaxBuffer(t *testing.T) {
+	const stuckAt = 3
+
+	// stuckWorker forwards every message but blocks forever on the one holding stuckAt,
+	// counting the messages it took
+	stuckWorker := func(taken *atomic.Int32) *mockRoutine {
+		return &mockRoutine{
+			processFunc: func(ctx context.Context, pipe pipeline.Pipe) error {
+				defer pipe.Close()
+
+				for msg := range pipe.In() {
+					taken.Add(1)
+
+					if msg.Data == stuckAt {
+						<-ctx.Done()
+						return nil
+					}
+
+					if pipe.Send(ctx, msg) != nil {
+						return nil
+					}
+				}
+
+				return nil
+			},
+		}
+	}
+
+	// start runs routine over 0..size-1 until the test ends
+	start := func(t *testing.T, routine pipeline.Routine, size int) pipeline.Pipe {
+		ctx, cancel := context.WithCancel(context.Background())
+		pipe := pipeline.NewChanPipe()
+		done := make(chan struct{})
+
+		go func() {
+			defer close(pipe.In())
+			for i := range size {
+				select {
+				case <-ctx.Done():
+					return
+				case pipe.In() <- pipeline.Msg{Data: i}:
+				}
+			}
+		}()
+
+		go func() {
+			defer close(done)
+			assert.NoError(t, routine.Start(ctx, pipe))
+		}()
+
+		t.Cleanup(func() {
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Error("ordered parallel did not stop on cancellation")
+			}
+		})
+
+		return pipe
+	}
+
+	receive := func(t *testing.T, pipe pipeline.Pipe, n int) []any {
+		var data []any
+		for range n {
+			select {
+			case msg := <-pipe.Out():
+				data = append(data, msg.Data)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timed out after %d results", len(data))
+			}
+		}
+		return data
+	}
+
+	t.Run("skips the stuck message once the buffer is full", func(t *testing.T) {
+		var taken atomic.Int32
+		routine := routines.Parallel(stuckWorker(&taken), 2).Ordered().WithMaxBuffer(3, routines.SkipWhenFull)
+
+		pipe := start(t, routine, 50)
+
+		results := receive(t, pipe, 15)
+		assert.Equal(t, []any{0, 1, 2}, results[:3])
+
+		// the stuck worker also holds the inputs queued to it after the stuck one, in its
+		// feeder and input pipe, so up to two more positions are skipped
+		skipped := 0
+		for i, data := range results[3:] {
+			expected := stuckAt + 1 + i + skipped
+			for data.(int) > expected && skipped < 2 {
+				skipped++
+				expected++
+			}
+			assert.Equal(t, expected, data)
+		}
+	})
+
+	t.Run("blocks behind the stuck message once the buffer is full", func(t *testing.T) {
+		var taken atomic.Int32
+		routine := routines.Parallel(stuckWorker(&taken), 2).Ordered().WithMaxBuffer(3, routines.BlockWhenFull)
+
+		pipe := start(t, routine, 100)
+
+		assert.Equal(t, []any{0, 1, 2}, receive(t, pipe, 3))
+
+		select {
+		case msg := <-pipe.Out():
+			t.Fatalf("unexpected result %v past the stuck message", msg.Data)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		// the messages up to the stuck one, the buffered ones, and the ones the other
+		// worker, its output pipe and its fan-in each hold
+		assert.LessOrEqual(t, int(taken.Load()), stuckAt+1+3+3)
+	})
+}
+
+// BenchmarkParallel_SmallInputs compares the time and allocations of many short Parallel
+// runs with and without a WorkerPool. The pool saves starting goroutines and reuses the
+// worker pipes; since every run closes them, only their channels are allocated again.
+func BenchmarkParallel_SmallInputs(b *testing.B) {
+	identity := routines.Transform(func(x int) int { return x })
+	input := generateTestMsgs(1, 4)
+
+	run := func(b *testing.B, parallel routines.ParallelRoutine) {
+		b.ReportAllocs()
+
+		for range b.N {
+			pipe := pipeline.NewChanPipe()
+			go func() {
+				for _, msg := range input {
+					pipe.In() <- msg
+				}
+				close(pipe.In())
+			}()
+
+			go func() {
+				_ = parallel.Start(context.Background(), pipe)
+			}()
+
+			for range pipe.Out() {
+			}
+		}
+	}
+
+	b.Run("fresh goroutines", func(b *testing.B) {
+		run(b, routines.Parallel(identity, 8))
+	})
+
+	b.Run("worker pool", func(b *testing.B) {
+		pool := routines.NewWorkerPool(32)
+		defer pool.Close()
+
+		run(b, routines.Parallel(identity, 8).WithPool(pool))
+	})
+}
+
 func generateTestMsgs(start, size int) []pipeline.Msg {
 	testData := make([]pipeline.Msg, 0, size)
 	for i := start; i < start+size; i++ {
@@ -536,3 +877,152 @@ func generateTestMsgs(start, size int) []pipeline.Msg {
 
 	return testData
 }
+
+func TestParallelRoutine_StatefulRoutine(t *testing.T) {
+	sum := routines.Reduce(func(acc, x int) int { return acc + x }, 0)
+
+	pipe := pipeline.NewChanPipe()
+	close(pipe.In())
+
+	err := routines.Parallel(sum, 4).Start(context.Background(), pipe)
+
+	require.ErrorIs(t, err, routines.ErrStatefulRoutine)
+	assert.ErrorContains(t, err, "ReduceRoutine")
+	assert.ErrorContains(t, err, "run it without Parallel")
+
+	_, open := <-pipe.Out()
+	assert.False(t, open, "output is closed")
+
+	key := func(msg pipeline.Msg) string { return strconv.Itoa(msg.Data.(int)) }
+	less := func(a, b pipeline.Msg) bool { return a.Data.(int) < b.Data.(int) }
+
+	for _, routine := range []pipeline.Routine{
+		routines.Batch(10),
+		routines.Dedup(key),
+		routines.GroupBy(key),
+		routines.Sort(less),
+		routines.ExternalSort(less, t.TempDir(), 10),
+		routines.TopN(key, 3),
+		routines.Take(5),
+		routines.Skip(5),
+		routines.Window(time.Second),
+		routines.MovingAverage(3),
+		routines.Reservoir(3, 1),
+		routines.DebounceByKey(key, time.Second),
+		routines.RunningReduceByKey(func(x int) int { return x % 2 }, func(acc, x int) int { return acc + x }, 0),
+	} {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		assert.ErrorIs(t, routines.Parallel(routine, 4).Start(context.Background(), pipe), routines.ErrStatefulRoutine, "%T", routine)
+	}
+}
+
+func TestParallelRoutine_InvalidConcurrency(t *testing.T) {
+	identity := routines.Transform(func(x int) int { return x })
+
+	for _, parallel := range []routines.ParallelRoutine{
+		routines.Parallel(identity, 0),
+		routines.Parallel(identity, -1),
+		routines.Parallel(identity, 0).Ordered(),
+	} {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := parallel.Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "concurrency must be at least 1")
+
+		_, open := <-pipe.Out()
+		assert.False(t, open, "output is closed")
+	}
+}
+
+func TestTransformConcurrentRoutine_Start(t *testing.T) {
+	square := func(n int) int { return n * n }
+
+	withIDs := func(msgs []pipeline.Msg) []pipeline.Msg {
+		for i := range msgs {
+			msgs[i].ID = strconv.Itoa(i)
+		}
+		return msgs
+	}
+
+	t.Run("transforms every message and passes others through", func(t *testing.T) {
+		input := withIDs(generateTestMsgs(1, 20))
+		input = append(input, pipeline.Msg{ID: "text", Data: "not a number"})
+
+		results := runRoutine(t, routines.TransformConcurrent(square, 4), input)
+
+		got := make(map[string]any, len(results))
+		for _, msg := range results {
+			got[msg.ID] = msg.Data
+		}
+
+		require.Len(t, got, 21)
+		for _, msg := range input[:20] {
+			assert.Equal(t, square(msg.Data.(int)), got[msg.ID])
+		}
+		assert.Equal(t, "not a number", got["text"])
+	})
+
+	t.Run("runs up to workers transforms at once", func(t *testing.T) {
+		const workers = 4
+
+		var active, peak atomic.Int32
+		release := make(chan struct{})
+		var releaseOnce sync.Once
+
+		slow := func(n int) int {
+			current := active.Add(1)
+			defer active.Add(-1)
+
+			for {
+				old := peak.Load()
+				if current <= old || peak.CompareAndSwap(old, current) {
+					break
+				}
+			}
+
+			// hold every worker until all of them are busy
+			if current == workers {
+				releaseOnce.Do(func() { close(release) })
+			}
+			select {
+			case <-release:
+			case <-time.After(2 * time.Second):
+			}
+
+			return n
+		}
+
+		results := runRoutine(t, routines.TransformConcurrent(slow, workers), generateTestMsgs(1, 12))
+
+		assert.Len(t, results, 12)
+		assert.Equal(t, int32(workers), peak.Load())
+	})
+
+	t.Run("keeps input order when ordered", func(t *testing.T) {
+		jittered := func(n int) string {
+			time.Sleep(time.Duration(rand.IntN(3)) * time.Millisecond)
+			return strconv.Itoa(n)
+		}
+
+		input := withIDs(generateTestMsgs(1, 50))
+		results := runRoutine(t, routines.TransformConcurrent(jittered, 5).Ordered(), input)
+
+		require.Len(t, results, 50)
+		for i, msg := range results {
+			assert.Equal(t, input[i].ID, msg.ID)
+			assert.Equal(t, strconv.Itoa(input[i].Data.(int)), msg.Data)
+		}
+	})
+
+	t.Run("rejects fewer than one worker", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+
+		err := routines.TransformConcurrent(square, 0).Start(context.Background(), pipe)
+
+		assert.Error(t, err)
+	})
+}
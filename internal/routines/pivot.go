@@ -0,0 +1,142 @@
+package routines
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+const (
+	// UnpivotKeyField holds the column name in records emitted by Unpivot
+	UnpivotKeyField = "key"
+	// UnpivotValueField holds the column value in records emitted by Unpivot
+	UnpivotValueField = "value"
+)
+
+// UnpivotRoutine turns wide map[string]any records into long form: every column that is
+// not an id column becomes its own record holding the id columns plus "key" and "value".
+// Columns are emitted in name order; other messages pass through unchanged.
+type UnpivotRoutine struct {
+	idColumns []string
+}
+
+func Unpivot(idColumns []string) *UnpivotRoutine {
+	return &UnpivotRoutine{idColumns: idColumns}
+}
+
+func (u *UnpivotRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		record, ok := msg.Data.(map[string]any)
+		if !ok {
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		columns := make([]string, 0, len(record))
+		for column := range record {
+			if !slices.Contains(u.idColumns, column) {
+				columns = append(columns, column)
+			}
+		}
+		sort.Strings(columns)
+
+		for _, column := range columns {
+			long := make(map[string]any, len(u.idColumns)+2)
+			for _, id := range u.idColumns {
+				if value, found := record[id]; found {
+					long[id] = value
+				}
+			}
+			long[UnpivotKeyField] = column
+			long[UnpivotValueField] = record[column]
+
+			if err := pipe.Send(ctx, pipeline.Msg{ID: pipeline.NewID(ctx), Data: long, IngestedAt: msg.IngestedAt}); err != nil {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// PivotRoutine is the inverse of Unpivot: it groups long-form map[string]any records by
+// id and emits one wide record per id, with a column per distinct key set to its value.
+// Fields other than the key and value fields are copied from the first record of each id.
+//
+// Pivoting must see every record of an id before emitting it, so all wide records are
+// held in memory and emitted, in first-seen id order, only when the input closes.
+// Memory grows with the number of distinct ids times the number of distinct keys.
+type PivotRoutine struct {
+	idFn       func(map[string]any) string
+	keyField   string
+	valueField string
+}
+
+// Pivot groups records by idFn, using keyField as the column name and valueField as the
+// column value.
+//
+// Example:
+//
+//	byID := func(r map[string]any) string { return fmt.Sprint(r["id"]) }
+//	script.Chain(routines.Pivot(byID, routines.UnpivotKeyField, routines.UnpivotValueField))
+func Pivot(idFn func(map[string]any) string, keyField, valueField string) *PivotRoutine {
+	return &PivotRoutine{idFn: idFn, keyField: keyField, valueField: valueField}
+}
+
+// Stateful marks PivotRoutine as a StatefulRoutine, since a wide record gathers every
+// record of its id.
+func (p *PivotRoutine) Stateful() {}
+
+func (p *PivotRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	var order []string
+	wide := make(map[string]map[string]any)
+
+	for msg := range pipe.In() {
+		record, ok := msg.Data.(map[string]any)
+		if !ok {
+			slog.Error("pivot received message with invalid type", "type", reflect.TypeOf(msg.Data))
+			continue
+		}
+
+		key, ok := record[p.keyField].(string)
+		if !ok {
+			slog.Error("pivot received record without string key field", "msg_id", msg.ID, "field", p.keyField)
+			continue
+		}
+
+		id := p.idFn(record)
+
+		row, found := wide[id]
+		if !found {
+			row = make(map[string]any)
+			for field, value := range record {
+				if field != p.keyField && field != p.valueField {
+					row[field] = value
+				}
+			}
+
+			wide[id] = row
+			order = append(order, id)
+		}
+
+		row[key] = record[p.valueField]
+	}
+
+	for _, id := range order {
+		if err := pipe.Send(ctx, pipeline.Msg{ID: pipeline.NewID(ctx), Data: wide[id]}); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
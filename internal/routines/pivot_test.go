@@ -0,0 +1,65 @@
+package routines_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpivotRoutine_Start(t *testing.T) {
+	t.Run("emits one long record per non-id column", func(t *testing.T) {
+		wide := map[string]any{"id": 7, "region": "north", "jan": 10, "feb": 20, "mar": 30}
+
+		results := runRoutine(t, routines.Unpivot([]string{"id", "region"}), []pipeline.Msg{{Data: wide}})
+
+		require.Len(t, results, 3)
+		assert.Equal(t, map[string]any{"id": 7, "region": "north", "key": "feb", "value": 20}, results[0].Data)
+		assert.Equal(t, map[string]any{"id": 7, "region": "north", "key": "jan", "value": 10}, results[1].Data)
+		assert.Equal(t, map[string]any{"id": 7, "region": "north", "key": "mar", "value": 30}, results[2].Data)
+	})
+
+	t.Run("passes through non-record messages", func(t *testing.T) {
+		input := []pipeline.Msg{{ID: "1", Data: "text"}}
+
+		results := runRoutine(t, routines.Unpivot([]string{"id"}), input)
+
+		assert.Equal(t, input, results)
+	})
+}
+
+func TestPivotRoutine_Start(t *testing.T) {
+	byID := func(r map[string]any) string { return fmt.Sprint(r["id"]) }
+
+	t.Run("groups long records back into wide records", func(t *testing.T) {
+		long := []pipeline.Msg{
+			{Data: map[string]any{"id": 1, "key": "jan", "value": 10}},
+			{Data: map[string]any{"id": 2, "key": "jan", "value": 5}},
+			{Data: map[string]any{"id": 1, "key": "feb", "value": 20}},
+			{Data: map[string]any{"id": 2, "key": "mar", "value": 7}},
+		}
+
+		results := runRoutine(t, routines.Pivot(byID, "key", "value"), long)
+
+		require.Len(t, results, 2)
+		assert.Equal(t, map[string]any{"id": 1, "jan": 10, "feb": 20}, results[0].Data)
+		assert.Equal(t, map[string]any{"id": 2, "jan": 5, "mar": 7}, results[1].Data)
+	})
+
+	t.Run("round trips unpivoted records", func(t *testing.T) {
+		wide := []pipeline.Msg{
+			{Data: map[string]any{"id": "a", "x": 1, "y": 2}},
+			{Data: map[string]any{"id": "b", "x": 3, "y": 4}},
+		}
+
+		long := runRoutine(t, routines.Unpivot([]string{"id"}), wide)
+		results := runRoutine(t, routines.Pivot(byID, routines.UnpivotKeyField, routines.UnpivotValueField), long)
+
+		require.Len(t, results, 2)
+		assert.Equal(t, wide[0].Data, results[0].Data)
+		assert.Equal(t, wide[1].Data, results[1].Data)
+	})
+}
@@ -0,0 +1,108 @@
+package routines
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// WorkerPool keeps a set of long-lived goroutines that run submitted tasks, so routines
+// started repeatedly (e.g. a Parallel per file) reuse goroutines instead of spawning new ones.
+// It also keeps the worker pipes of finished Parallel runs and resets them for the next run,
+// which then only allocates their channels. A pool can be shared by many routines; when
+// every pooled goroutine is busy, Go falls back to a fresh goroutine so tasks never wait on
+// each other.
+type WorkerPool struct {
+	tasks chan func()
+	pipes sync.Pool
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewWorkerPool(size int) *WorkerPool {
+	p := &WorkerPool{tasks: make(chan func())}
+
+	for range size {
+		go func() {
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+
+	return p
+}
+
+// Go runs task on an idle pooled goroutine, or on a new goroutine if none is idle.
+func (p *WorkerPool) Go(task func()) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		go task()
+		return
+	}
+
+	select {
+	case p.tasks <- task:
+	default:
+		go task()
+	}
+}
+
+// Close stops the pooled goroutines once they finish their current task. Tasks
+// submitted afterwards run on fresh goroutines.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	p.closed = true
+	close(p.tasks)
+}
+
+// workerPipes are the pipes of one Parallel run's workers. Every goroutine using them
+// calls release when it returns, and the last one hands them back to the pool.
+type workerPipes struct {
+	pipes []*pipeline.ChannelPipe
+	users atomic.Int32
+	pool  *WorkerPool
+}
+
+// workerPipes returns n open pipes for as many goroutines, reusing released ones. A nil
+// pool allocates new pipes.
+func (p *WorkerPool) workerPipes(n int, users int32) *workerPipes {
+	var set *workerPipes
+	if p != nil {
+		set, _ = p.pipes.Get().(*workerPipes)
+	}
+	if set == nil {
+		set = &workerPipes{pool: p}
+	}
+
+	set.users.Store(users)
+	set.pipes = slices.Grow(set.pipes[:0], n)[:n]
+	for i, sp := range set.pipes {
+		if sp == nil {
+			set.pipes[i] = pipeline.NewChanPipe()
+			continue
+		}
+
+		sp.Reset()
+	}
+
+	return set
+}
+
+// release records that one goroutine stopped using the pipes.
+func (w *workerPipes) release() {
+	if w.users.Add(-1) == 0 && w.pool != nil {
+		w.pool.pipes.Put(w)
+	}
+}
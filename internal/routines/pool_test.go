@@ -0,0 +1,60 @@
+package routines_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool_Go(t *testing.T) {
+	t.Run("runs every task even when all workers are busy", func(t *testing.T) {
+		pool := routines.NewWorkerPool(2)
+		defer pool.Close()
+
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+
+		// more blocking tasks than pooled goroutines must not deadlock
+		for range 5 {
+			wg.Add(1)
+			pool.Go(func() {
+				defer wg.Done()
+				<-release
+			})
+		}
+
+		close(release)
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("tasks did not complete")
+		}
+	})
+
+	t.Run("runs tasks submitted after close", func(t *testing.T) {
+		pool := routines.NewWorkerPool(1)
+		pool.Close()
+		pool.Close()
+
+		ran := make(chan struct{})
+		assert.NotPanics(t, func() {
+			pool.Go(func() { close(ran) })
+		})
+
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatal("task did not run")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package routines
+
+import (
+	"context"
+	"maps"
+	"strings"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// RedactedValue is what Redact replaces sensitive values with when no mask is given.
+const RedactedValue = "***"
+
+// RedactRoutine masks sensitive fields of map[string]any messages, e.g. before logging or
+// exporting data. Fields are named by dotted paths, so "user.email" masks the email field
+// of the nested user object. Missing fields are left absent, and messages of other types
+// pass through unchanged.
+//
+// Redaction copies every map on a field's path, so maps shared with earlier stages are
+// not modified.
+type RedactRoutine struct {
+	paths [][]string
+	mask  func(any) any
+}
+
+// Redact masks the given fields with mask, or with RedactedValue when mask is nil.
+func Redact(fields []string, mask func(any) any) *RedactRoutine {
+	if mask == nil {
+		mask = func(any) any { return RedactedValue }
+	}
+
+	paths := make([][]string, len(fields))
+	for i, field := range fields {
+		paths[i] = strings.Split(field, ".")
+	}
+
+	return &RedactRoutine{paths: paths, mask: mask}
+}
+
+func (r *RedactRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		if obj, ok := msg.Data.(map[string]any); ok {
+			for _, path := range r.paths {
+				obj = r.redact(obj, path)
+			}
+
+			msg = msg.WithData(obj)
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// redact returns a copy of obj with the field at path masked, or obj itself when the
+// path does not exist.
+func (r *RedactRoutine) redact(obj map[string]any, path []string) map[string]any {
+	value, ok := obj[path[0]]
+	if !ok {
+		return obj
+	}
+
+	if len(path) > 1 {
+		nested, ok := value.(map[string]any)
+		if !ok {
+			return obj
+		}
+
+		value = r.redact(nested, path[1:])
+	} else {
+		value = r.mask(value)
+	}
+
+	redacted := maps.Clone(obj)
+	redacted[path[0]] = value
+
+	return redacted
+}
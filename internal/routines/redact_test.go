@@ -0,0 +1,61 @@
+package routines_test
+
+import (
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactRoutine_Start(t *testing.T) {
+	newRecord := func() map[string]any {
+		return map[string]any{
+			"id":       1.0,
+			"password": "hunter2",
+			"user": map[string]any{
+				"name":  "Jane",
+				"email": "jane@example.com",
+			},
+		}
+	}
+
+	t.Run("masks top-level and nested fields", func(t *testing.T) {
+		input := newRecord()
+
+		results := runRoutine(t, routines.Redact([]string{"password", "user.email"}, nil), []pipeline.Msg{{ID: "1", Data: input}})
+
+		expected := map[string]any{
+			"id":       1.0,
+			"password": "***",
+			"user": map[string]any{
+				"name":  "Jane",
+				"email": "***",
+			},
+		}
+		assert.Equal(t, []pipeline.Msg{{ID: "1", Data: expected}}, results)
+		assert.Equal(t, newRecord(), input, "input message was modified")
+	})
+
+	t.Run("custom mask", func(t *testing.T) {
+		lastFour := func(v any) any {
+			s := v.(string)
+			return "****" + s[len(s)-4:]
+		}
+
+		results := runRoutine(t, routines.Redact([]string{"card"}, lastFour), []pipeline.Msg{{Data: map[string]any{"card": "4111111111111111"}}})
+
+		assert.Equal(t, map[string]any{"card": "****1111"}, results[0].Data)
+	})
+
+	t.Run("missing paths and other types are left intact", func(t *testing.T) {
+		input := []pipeline.Msg{
+			{Data: newRecord()},
+			{Data: "plain line"},
+		}
+
+		results := runRoutine(t, routines.Redact([]string{"token", "user.phone", "id.value"}, nil), input)
+
+		assert.Equal(t, input, results)
+	})
+}
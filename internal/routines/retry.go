@@ -0,0 +1,124 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// RetryRoutine maps the data of each message with a function that may fail, e.g. a call to
+// a remote service, retrying it per a BackoffPolicy. Messages whose data is not a T pass
+// through unchanged. A message still failing once its retries are exhausted stops the
+// routine with the error.
+type RetryRoutine[T, V any] struct {
+	f          func(ctx context.Context, in T) (V, error)
+	maxRetries int
+	backoff    BackoffPolicy
+}
+
+// Retry calls f on every message, retrying a failed call up to maxRetries times and
+// waiting policy.Next between attempts, or per DefaultBackoff with a nil policy.
+//
+// Example:
+//
+//	script.Chain(routines.Retry(geocode, 3, routines.NewExponentialBackoff(time.Second, time.Minute)))
+func Retry[T, V any](f func(ctx context.Context, in T) (V, error), maxRetries int, policy BackoffPolicy) *RetryRoutine[T, V] {
+	return &RetryRoutine[T, V]{f: f, maxRetries: maxRetries, backoff: policy}
+}
+
+func (r *RetryRoutine[T, V]) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		in, ok := msg.Data.(T)
+		if !ok {
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		var out V
+		err := retryWithBackoff(ctx, r.maxRetries, r.backoff, func() (bool, error) {
+			var err error
+			out, err = r.f(ctx, in)
+			return ctx.Err() == nil, err
+		}, "msg_id", msg.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to process message %s after %d retries: %w", msg.ID, r.maxRetries, err)
+		}
+
+		if err := pipe.Send(ctx, msg.WithData(out)); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// ReconnectRoutine restarts a source that fails, e.g. a command streaming from a remote
+// host, waiting per a BackoffPolicy between attempts. Messages emitted before a failure
+// are kept, so a restarted source may repeat some of them.
+type ReconnectRoutine struct {
+	source     pipeline.Routine
+	maxRetries int
+	backoff    BackoffPolicy
+}
+
+// Reconnect runs source and starts it again when it returns an error, up to maxRetries
+// times, waiting policy.Next between attempts, or per DefaultBackoff with a nil policy. The
+// source finishing without an error ends the routine.
+//
+// Example:
+//
+//	script.In(routines.Reconnect(routines.Exec("ssh", "host", "tail", "-f", "app.log"), 10, nil))
+func Reconnect(source pipeline.Routine, maxRetries int, policy BackoffPolicy) *ReconnectRoutine {
+	return &ReconnectRoutine{source: source, maxRetries: maxRetries, backoff: policy}
+}
+
+func (r *ReconnectRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	err := retryWithBackoff(ctx, r.maxRetries, r.backoff, func() (bool, error) {
+		err := r.run(ctx, pipe)
+		return ctx.Err() == nil, err
+	}, "source", fmt.Sprintf("%T", r.source))
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("source failed after %d reconnects: %w", r.maxRetries, err)
+	}
+
+	return nil
+}
+
+// run starts the source once, forwarding its messages to pipe, and returns its error. A
+// closed pipe stops the source and reports no error, as nothing is left to reconnect for.
+func (r *ReconnectRoutine) run(ctx context.Context, pipe pipeline.Pipe) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sourcePipe := pipeline.NewChanPipe()
+
+	errCh := make(chan error, 1)
+	pipeline.Go(ctx, func() {
+		// a recovered panic leaves no error to read
+		defer close(errCh)
+
+		errCh <- r.source.Start(ctx, sourcePipe)
+	})
+
+	for msg := range sourcePipe.Out() {
+		if err := pipe.Send(ctx, msg); err != nil {
+			cancel()
+			for range sourcePipe.Out() {
+			}
+			<-errCh
+			return nil
+		}
+	}
+
+	return <-errCh
+}
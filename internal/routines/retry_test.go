@@ -0,0 +1,119 @@
+package routines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySource emits its messages and then fails, until it has failed failures times.
+type flakySource struct {
+	msgs     []pipeline.Msg
+	failures int
+	starts   int
+}
+
+func (s *flakySource) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	s.starts++
+
+	for _, msg := range s.msgs {
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	if s.starts <= s.failures {
+		return errors.New("connection reset")
+	}
+
+	return nil
+}
+
+func TestRetryRoutine_Start(t *testing.T) {
+	backoff := routines.NewConstantBackoff(time.Millisecond)
+
+	t.Run("retries a failing call until it succeeds", func(t *testing.T) {
+		var calls int
+		double := func(ctx context.Context, n int) (int, error) {
+			calls++
+			if calls < 3 {
+				return 0, errors.New("unavailable")
+			}
+			return n * 2, nil
+		}
+
+		results := runRoutine(t, routines.Retry(double, 3, backoff), []pipeline.Msg{{ID: "1", Data: 21}, {ID: "2", Data: "skip"}})
+
+		require.Len(t, results, 2)
+		assert.Equal(t, 42, results[0].Data)
+		assert.Equal(t, "1", results[0].ID)
+		assert.Equal(t, "skip", results[1].Data, "messages of another type pass through")
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("fails once the retries are exhausted", func(t *testing.T) {
+		var calls int
+		fail := func(ctx context.Context, n int) (int, error) {
+			calls++
+			return 0, errors.New("unavailable")
+		}
+
+		pipe := pipeline.NewChanPipe()
+		pipe.In() <- pipeline.Msg{ID: "7", Data: 1}
+		close(pipe.In())
+
+		err := routines.Retry(fail, 2, backoff).Start(t.Context(), pipe)
+
+		assert.ErrorContains(t, err, "failed to process message 7 after 2 retries: unavailable")
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("uses the default policy when none is given", func(t *testing.T) {
+		var calls int
+		flaky := func(ctx context.Context, s string) (string, error) {
+			calls++
+			if calls == 1 {
+				return "", errors.New("unavailable")
+			}
+			return s, nil
+		}
+
+		results := runRoutine(t, routines.Retry(flaky, 1, nil), []pipeline.Msg{{Data: "ok"}})
+
+		require.Len(t, results, 1)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestReconnectRoutine_Start(t *testing.T) {
+	backoff := routines.NewConstantBackoff(time.Millisecond)
+
+	t.Run("restarts a failing source", func(t *testing.T) {
+		source := &flakySource{msgs: []pipeline.Msg{{Data: "line"}}, failures: 2}
+
+		results := runRoutine(t, routines.Reconnect(source, 3, backoff), nil)
+
+		assert.Len(t, results, 3, "each run emits its messages")
+		assert.Equal(t, 3, source.starts)
+	})
+
+	t.Run("fails once the reconnects are exhausted", func(t *testing.T) {
+		source := &flakySource{failures: 10}
+
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.Reconnect(source, 2, backoff).Start(t.Context(), pipe)
+
+		assert.ErrorContains(t, err, "source failed after 2 reconnects: connection reset")
+		assert.Equal(t, 3, source.starts)
+	})
+}
@@ -0,0 +1,92 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// RowsToMapsRoutine names the fields of []string and []any rows, such as CSV records,
+// turning each row into a map[string]any keyed by the header at the same position. Fields
+// past the last header are dropped and headers past the last field are left out of the
+// map. Other messages pass through unchanged.
+type RowsToMapsRoutine struct {
+	headers []string
+}
+
+func RowsToMaps(headers []string) *RowsToMapsRoutine {
+	return &RowsToMapsRoutine{headers: headers}
+}
+
+func (r *RowsToMapsRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		switch row := msg.Data.(type) {
+		case []string:
+			msg = msg.WithData(rowToMap(r.headers, row))
+		case []any:
+			msg = msg.WithData(rowToMap(r.headers, row))
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func rowToMap[T any](headers []string, row []T) map[string]any {
+	record := make(map[string]any, len(headers))
+	for i, header := range headers {
+		if i >= len(row) {
+			break
+		}
+
+		record[header] = row[i]
+	}
+
+	return record
+}
+
+// MapsToRowsRoutine turns map[string]any records into []string rows holding the values
+// of headers in order, ready for the CSV codec. Values are formatted with %v and missing
+// or nil values become empty fields. Other messages pass through unchanged.
+type MapsToRowsRoutine struct {
+	headers []string
+}
+
+func MapsToRows(headers []string) *MapsToRowsRoutine {
+	return &MapsToRowsRoutine{headers: headers}
+}
+
+func (m *MapsToRowsRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		if record, ok := msg.Data.(map[string]any); ok {
+			msg = msg.WithData(mapToRow(m.headers, record))
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// mapToRow returns the values of headers in record formatted with %v, leaving missing and
+// nil values empty.
+func mapToRow(headers []string, record map[string]any) []string {
+	row := make([]string, len(headers))
+	for i, header := range headers {
+		if value, found := record[header]; found && value != nil {
+			row[i] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	return row
+}
@@ -0,0 +1,57 @@
+package routines_test
+
+import (
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowsToMapsRoutine_Start(t *testing.T) {
+	headers := []string{"name", "age", "city"}
+
+	results := runRoutine(t, routines.RowsToMaps(headers), []pipeline.Msg{
+		{ID: "1", Data: []string{"ana", "30", "lisbon"}},
+		{ID: "2", Data: []any{"bob", 25}},
+		{ID: "3", Data: []string{"eve", "41", "porto", "extra"}},
+		{ID: "4", Data: "not a row"},
+	})
+
+	assert.Equal(t, []pipeline.Msg{
+		{ID: "1", Data: map[string]any{"name": "ana", "age": "30", "city": "lisbon"}},
+		{ID: "2", Data: map[string]any{"name": "bob", "age": 25}},
+		{ID: "3", Data: map[string]any{"name": "eve", "age": "41", "city": "porto"}},
+		{ID: "4", Data: "not a row"},
+	}, results)
+}
+
+func TestMapsToRowsRoutine_Start(t *testing.T) {
+	headers := []string{"name", "age", "city"}
+
+	t.Run("orders values by headers", func(t *testing.T) {
+		results := runRoutine(t, routines.MapsToRows(headers), []pipeline.Msg{
+			{ID: "1", Data: map[string]any{"city": "lisbon", "age": 30.0, "name": "ana", "ignored": true}},
+			{ID: "2", Data: map[string]any{"name": "bob", "city": nil}},
+			{ID: "3", Data: 7},
+		})
+
+		assert.Equal(t, []pipeline.Msg{
+			{ID: "1", Data: []string{"ana", "30", "lisbon"}},
+			{ID: "2", Data: []string{"bob", "", ""}},
+			{ID: "3", Data: 7},
+		}, results)
+	})
+
+	t.Run("round-trips rows through maps", func(t *testing.T) {
+		rows := []pipeline.Msg{
+			{ID: "1", Data: []string{"ana", "30", "lisbon"}},
+			{ID: "2", Data: []string{"bob", "25", ""}},
+		}
+
+		maps := runRoutine(t, routines.RowsToMaps(headers), rows)
+		results := runRoutine(t, routines.MapsToRows(headers), maps)
+
+		assert.Equal(t, rows, results)
+	})
+}
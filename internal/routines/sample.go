@@ -0,0 +1,70 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// ReservoirRoutine keeps a uniform random sample of k messages over the whole stream,
+// using reservoir sampling, and emits it once the input ends. Every message has the same
+// k/n chance of being kept regardless of the stream length n, which need not be known in
+// advance, and memory stays bounded by k.
+//
+// The sample is emitted in input order. Streams of at most k messages are emitted whole.
+type ReservoirRoutine struct {
+	k    int
+	seed int64
+}
+
+// Reservoir samples k messages, drawing from a generator seeded with seed so the same
+// input yields the same sample.
+func Reservoir(k int, seed int64) *ReservoirRoutine {
+	return &ReservoirRoutine{k: k, seed: seed}
+}
+
+// sampled is a message kept in the reservoir with its position in the stream.
+type sampled struct {
+	index int
+	msg   pipeline.Msg
+}
+
+// Stateful marks ReservoirRoutine as a StatefulRoutine, since the sample is drawn from
+// the whole stream.
+func (r *ReservoirRoutine) Stateful() {}
+
+func (r *ReservoirRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if r.k < 1 {
+		return fmt.Errorf("reservoir size must be at least 1, got %d", r.k)
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(r.seed), uint64(r.seed)))
+	reservoir := make([]sampled, 0, r.k)
+
+	seen := 0
+	for msg := range pipe.In() {
+		if len(reservoir) < r.k {
+			reservoir = append(reservoir, sampled{index: seen, msg: msg})
+		} else if j := rng.IntN(seen + 1); j < r.k {
+			// keep the message with probability k/(seen+1), evicting a random one
+			reservoir[j] = sampled{index: seen, msg: msg}
+		}
+
+		seen++
+	}
+
+	sort.Slice(reservoir, func(i, j int) bool { return reservoir[i].index < reservoir[j].index })
+
+	for _, s := range reservoir {
+		if err := pipe.Send(ctx, s.msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
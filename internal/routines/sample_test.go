@@ -0,0 +1,64 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservoirRoutine_Start(t *testing.T) {
+	t.Run("keeps exactly k messages of a longer stream in input order", func(t *testing.T) {
+		results := runRoutine(t, routines.Reservoir(10, 42), generateTestMsgs(0, 1000))
+
+		require.Len(t, results, 10)
+		for i := 1; i < len(results); i++ {
+			assert.Less(t, results[i-1].Data.(int), results[i].Data.(int))
+		}
+	})
+
+	t.Run("emits every message of a shorter stream", func(t *testing.T) {
+		input := generateTestMsgs(0, 5)
+
+		results := runRoutine(t, routines.Reservoir(10, 42), input)
+
+		assert.Equal(t, input, results)
+	})
+
+	t.Run("same seed yields the same sample", func(t *testing.T) {
+		first := runRoutine(t, routines.Reservoir(5, 7), generateTestMsgs(0, 200))
+		second := runRoutine(t, routines.Reservoir(5, 7), generateTestMsgs(0, 200))
+
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("samples uniformly", func(t *testing.T) {
+		const (
+			n      = 20
+			k      = 5
+			trials = 4000
+		)
+
+		counts := make([]int, n)
+		for seed := range int64(trials) {
+			for _, msg := range runRoutine(t, routines.Reservoir(k, seed), generateTestMsgs(0, n)) {
+				counts[msg.Data.(int)]++
+			}
+		}
+
+		// each message is expected in k/n of the trials
+		expected := trials * k / n
+		for i, count := range counts {
+			assert.InDelta(t, expected, count, float64(expected)/5, "message %d", i)
+		}
+	})
+
+	t.Run("rejects a size below one", func(t *testing.T) {
+		err := routines.Reservoir(0, 1).Start(context.Background(), pipeline.NewChanPipe())
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,77 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// ShedOverRateRoutine protects the stages after it from overload by dropping messages
+// above a rate, where a rate limiter would make the stages before it wait. Time is cut
+// into consecutive windows of per, starting with the first message; the first n messages
+// of each window are forwarded and the rest are shed.
+type ShedOverRateRoutine struct {
+	n      int
+	per    time.Duration
+	onShed func(pipeline.Msg)
+}
+
+// ShedOverRate forwards up to n messages per window of per and drops the excess.
+//
+// Example:
+//
+//	script.Chain(routines.ShedOverRate(100, time.Second).OnShed(countShed))
+func ShedOverRate(n int, per time.Duration) *ShedOverRateRoutine {
+	return &ShedOverRateRoutine{n: n, per: per}
+}
+
+// OnShed routes shed messages to fn instead of discarding them, e.g. to count them or
+// send them to a dead letter file. fn runs on the routine goroutine, so a slow fn slows
+// the stream down.
+func (s *ShedOverRateRoutine) OnShed(fn func(pipeline.Msg)) *ShedOverRateRoutine {
+	s.onShed = fn
+	return s
+}
+
+// Stateful marks ShedOverRateRoutine as a StatefulRoutine, since the rate is counted over
+// the whole stream.
+func (s *ShedOverRateRoutine) Stateful() {}
+
+func (s *ShedOverRateRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if s.n < 1 || s.per <= 0 {
+		return fmt.Errorf("shed over rate needs a positive count and window, got %d per %s", s.n, s.per)
+	}
+
+	var (
+		windowStart time.Time
+		forwarded   int
+	)
+
+	for msg := range pipe.In() {
+		now := time.Now()
+		if now.Sub(windowStart) >= s.per {
+			windowStart, forwarded = now, 0
+		}
+
+		if forwarded >= s.n {
+			slog.Debug("shedding message over rate", "msg_id", msg.ID, "rate", s.n, "per", s.per)
+
+			if s.onShed != nil {
+				s.onShed(msg)
+			}
+			continue
+		}
+
+		forwarded++
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
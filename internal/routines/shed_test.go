@@ -0,0 +1,71 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShedOverRateRoutine_Start(t *testing.T) {
+	t.Run("forwards up to the rate and drops the rest of a burst", func(t *testing.T) {
+		results := runRoutine(t, routines.ShedOverRate(3, time.Hour), generateTestMsgs(1, 10))
+
+		assert.Equal(t, []any{1, 2, 3}, msgData(results))
+	})
+
+	t.Run("diverts shed messages to OnShed", func(t *testing.T) {
+		var shed []any
+		routine := routines.ShedOverRate(2, time.Hour).OnShed(func(msg pipeline.Msg) {
+			shed = append(shed, msg.Data)
+		})
+
+		results := runRoutine(t, routine, generateTestMsgs(1, 5))
+
+		assert.Equal(t, []any{1, 2}, msgData(results))
+		assert.Equal(t, []any{3, 4, 5}, shed)
+	})
+
+	t.Run("forwards again once the window passes", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.ShedOverRate(2, 100*time.Millisecond).Start(context.Background(), pipe)
+		}()
+
+		var results []any
+		collected := make(chan struct{})
+		go func() {
+			defer close(collected)
+			for msg := range pipe.Out() {
+				results = append(results, msg.Data)
+			}
+		}()
+
+		for i := 1; i <= 4; i++ {
+			pipe.In() <- pipeline.Msg{Data: i}
+		}
+		time.Sleep(150 * time.Millisecond)
+		for i := 5; i <= 8; i++ {
+			pipe.In() <- pipeline.Msg{Data: i}
+		}
+		close(pipe.In())
+
+		require.NoError(t, <-done)
+		<-collected
+		assert.Equal(t, []any{1, 2, 5, 6}, results)
+	})
+
+	t.Run("rejects a non-positive rate", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.ShedOverRate(0, time.Second).Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "positive count")
+	})
+}
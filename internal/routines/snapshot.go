@@ -0,0 +1,81 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// PeriodicSnapshotRoutine emits interim results of a long-running aggregation. It consumes
+// its input, usually the output of the aggregator it observes, and emits snapshot() every
+// interval while the input is open, then a final snapshot once it closes. Snapshots without
+// an ID are given one.
+//
+// snapshot is called from the routine's goroutine while the aggregator may be updating, so
+// it must be safe for concurrent use, as ReduceRoutine.Snapshot is.
+//
+// Example:
+//
+//	lines := routines.Reduce(func(count int, _ string) int { return count + 1 }, 0)
+//	script.Chain(lines).Chain(routines.PeriodicSnapshot(10*time.Second, lines.Snapshot))
+type PeriodicSnapshotRoutine struct {
+	interval  time.Duration
+	snapshot  func() pipeline.Msg
+	newTicker tickerFunc
+}
+
+func PeriodicSnapshot(interval time.Duration, snapshot func() pipeline.Msg) *PeriodicSnapshotRoutine {
+	return &PeriodicSnapshotRoutine{interval: interval, snapshot: snapshot, newTicker: newTimeTicker}
+}
+
+// tickerFunc starts a ticker, returning its channel and a stop func. Routines that tick
+// hold one so tests can drive the ticks.
+type tickerFunc func(d time.Duration) (<-chan time.Time, func())
+
+// newTimeTicker is the tickerFunc backed by a time.Ticker.
+func newTimeTicker(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+	return ticker.C, ticker.Stop
+}
+
+// Stateful marks PeriodicSnapshotRoutine as a StatefulRoutine, since each worker would
+// emit its own snapshots.
+func (p *PeriodicSnapshotRoutine) Stateful() {}
+
+func (p *PeriodicSnapshotRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if p.interval <= 0 {
+		return fmt.Errorf("periodic snapshot needs a positive interval, got %s", p.interval)
+	}
+
+	ticks, stop := p.newTicker(p.interval)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+			if err := p.emit(ctx, pipe); err != nil {
+				return nil
+			}
+		case _, ok := <-pipe.In():
+			if !ok {
+				p.emit(ctx, pipe)
+				return nil
+			}
+		}
+	}
+}
+
+func (p *PeriodicSnapshotRoutine) emit(ctx context.Context, pipe pipeline.Pipe) error {
+	msg := p.snapshot()
+	if msg.ID == "" {
+		msg.ID = pipeline.NewID(ctx)
+	}
+
+	return pipe.Send(ctx, msg)
+}
@@ -0,0 +1,94 @@
+package routines_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTicker returns a ticker func for a routine's WithTicker, ticking on the returned
+// channel by hand.
+func fakeTicker() (chan<- time.Time, func(time.Duration) (<-chan time.Time, func())) {
+	ticks := make(chan time.Time)
+
+	return ticks, func(time.Duration) (<-chan time.Time, func()) {
+		return ticks, func() {}
+	}
+}
+
+func TestPeriodicSnapshotRoutine_Start(t *testing.T) {
+	t.Run("emits a snapshot at each interval and a final one", func(t *testing.T) {
+		ticks, ticker := fakeTicker()
+
+		var calls atomic.Int32
+		snapshot := func() pipeline.Msg {
+			return pipeline.Msg{Data: int(calls.Add(1))}
+		}
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.PeriodicSnapshot(10*time.Second, snapshot).WithTicker(ticker).Start(context.Background(), pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: "consumed"}
+
+		ticks <- time.Now()
+		first := <-pipe.Out()
+		assert.Equal(t, 1, first.Data)
+		assert.NotEmpty(t, first.ID)
+
+		ticks <- time.Now()
+		assert.Equal(t, 2, (<-pipe.Out()).Data)
+
+		close(pipe.In())
+		assert.Equal(t, 3, (<-pipe.Out()).Data)
+
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("reports the running value of a Reduce", func(t *testing.T) {
+		ticks, ticker := fakeTicker()
+
+		sum := routines.Reduce(func(acc, x int) int { return acc + x }, 0)
+
+		reducePipe := pipeline.NewChanPipe()
+		snapshotPipe := pipeline.NewChanPipe()
+		reducePipe.Chain(snapshotPipe)
+
+		ctx := context.Background()
+		go sum.Start(ctx, reducePipe)
+		go routines.PeriodicSnapshot(time.Second, sum.Snapshot).WithTicker(ticker).Start(ctx, snapshotPipe)
+
+		for _, v := range []int{1, 2, 3} {
+			reducePipe.In() <- pipeline.Msg{Data: v}
+		}
+		require.Eventually(t, func() bool { return sum.Snapshot().Data == 6 }, time.Second, time.Millisecond)
+
+		ticks <- time.Now()
+		assert.Equal(t, 6, (<-snapshotPipe.Out()).Data)
+
+		reducePipe.In() <- pipeline.Msg{Data: 4}
+		close(reducePipe.In())
+
+		assert.Equal(t, 10, (<-snapshotPipe.Out()).Data)
+		_, open := <-snapshotPipe.Out()
+		assert.False(t, open)
+	})
+	t.Run("rejects a non-positive interval", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.PeriodicSnapshot(0, func() pipeline.Msg { return pipeline.Msg{} }).Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "positive interval")
+	})
+}
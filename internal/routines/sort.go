@@ -0,0 +1,62 @@
+package routines
+
+import (
+	"context"
+	"sort"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// SortRoutine sorts the whole stream in memory and emits it once the input closes.
+//
+// Every message is buffered until then, so memory grows with the input; use ExternalSort
+// for streams that may not fit. Nothing is emitted before the input closes.
+type SortRoutine struct {
+	less func(a, b pipeline.Msg) bool
+}
+
+// Sort orders messages by less. The sort is stable, so equal messages keep their input
+// order.
+//
+// Example:
+//
+//	script.Chain(routines.Sort(routines.SortByField("age", routines.Descending)))
+func Sort(less func(a, b pipeline.Msg) bool) *SortRoutine {
+	return &SortRoutine{less: less}
+}
+
+// Stateful marks SortRoutine as a StatefulRoutine, since sorting needs every message.
+func (s *SortRoutine) Stateful() {}
+
+func (s *SortRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	var msgs []pipeline.Msg
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-pipe.In():
+			if !ok {
+				s.emit(ctx, msgs, pipe)
+				return nil
+			}
+
+			msgs = append(msgs, msg)
+		}
+	}
+}
+
+// emit sends msgs in sorted order, stopping early if the context ends.
+func (s *SortRoutine) emit(ctx context.Context, msgs []pipeline.Msg, pipe pipeline.Pipe) {
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return s.less(msgs[i], msgs[j])
+	})
+
+	for _, msg := range msgs {
+		if err := pipe.Send(ctx, msg); err != nil {
+			return
+		}
+	}
+}
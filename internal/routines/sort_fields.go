@@ -0,0 +1,124 @@
+package routines
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// Order is the direction of a sort key.
+type Order int
+
+const (
+	Ascending Order = iota
+	Descending
+)
+
+// CompareAs selects how the values of a sort key are compared.
+type CompareAs int
+
+const (
+	// CompareAuto compares two values as numbers when both are numeric, numeric strings
+	// such as CSV fields included, and as text otherwise. It is the default.
+	CompareAuto CompareAs = iota
+	// CompareNumeric compares values as numbers; values that are not numeric sort as missing.
+	CompareNumeric
+	// CompareString compares values as text formatted with %v.
+	CompareString
+)
+
+// SortKey is a map[string]any field to sort messages by.
+type SortKey struct {
+	Field string
+	Order Order
+	As    CompareAs
+}
+
+// SortByField returns a less function ordering map[string]any messages by field, comparing
+// values as with CompareAuto, for use with Sort or ExternalSort.
+//
+// Example:
+//
+//	routines.ExternalSort(routines.SortByField("age", routines.Descending), os.TempDir(), 10_000)
+func SortByField(field string, order Order) func(a, b pipeline.Msg) bool {
+	return SortByFields(SortKey{Field: field, Order: order})
+}
+
+// SortByFields returns a less function ordering map[string]any messages by the first key,
+// breaking ties with the following ones. Missing and nil values sort before any other in
+// ascending order, and so do messages that are not maps.
+//
+// Example:
+//
+//	less := routines.SortByFields(
+//		routines.SortKey{Field: "country"},
+//		routines.SortKey{Field: "revenue", Order: routines.Descending, As: routines.CompareNumeric},
+//	)
+func SortByFields(keys ...SortKey) func(a, b pipeline.Msg) bool {
+	return func(a, b pipeline.Msg) bool {
+		recordA, _ := a.Data.(map[string]any)
+		recordB, _ := b.Data.(map[string]any)
+
+		for _, key := range keys {
+			c := key.compare(recordA[key.Field], recordB[key.Field])
+			if key.Order == Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+
+		return false
+	}
+}
+
+func (k SortKey) compare(a, b any) int {
+	switch k.As {
+	case CompareNumeric:
+		numA, okA := sortNumber(a)
+		numB, okB := sortNumber(b)
+		if !okA || !okB {
+			return cmp.Compare(boolRank(okA), boolRank(okB))
+		}
+		return cmp.Compare(numA, numB)
+	case CompareString:
+		if a == nil || b == nil {
+			return cmp.Compare(boolRank(a != nil), boolRank(b != nil))
+		}
+		return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+	default:
+		if numA, okA := sortNumber(a); okA {
+			if numB, okB := sortNumber(b); okB {
+				return cmp.Compare(numA, numB)
+			}
+		}
+		return SortKey{As: CompareString}.compare(a, b)
+	}
+}
+
+// sortNumber reads a as a number, accepting Go numbers, json.Number and numeric strings.
+func sortNumber(a any) (float64, bool) {
+	switch v := a.(type) {
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return toFloat64(a)
+	}
+}
+
+// boolRank ranks missing values, false, before present ones.
+func boolRank(present bool) int {
+	if present {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package routines_test
+
+import (
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+// csvPeople are records as read from a CSV file and named by RowsToMaps, every value a string.
+func csvPeople() []pipeline.Msg {
+	rows := [][]string{{"carol", "100"}, {"ana", "9"}, {"bob", "10"}, {"dave", "9"}}
+
+	msgs := make([]pipeline.Msg, len(rows))
+	for i, row := range rows {
+		msgs[i] = pipeline.Msg{Data: map[string]any{"name": row[0], "age": row[1]}}
+	}
+	return msgs
+}
+
+func sortedNames(t *testing.T, less func(a, b pipeline.Msg) bool, input []pipeline.Msg) []string {
+	t.Helper()
+
+	results := runRoutine(t, routines.ExternalSort(less, t.TempDir(), 100), input)
+
+	names := make([]string, len(results))
+	for i, msg := range results {
+		names[i], _ = msg.Data.(map[string]any)["name"].(string)
+	}
+	return names
+}
+
+func TestSortByField(t *testing.T) {
+	tests := []struct {
+		name     string
+		less     func(a, b pipeline.Msg) bool
+		expected []string
+	}{
+		{
+			name:     "numeric field ascending",
+			less:     routines.SortByField("age", routines.Ascending),
+			expected: []string{"ana", "dave", "bob", "carol"},
+		},
+		{
+			name:     "numeric field descending",
+			less:     routines.SortByField("age", routines.Descending),
+			expected: []string{"carol", "bob", "ana", "dave"},
+		},
+		{
+			name:     "string field ascending",
+			less:     routines.SortByField("name", routines.Ascending),
+			expected: []string{"ana", "bob", "carol", "dave"},
+		},
+		{
+			name:     "string field descending",
+			less:     routines.SortByField("name", routines.Descending),
+			expected: []string{"dave", "carol", "bob", "ana"},
+		},
+		{
+			name:     "numbers compared as text",
+			less:     routines.SortByFields(routines.SortKey{Field: "age", As: routines.CompareString}),
+			expected: []string{"bob", "carol", "ana", "dave"},
+		},
+		{
+			name: "ties broken by the next key",
+			less: routines.SortByFields(
+				routines.SortKey{Field: "age", Order: routines.Descending, As: routines.CompareNumeric},
+				routines.SortKey{Field: "name", Order: routines.Descending},
+			),
+			expected: []string{"carol", "bob", "dave", "ana"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sortedNames(t, tt.less, csvPeople()))
+		})
+	}
+
+	t.Run("sorts missing values first", func(t *testing.T) {
+		input := append(csvPeople(), pipeline.Msg{Data: map[string]any{"name": "eve"}})
+
+		assert.Equal(t, []string{"eve", "ana", "dave", "bob", "carol"}, sortedNames(t, routines.SortByField("age", routines.Ascending), input))
+	})
+}
@@ -0,0 +1,92 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortRoutine_Start(t *testing.T) {
+	t.Run("sorts ints", func(t *testing.T) {
+		byInt := func(a, b pipeline.Msg) bool { return a.Data.(int) < b.Data.(int) }
+		input := []pipeline.Msg{{ID: "a", Data: 3}, {ID: "b", Data: 1}, {ID: "c", Data: 2}}
+
+		results := runRoutine(t, routines.Sort(byInt), input)
+
+		assert.Equal(t, []pipeline.Msg{input[1], input[2], input[0]}, results)
+	})
+
+	t.Run("sorts strings keeping equal ones in input order", func(t *testing.T) {
+		byString := func(a, b pipeline.Msg) bool { return a.Data.(string) < b.Data.(string) }
+		input := []pipeline.Msg{{ID: "1", Data: "pear"}, {ID: "2", Data: "apple"}, {ID: "3", Data: "fig"}, {ID: "4", Data: "apple"}}
+
+		results := runRoutine(t, routines.Sort(byString), input)
+
+		assert.Equal(t, []pipeline.Msg{input[1], input[3], input[2], input[0]}, results)
+	})
+
+	t.Run("sorts records by field", func(t *testing.T) {
+		input := []pipeline.Msg{
+			{Data: map[string]any{"age": 31.0}},
+			{Data: map[string]any{"age": 45.0}},
+			{Data: map[string]any{"age": 27.0}},
+		}
+
+		results := runRoutine(t, routines.Sort(routines.SortByField("age", routines.Descending)), input)
+
+		assert.Equal(t, []pipeline.Msg{input[1], input[0], input[2]}, results)
+	})
+
+	t.Run("emits nothing until the input closes", func(t *testing.T) {
+		byInt := func(a, b pipeline.Msg) bool { return a.Data.(int) < b.Data.(int) }
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Sort(byInt).Start(context.Background(), pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: 2}
+		pipe.In() <- pipeline.Msg{Data: 1}
+
+		select {
+		case msg := <-pipe.Out():
+			t.Fatalf("sort emitted %v before its input closed", msg.Data)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(pipe.In())
+
+		assert.Equal(t, 1, (<-pipe.Out()).Data)
+		assert.Equal(t, 2, (<-pipe.Out()).Data)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("stops collecting when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Sort(func(a, b pipeline.Msg) bool { return false }).Start(ctx, pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: 1}
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("sort did not stop after cancellation")
+		}
+
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+	})
+}
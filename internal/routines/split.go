@@ -0,0 +1,127 @@
+package routines
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// SplitLinesRoutine splits string and []byte messages, such as a blob read from a file,
+// into one message per line of the same type. A trailing separator does not produce an
+// empty last line, and with the default "\n" separator a "\r" ending each line is dropped,
+// as the line codec does. Other messages pass through unchanged.
+type SplitLinesRoutine struct {
+	separator string
+}
+
+func SplitLines() *SplitLinesRoutine {
+	return &SplitLinesRoutine{separator: "\n"}
+}
+
+// WithSeparator splits on sep instead of newlines, e.g. "\x00" for find -print0 output
+func (s *SplitLinesRoutine) WithSeparator(sep string) *SplitLinesRoutine {
+	s.separator = sep
+	return s
+}
+
+func (s *SplitLinesRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		var lines []any
+
+		switch v := msg.Data.(type) {
+		case string:
+			for _, line := range s.splitString(v) {
+				lines = append(lines, line)
+			}
+		case []byte:
+			for _, line := range s.splitBytes(v) {
+				lines = append(lines, line)
+			}
+		default:
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		for _, line := range lines {
+			if err := pipe.Send(ctx, pipeline.Msg{ID: pipeline.NewID(ctx), Data: line, IngestedAt: msg.IngestedAt}); err != nil {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *SplitLinesRoutine) splitString(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, s.separator), s.separator)
+	if s.separator == "\n" {
+		for i, line := range lines {
+			lines[i] = strings.TrimSuffix(line, "\r")
+		}
+	}
+
+	return lines
+}
+
+func (s *SplitLinesRoutine) splitBytes(text []byte) [][]byte {
+	if len(text) == 0 {
+		return nil
+	}
+
+	sep := []byte(s.separator)
+
+	lines := bytes.Split(bytes.TrimSuffix(text, sep), sep)
+	if s.separator == "\n" {
+		for i, line := range lines {
+			lines[i] = bytes.TrimSuffix(line, []byte("\r"))
+		}
+	}
+
+	return lines
+}
+
+// JoinRoutine joins []string and []any messages into a single string, the inverse of
+// splitting, e.g. to rebuild a delimited line from a parsed CSV record. Elements of []any
+// are formatted with %v. Other messages pass through unchanged.
+type JoinRoutine struct {
+	separator string
+}
+
+func Join(sep string) *JoinRoutine {
+	return &JoinRoutine{separator: sep}
+}
+
+func (j *JoinRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		switch v := msg.Data.(type) {
+		case []string:
+			msg = msg.WithData(strings.Join(v, j.separator))
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprintf("%v", item)
+			}
+
+			msg = msg.WithData(strings.Join(items, j.separator))
+		}
+
+		if err := pipe.Send(ctx, msg); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
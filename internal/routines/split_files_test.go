@@ -0,0 +1,96 @@
+package routines_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lineMsgs(n int) []pipeline.Msg {
+	msgs := make([]pipeline.Msg, n)
+	for i := range msgs {
+		msgs[i] = pipeline.Msg{ID: fmt.Sprint(i), Data: fmt.Sprintf("line-%02d", i)}
+	}
+	return msgs
+}
+
+// readShards returns the lines of every file in dir, in file name order.
+func readShards(t *testing.T, dir string) [][]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var shards [][]string
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		shards = append(shards, strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"))
+	}
+
+	return shards
+}
+
+func TestSplitFilesRoutine_Start(t *testing.T) {
+	t.Run("rolls to a new file every perFile records", func(t *testing.T) {
+		dir := t.TempDir()
+
+		runRoutine(t, routines.SplitFilesByCount(filepath.Join(dir, "out_%03d"), 3), lineMsgs(10))
+
+		assert.FileExists(t, filepath.Join(dir, "out_000"))
+		assert.FileExists(t, filepath.Join(dir, "out_003"))
+
+		shards := readShards(t, dir)
+		require.Len(t, shards, 4)
+		assert.Equal(t, []string{"line-00", "line-01", "line-02"}, shards[0])
+		assert.Len(t, shards[1], 3)
+		assert.Len(t, shards[2], 3)
+		assert.Equal(t, []string{"line-09"}, shards[3])
+	})
+
+	t.Run("rolls before a record that would exceed the size", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// each record is 8 bytes with its newline, so two fit in 20 bytes
+		runRoutine(t, routines.SplitFilesBySize(filepath.Join(dir, "out_%03d"), 20), lineMsgs(5))
+
+		shards := readShards(t, dir)
+		require.Len(t, shards, 3)
+		assert.Equal(t, []string{"line-00", "line-01"}, shards[0])
+		assert.Equal(t, []string{"line-02", "line-03"}, shards[1])
+		assert.Equal(t, []string{"line-04"}, shards[2])
+	})
+
+	t.Run("gives a record larger than the size its own file", func(t *testing.T) {
+		dir := t.TempDir()
+
+		runRoutine(t, routines.SplitFilesBySize(filepath.Join(dir, "out_%03d"), 4), lineMsgs(2))
+
+		assert.Len(t, readShards(t, dir), 2)
+	})
+
+	t.Run("creates no file without input", func(t *testing.T) {
+		dir := t.TempDir()
+
+		runRoutine(t, routines.SplitFilesByCount(filepath.Join(dir, "out_%03d"), 3), nil)
+
+		assert.Empty(t, readShards(t, dir))
+	})
+
+	t.Run("rejects a pattern without an index placeholder", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.SplitFilesByCount(filepath.Join(t.TempDir(), "out"), 3).Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "needs a single index placeholder")
+	})
+}
@@ -0,0 +1,102 @@
+package routines_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLinesRoutine_Start(t *testing.T) {
+	ingestedAt := time.Now()
+
+	tests := []struct {
+		name     string
+		routine  *routines.SplitLinesRoutine
+		input    any
+		expected []any
+	}{
+		{
+			name:     "multi-line blob",
+			routine:  routines.SplitLines(),
+			input:    "first\nsecond\nthird\n",
+			expected: []any{"first", "second", "third"},
+		},
+		{
+			name:     "no trailing newline and blank lines",
+			routine:  routines.SplitLines(),
+			input:    "first\n\nthird",
+			expected: []any{"first", "", "third"},
+		},
+		{
+			name:     "crlf",
+			routine:  routines.SplitLines(),
+			input:    "first\r\nsecond\r\n",
+			expected: []any{"first", "second"},
+		},
+		{
+			name:     "bytes stay bytes",
+			routine:  routines.SplitLines(),
+			input:    []byte("first\nsecond"),
+			expected: []any{[]byte("first"), []byte("second")},
+		},
+		{
+			name:     "custom separator",
+			routine:  routines.SplitLines().WithSeparator("\x00"),
+			input:    "a.txt\x00b\r.txt\x00",
+			expected: []any{"a.txt", "b\r.txt"},
+		},
+		{
+			name:    "empty blob",
+			routine: routines.SplitLines(),
+			input:   "",
+		},
+		{
+			name:     "other types pass through",
+			routine:  routines.SplitLines(),
+			input:    42,
+			expected: []any{42},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := runRoutine(t, tt.routine, []pipeline.Msg{{ID: "blob", Data: tt.input, IngestedAt: ingestedAt}})
+
+			var data []any
+			for _, msg := range results {
+				data = append(data, msg.Data)
+				assert.Equal(t, ingestedAt, msg.IngestedAt)
+			}
+
+			assert.Equal(t, tt.expected, data)
+		})
+	}
+}
+
+func TestJoinRoutine_Start(t *testing.T) {
+	tests := []struct {
+		name     string
+		sep      string
+		input    any
+		expected any
+	}{
+		{name: "strings", sep: ",", input: []string{"alice", "30", "NYC"}, expected: "alice,30,NYC"},
+		{name: "mixed values", sep: " | ", input: []any{"total", 42, 1.5}, expected: "total | 42 | 1.5"},
+		{name: "empty separator", sep: "", input: []string{"a", "b"}, expected: "ab"},
+		{name: "multi-character separator", sep: "\n", input: []string{"first", "second"}, expected: "first\nsecond"},
+		{name: "empty slice", sep: ",", input: []string{}, expected: ""},
+		{name: "empty any slice", sep: ",", input: []any{}, expected: ""},
+		{name: "other types pass through", sep: ",", input: 7, expected: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := runRoutine(t, routines.Join(tt.sep), []pipeline.Msg{{ID: "row", Data: tt.input}})
+
+			assert.Equal(t, []pipeline.Msg{{ID: "row", Data: tt.expected}}, results)
+		})
+	}
+}
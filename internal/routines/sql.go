@@ -0,0 +1,239 @@
+package routines
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// SQLRoutine configures sinks writing messages to a database through database/sql.
+type SQLRoutine struct {
+	db          *sql.DB
+	placeholder func(n int) string
+}
+
+// SQL writes to db, using "?" placeholders unless WithPlaceholder is set.
+func SQL(db *sql.DB) *SQLRoutine {
+	return &SQLRoutine{
+		db:          db,
+		placeholder: func(int) string { return "?" },
+	}
+}
+
+// WithPlaceholder sets how the n-th (1-based) statement parameter is written, e.g.
+// "$1", "$2"... for PostgreSQL drivers
+func (s *SQLRoutine) WithPlaceholder(placeholder func(n int) string) *SQLRoutine {
+	s.placeholder = placeholder
+	return s
+}
+
+// Insert returns a sink inserting every message as a row of table. A map[string]any
+// message fills the columns by name, missing keys becoming NULL, and a []any message
+// fills them by position. Table and column names are written into the statement as is,
+// so they must not come from untrusted input.
+//
+// Example:
+//
+//	script.Out(routines.SQL(db).Insert("users", []string{"id", "name"}).WithTxBatchSize(500))
+func (s *SQLRoutine) Insert(table string, columns []string) *SQLInsertRoutine {
+	return &SQLInsertRoutine{sql: s, table: table, columns: columns}
+}
+
+// SQLBatchError reports a transaction batch that failed, or was interrupted by the context
+// ending, and was rolled back. Batches before it stay committed.
+type SQLBatchError struct {
+	// FirstRow is the 1-based number, in the stream, of the first row of the batch
+	FirstRow int
+	// Rows is how many rows of the batch were rolled back, the failing one included
+	Rows int
+	Err  error
+}
+
+func (e *SQLBatchError) Error() string {
+	return fmt.Sprintf("sql batch of rows %d to %d rolled back: %v", e.FirstRow, e.FirstRow+e.Rows-1, e.Err)
+}
+
+func (e *SQLBatchError) Unwrap() error {
+	return e.Err
+}
+
+type SQLInsertRoutine struct {
+	sql         *SQLRoutine
+	table       string
+	columns     []string
+	txBatchSize int
+}
+
+// WithTxBatchSize inserts rows in transactions of n rows, committing one and beginning
+// the next every n rows, so larger n trades durability for throughput. A failing or
+// malformed row rolls back only the rows of its batch and stops the sink with a
+// *SQLBatchError, as does the context ending with a batch open. By default every row is
+// inserted on its own, without an explicit transaction.
+func (r *SQLInsertRoutine) WithTxBatchSize(n int) *SQLInsertRoutine {
+	r.txBatchSize = n
+	return r
+}
+
+func (r *SQLInsertRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if r.txBatchSize < 0 {
+		return fmt.Errorf("sql transaction batch size must not be negative, got %d", r.txBatchSize)
+	}
+
+	stmt, err := r.sql.db.PrepareContext(ctx, r.query())
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert into %s: %w", r.table, err)
+	}
+	defer stmt.Close()
+
+	batch := &sqlBatch{stmt: stmt}
+	defer batch.rollback()
+
+	for row := 1; ; row++ {
+		var msg pipeline.Msg
+		var ok bool
+
+		select {
+		case <-ctx.Done():
+			// the rows of the open batch are rolled back, so report them as lost
+			if batch.tx != nil {
+				return &SQLBatchError{FirstRow: batch.firstRow, Rows: batch.rows, Err: ctx.Err()}
+			}
+			return nil
+		case msg, ok = <-pipe.In():
+		}
+
+		if !ok {
+			if err := batch.commit(); err != nil {
+				return &SQLBatchError{FirstRow: batch.firstRow, Rows: batch.rows, Err: err}
+			}
+			return nil
+		}
+
+		args, err := r.args(msg.Data)
+		if err != nil {
+			err = fmt.Errorf("failed to insert row %d into %s: %w", row, r.table, err)
+
+			// a malformed row ends the open batch like a failing insert, counting itself
+			if batch.tx != nil {
+				batch.rollback()
+				return &SQLBatchError{FirstRow: batch.firstRow, Rows: batch.rows + 1, Err: err}
+			}
+			return err
+		}
+
+		if r.txBatchSize == 0 {
+			if _, err := stmt.ExecContext(ctx, args...); err != nil {
+				return fmt.Errorf("failed to insert row %d into %s: %w", row, r.table, err)
+			}
+			continue
+		}
+
+		// no transaction is open when beginning one fails, so no rows are rolled back
+		if batch.tx == nil {
+			if err := batch.begin(ctx, r.sql.db, row); err != nil {
+				return fmt.Errorf("failed to insert row %d into %s: %w", row, r.table, err)
+			}
+		}
+
+		if err := batch.exec(ctx, args); err != nil {
+			batch.rollback()
+			return &SQLBatchError{FirstRow: batch.firstRow, Rows: batch.rows, Err: err}
+		}
+
+		if batch.rows == r.txBatchSize {
+			if err := batch.commit(); err != nil {
+				return &SQLBatchError{FirstRow: batch.firstRow, Rows: batch.rows, Err: err}
+			}
+		}
+	}
+}
+
+func (r *SQLInsertRoutine) query() string {
+	placeholders := make([]string, len(r.columns))
+	for i := range r.columns {
+		placeholders[i] = r.sql.placeholder(i + 1)
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		r.table, strings.Join(r.columns, ", "), strings.Join(placeholders, ", "))
+}
+
+// args returns the statement parameters for a row message.
+func (r *SQLInsertRoutine) args(data any) ([]any, error) {
+	switch row := data.(type) {
+	case map[string]any:
+		args := make([]any, len(r.columns))
+		for i, column := range r.columns {
+			args[i] = row[column]
+		}
+		return args, nil
+	case []any:
+		if len(row) != len(r.columns) {
+			return nil, fmt.Errorf("row has %d values for %d columns", len(row), len(r.columns))
+		}
+		return row, nil
+	default:
+		return nil, fmt.Errorf("unsupported row type %T", data)
+	}
+}
+
+// sqlBatch is the open transaction of a batched insert and the rows executed in it.
+type sqlBatch struct {
+	stmt *sql.Stmt
+	tx   *sql.Tx
+	// txStmt is stmt bound to tx, created once per transaction
+	txStmt   *sql.Stmt
+	firstRow int
+	rows     int
+}
+
+// begin opens a transaction whose first row is row.
+func (b *sqlBatch) begin(ctx context.Context, db *sql.DB, row int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	b.tx, b.txStmt, b.firstRow, b.rows = tx, tx.StmtContext(ctx, b.stmt), row, 0
+
+	return nil
+}
+
+// exec inserts a row in the open transaction.
+func (b *sqlBatch) exec(ctx context.Context, args []any) error {
+	b.rows++
+
+	_, err := b.txStmt.ExecContext(ctx, args...)
+	return err
+}
+
+// commit commits the open transaction, if any.
+func (b *sqlBatch) commit() error {
+	if b.tx == nil {
+		return nil
+	}
+
+	tx := b.tx
+	b.tx, b.txStmt = nil, nil
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// rollback discards the open transaction, if any.
+func (b *sqlBatch) rollback() {
+	if b.tx == nil {
+		return
+	}
+
+	_ = b.tx.Rollback()
+	b.tx, b.txStmt = nil, nil
+}
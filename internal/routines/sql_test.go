@@ -0,0 +1,308 @@
+package routines_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB is an in-memory database/sql backend recording prepared queries, committed rows
+// and transaction outcomes. Executing a row holding failOn fails.
+type fakeDB struct {
+	mu        sync.Mutex
+	queries   []string
+	committed [][]driver.Value
+	commits   int
+	rollbacks int
+	executed  int
+	failOn    driver.Value
+	// failBegin makes the n-th (1-based) transaction fail to begin
+	failBegin int
+	begins    int
+}
+
+func (f *fakeDB) open() *sql.DB {
+	return sql.OpenDB(fakeConnector{db: f})
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: c.db}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("open through the connector")
+}
+
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.queries = append(c.db.queries, query)
+
+	return &fakeStmt{conn: c}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	c.db.begins++
+	failed := c.db.begins == c.db.failBegin
+	c.db.mu.Unlock()
+
+	if failed {
+		return nil, errors.New("too many connections")
+	}
+
+	c.tx = &fakeTx{conn: c}
+	return c.tx, nil
+}
+
+type fakeTx struct {
+	conn    *fakeConn
+	pending [][]driver.Value
+}
+
+func (t *fakeTx) Commit() error {
+	t.conn.db.mu.Lock()
+	defer t.conn.db.mu.Unlock()
+
+	t.conn.db.committed = append(t.conn.db.committed, t.pending...)
+	t.conn.db.commits++
+	t.conn.tx = nil
+
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.conn.db.mu.Lock()
+	defer t.conn.db.mu.Unlock()
+
+	t.conn.db.rollbacks++
+	t.conn.tx = nil
+
+	return nil
+}
+
+type fakeStmt struct{ conn *fakeConn }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.executed++
+
+	for _, arg := range args {
+		if db.failOn != nil && arg == db.failOn {
+			return nil, errors.New("constraint violated")
+		}
+	}
+
+	if s.conn.tx != nil {
+		s.conn.tx.pending = append(s.conn.tx.pending, args)
+	} else {
+		db.committed = append(db.committed, args)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func insertRows(routine pipeline.Routine, n int) error {
+	pipe := pipeline.NewChanPipe()
+	go func() {
+		defer close(pipe.In())
+		for i := 1; i <= n; i++ {
+			pipe.In() <- pipeline.Msg{Data: map[string]any{"id": int64(i), "name": "user" + strconv.Itoa(i)}}
+		}
+	}()
+
+	return routine.Start(context.Background(), pipe)
+}
+
+func TestSQLInsertRoutine_Start(t *testing.T) {
+	columns := []string{"id", "name"}
+
+	committedIDs := func(f *fakeDB) []int64 {
+		var ids []int64
+		for _, row := range f.committed {
+			ids = append(ids, row[0].(int64))
+		}
+		return ids
+	}
+
+	t.Run("inserts every row on its own by default", func(t *testing.T) {
+		fake := &fakeDB{}
+
+		require.NoError(t, insertRows(routines.SQL(fake.open()).Insert("users", columns), 3))
+
+		assert.Equal(t, "INSERT INTO users (id, name) VALUES (?, ?)", fake.queries[0])
+		assert.Equal(t, [][]driver.Value{{int64(1), "user1"}, {int64(2), "user2"}, {int64(3), "user3"}}, fake.committed)
+		assert.Zero(t, fake.commits)
+	})
+
+	t.Run("uses the configured placeholders", func(t *testing.T) {
+		fake := &fakeDB{}
+		dollar := func(n int) string { return "$" + strconv.Itoa(n) }
+
+		require.NoError(t, insertRows(routines.SQL(fake.open()).WithPlaceholder(dollar).Insert("users", columns), 1))
+
+		assert.Equal(t, "INSERT INTO users (id, name) VALUES ($1, $2)", fake.queries[0])
+	})
+
+	t.Run("commits every n rows and the remainder", func(t *testing.T) {
+		fake := &fakeDB{}
+
+		require.NoError(t, insertRows(routines.SQL(fake.open()).Insert("users", columns).WithTxBatchSize(2), 5))
+
+		assert.Equal(t, 3, fake.commits)
+		assert.Zero(t, fake.rollbacks)
+		assert.Equal(t, []int64{1, 2, 3, 4, 5}, committedIDs(fake))
+	})
+
+	t.Run("rolls back only the failing batch", func(t *testing.T) {
+		fake := &fakeDB{failOn: "user4"}
+
+		err := insertRows(routines.SQL(fake.open()).Insert("users", columns).WithTxBatchSize(2), 5)
+
+		var batchErr *routines.SQLBatchError
+		require.ErrorAs(t, err, &batchErr)
+		assert.Equal(t, 3, batchErr.FirstRow)
+		assert.Equal(t, 2, batchErr.Rows)
+		assert.ErrorContains(t, err, "rows 3 to 4 rolled back: constraint violated")
+
+		assert.Equal(t, 1, fake.commits)
+		assert.Equal(t, 1, fake.rollbacks)
+		assert.Equal(t, []int64{1, 2}, committedIDs(fake))
+	})
+
+	t.Run("rolls back the open batch on a malformed row", func(t *testing.T) {
+		fake := &fakeDB{}
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			defer close(pipe.In())
+			for _, row := range []any{
+				[]any{int64(1), "ana"},
+				[]any{int64(2), "bob"},
+				[]any{int64(3), "cid"},
+				[]any{int64(4)},
+			} {
+				pipe.In() <- pipeline.Msg{Data: row}
+			}
+		}()
+
+		err := routines.SQL(fake.open()).Insert("users", columns).WithTxBatchSize(2).Start(context.Background(), pipe)
+
+		var batchErr *routines.SQLBatchError
+		require.True(t, errors.As(err, &batchErr))
+		assert.Equal(t, 3, batchErr.FirstRow)
+		assert.Equal(t, 2, batchErr.Rows)
+		assert.ErrorContains(t, err, "rows 3 to 4 rolled back: failed to insert row 4 into users: row has 1 values for 2 columns")
+
+		assert.Equal(t, 1, fake.rollbacks)
+		assert.Equal(t, []int64{1, 2}, committedIDs(fake))
+	})
+
+	t.Run("reports a malformed row starting a batch without a batch", func(t *testing.T) {
+		fake := &fakeDB{}
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			defer close(pipe.In())
+			pipe.In() <- pipeline.Msg{Data: "not a row"}
+		}()
+
+		err := routines.SQL(fake.open()).Insert("users", columns).WithTxBatchSize(2).Start(context.Background(), pipe)
+
+		var batchErr *routines.SQLBatchError
+		assert.False(t, errors.As(err, &batchErr))
+		assert.ErrorContains(t, err, "failed to insert row 1 into users: unsupported row type string")
+		assert.Zero(t, fake.rollbacks)
+	})
+
+	t.Run("reports a transaction failing to begin without a batch", func(t *testing.T) {
+		fake := &fakeDB{failBegin: 2}
+
+		err := insertRows(routines.SQL(fake.open()).Insert("users", columns).WithTxBatchSize(2), 5)
+
+		var batchErr *routines.SQLBatchError
+		assert.False(t, errors.As(err, &batchErr))
+		assert.ErrorContains(t, err, "failed to insert row 3 into users: failed to begin transaction: too many connections")
+
+		assert.Equal(t, 1, fake.commits)
+		assert.Equal(t, []int64{1, 2}, committedIDs(fake))
+	})
+
+	t.Run("reports the rows of the open batch lost on cancellation", func(t *testing.T) {
+		fake := &fakeDB{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.SQL(fake.open()).Insert("users", columns).WithTxBatchSize(10).Start(ctx, pipe)
+		}()
+
+		for i := int64(1); i <= 3; i++ {
+			pipe.In() <- pipeline.Msg{Data: []any{i, "user"}}
+		}
+
+		require.Eventually(t, func() bool {
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			return fake.executed == 3
+		}, time.Second, time.Millisecond)
+		cancel()
+
+		err := <-done
+
+		var batchErr *routines.SQLBatchError
+		require.ErrorAs(t, err, &batchErr)
+		assert.Equal(t, 1, batchErr.FirstRow)
+		assert.Equal(t, 3, batchErr.Rows)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, committedIDs(fake))
+	})
+
+	t.Run("fills rows given as slices by position", func(t *testing.T) {
+		fake := &fakeDB{}
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			defer close(pipe.In())
+			pipe.In() <- pipeline.Msg{Data: []any{int64(7), "ana"}}
+		}()
+
+		require.NoError(t, routines.SQL(fake.open()).Insert("users", columns).Start(context.Background(), pipe))
+
+		assert.Equal(t, [][]driver.Value{{int64(7), "ana"}}, fake.committed)
+	})
+}
@@ -0,0 +1,81 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// StatusLineRoutine forwards messages unchanged while keeping a live status line on
+// stderr, for CLIs whose data goes to stdout. Every interval the line is rewritten in place
+// with a carriage return; when the input closes or ctx ends it shows the final count and
+// ends with a newline, leaving the terminal on a fresh line.
+type StatusLineRoutine struct {
+	format    string
+	interval  time.Duration
+	writer    io.Writer
+	newTicker tickerFunc
+}
+
+// StatusLine reports progress as format applied to the number of messages forwarded so
+// far, e.g. "processed %d records".
+func StatusLine(format string, interval time.Duration) *StatusLineRoutine {
+	return &StatusLineRoutine{format: format, interval: interval, writer: os.Stderr, newTicker: newTimeTicker}
+}
+
+// WithWriter writes the status line to w instead of stderr
+func (s *StatusLineRoutine) WithWriter(w io.Writer) *StatusLineRoutine {
+	s.writer = w
+	return s
+}
+
+// Stateful marks StatusLineRoutine as a StatefulRoutine, since its counts cover the whole
+// stream.
+func (s *StatusLineRoutine) Stateful() {}
+
+func (s *StatusLineRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if s.interval <= 0 {
+		return fmt.Errorf("status line needs a positive interval, got %s", s.interval)
+	}
+
+	ticks, stop := s.newTicker(s.interval)
+	defer stop()
+
+	var count int
+
+	defer func() {
+		s.render(count)
+		fmt.Fprintln(s.writer)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+			s.render(count)
+		case msg, ok := <-pipe.In():
+			if !ok {
+				return nil
+			}
+
+			count++
+
+			if err := pipe.Send(ctx, msg); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// render rewrites the status line. The count only grows, so each status is at least as
+// wide as the one it overwrites.
+func (s *StatusLineRoutine) render(count int) {
+	fmt.Fprintf(s.writer, "\r"+s.format, count)
+}
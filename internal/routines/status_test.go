@@ -0,0 +1,80 @@
+package routines_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusLineRoutine_Start(t *testing.T) {
+	t.Run("rewrites the status at each interval and ends with a newline", func(t *testing.T) {
+		ticks, ticker := fakeTicker()
+
+		var stderr bytes.Buffer
+		status := routines.StatusLine("processed %d records", time.Second).WithWriter(&stderr).WithTicker(ticker)
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- status.Start(context.Background(), pipe)
+		}()
+
+		forward := func(data string) {
+			pipe.In() <- pipeline.Msg{Data: data}
+			assert.Equal(t, data, (<-pipe.Out()).Data, "messages are forwarded unchanged")
+		}
+
+		ticks <- time.Now()
+		forward("a")
+		forward("b")
+		ticks <- time.Now()
+		forward("c")
+
+		close(pipe.In())
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+		require.NoError(t, <-done)
+
+		assert.Equal(t, "\rprocessed 0 records\rprocessed 2 records\rprocessed 3 records\n", stderr.String())
+	})
+
+	t.Run("ends the status line when cancelled", func(t *testing.T) {
+		_, ticker := fakeTicker()
+
+		var stderr bytes.Buffer
+		status := routines.StatusLine("processed %d records", time.Second).WithWriter(&stderr).WithTicker(ticker)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- status.Start(ctx, pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: "a"}
+		<-pipe.Out()
+		cancel()
+
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+		require.NoError(t, <-done)
+
+		assert.Equal(t, "\rprocessed 1 records\n", stderr.String())
+	})
+	t.Run("rejects a non-positive interval", func(t *testing.T) {
+		var stderr bytes.Buffer
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.StatusLine("processed %d records", 0).WithWriter(&stderr).Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "positive interval")
+		assert.Empty(t, stderr.String())
+	})
+}
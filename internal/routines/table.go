@@ -0,0 +1,110 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// TableOutRoutine writes messages to w as a table of aligned columns, for output read by
+// people in a terminal.
+//
+// map[string]any messages become rows under a header holding every key seen, sorted, unless
+// WithColumns fixes the columns. Missing and nil values are left blank and others are
+// formatted with %v. []string messages, such as CSV records, are written as they are, so the
+// first one usually serves as the header. Other messages are logged and skipped.
+//
+// Aligning a column needs the width of all its cells, so the routine buffers every message
+// and writes nothing until its input closes. Use it for bounded streams only; unbounded ones
+// are better served by StdOut.
+type TableOutRoutine struct {
+	w       io.Writer
+	columns []string
+}
+
+func TableOut(w io.Writer) *TableOutRoutine {
+	return &TableOutRoutine{w: w}
+}
+
+// WithColumns sets the columns of map rows and their order, instead of every key sorted.
+// Keys outside columns are left out.
+func (t *TableOutRoutine) WithColumns(columns ...string) *TableOutRoutine {
+	t.columns = columns
+	return t
+}
+
+// Stateful marks TableOutRoutine as a StatefulRoutine, since column widths depend on
+// every row.
+func (t *TableOutRoutine) Stateful() {}
+
+func (t *TableOutRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	var records []any
+	keys := make(map[string]struct{})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-pipe.In():
+			if !ok {
+				return t.render(records, keys)
+			}
+
+			switch v := msg.Data.(type) {
+			case map[string]any:
+				for key := range v {
+					keys[key] = struct{}{}
+				}
+				records = append(records, v)
+			case []string:
+				records = append(records, v)
+			default:
+				slog.Warn("table unknown type", "msg_id", msg.ID, "type", fmt.Sprintf("%T", msg.Data))
+			}
+		}
+	}
+}
+
+// render writes records through a tabwriter, preceded by the header when any record is a map.
+func (t *TableOutRoutine) render(records []any, keys map[string]struct{}) error {
+	header := t.columns
+	if header == nil {
+		for key := range keys {
+			header = append(header, key)
+		}
+		slices.Sort(header)
+	}
+
+	tw := tabwriter.NewWriter(t.w, 0, 0, 2, ' ', 0)
+
+	if len(keys) > 0 {
+		writeTableRow(tw, header)
+	}
+
+	for _, record := range records {
+		switch v := record.(type) {
+		case map[string]any:
+			writeTableRow(tw, mapToRow(header, v))
+		case []string:
+			writeTableRow(tw, v)
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write table: %w", err)
+	}
+
+	return nil
+}
+
+func writeTableRow(w io.Writer, cells []string) {
+	fmt.Fprintln(w, strings.Join(cells, "\t"))
+}
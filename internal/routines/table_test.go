@@ -0,0 +1,84 @@
+package routines_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func renderTable(t *testing.T, routine *routines.TableOutRoutine, data ...any) {
+	t.Helper()
+
+	pipe := pipeline.NewChanPipe()
+	go func() {
+		defer close(pipe.In())
+		for _, d := range data {
+			pipe.In() <- pipeline.Msg{Data: d}
+		}
+	}()
+
+	require.NoError(t, routine.Start(context.Background(), pipe))
+}
+
+func TestTableOutRoutine_Start(t *testing.T) {
+	t.Run("aligns map rows under a sorted header", func(t *testing.T) {
+		var out bytes.Buffer
+
+		renderTable(t, routines.TableOut(&out),
+			map[string]any{"name": "ana", "age": 31, "city": "Lisbon"},
+			map[string]any{"name": "maximiliano", "age": 7, "city": "Rio"},
+			map[string]any{"name": "bo", "age": 104},
+		)
+
+		expected := "" +
+			"age  city    name\n" +
+			"31   Lisbon  ana\n" +
+			"7    Rio     maximiliano\n" +
+			"104          bo\n"
+		assert.Equal(t, expected, out.String())
+	})
+
+	t.Run("uses the given columns in order", func(t *testing.T) {
+		var out bytes.Buffer
+
+		renderTable(t, routines.TableOut(&out).WithColumns("name", "age"),
+			map[string]any{"name": "ana", "age": 31, "city": "Lisbon"},
+			map[string]any{"name": "maximiliano", "age": nil},
+		)
+
+		expected := "" +
+			"name         age\n" +
+			"ana          31\n" +
+			"maximiliano  \n"
+		assert.Equal(t, expected, out.String())
+	})
+
+	t.Run("writes string rows as they are", func(t *testing.T) {
+		var out bytes.Buffer
+
+		renderTable(t, routines.TableOut(&out),
+			[]string{"id", "description"},
+			[]string{"1", "short"},
+			[]string{"1024", "a longer description"},
+		)
+
+		expected := "" +
+			"id    description\n" +
+			"1     short\n" +
+			"1024  a longer description\n"
+		assert.Equal(t, expected, out.String())
+	})
+
+	t.Run("skips other messages", func(t *testing.T) {
+		var out bytes.Buffer
+
+		renderTable(t, routines.TableOut(&out), 42, []string{"a", "b"})
+
+		assert.Equal(t, "a  b\n", out.String())
+	})
+}
@@ -0,0 +1,190 @@
+package routines
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+const defaultTailPollInterval = 100 * time.Millisecond
+
+// TailRoutine follows a file like `tail -f`: it emits the existing lines, then keeps
+// polling for appended lines until ctx is done. A truncated file is read again from the
+// start and a rotated file (a new file at the same path) is reopened.
+//
+// Content after the last newline is held back until its terminator arrives, so a line
+// caught while being written is emitted once, whole. See WithPartialLineTimeout to emit
+// such a line anyway when its writer stalls.
+type TailRoutine struct {
+	path           string
+	pollInterval   time.Duration
+	partialTimeout time.Duration
+}
+
+func Tail(path string) *TailRoutine {
+	return &TailRoutine{path: path, pollInterval: defaultTailPollInterval}
+}
+
+// WithPollInterval sets how often the file is checked for new content. Defaults to 100ms.
+func (t *TailRoutine) WithPollInterval(interval time.Duration) *TailRoutine {
+	t.pollInterval = interval
+	return t
+}
+
+// WithPartialLineTimeout emits an unterminated line once it has waited d for its newline,
+// e.g. for a writer that never ends its last line. Content appended afterwards starts a new
+// line. By default partial lines wait indefinitely.
+func (t *TailRoutine) WithPartialLineTimeout(d time.Duration) *TailRoutine {
+	t.partialTimeout = d
+	return t
+}
+
+func (t *TailRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	file, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to open file to tail: %w", err)
+	}
+	defer func() { file.Close() }()
+
+	slog.Info("tailing file", "path", t.path)
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	var partial strings.Builder
+	// partialSince is when the content buffered in partial was first read
+	var partialSince time.Time
+
+	// next is a rotated-in file, switched to once the old one is fully read
+	var next *os.File
+	defer func() {
+		if next != nil {
+			next.Close()
+		}
+	}()
+
+	emit := func(line string) bool {
+		msg := pipeline.NewMsg(pipeline.NewID(ctx), strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+
+		return pipe.Send(ctx, msg) == nil
+	}
+
+	swap := func(reopened *os.File) {
+		file.Close()
+		file = reopened
+		reader.Reset(file)
+		offset = 0
+		partial.Reset()
+	}
+
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		chunk, err := reader.ReadString('\n')
+		offset += int64(len(chunk))
+		if partial.Len() == 0 && chunk != "" {
+			partialSince = time.Now()
+		}
+		partial.WriteString(chunk)
+
+		if err == nil {
+			line := partial.String()
+			partial.Reset()
+
+			if !emit(line) {
+				return nil
+			}
+			continue
+		}
+
+		if !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read tailed file: %w", err)
+		}
+
+		if next != nil {
+			// the rotated file is exhausted, flush its unterminated last line
+			if partial.Len() > 0 && !emit(partial.String()) {
+				return nil
+			}
+
+			swap(next)
+			next = nil
+			continue
+		}
+
+		if t.partialTimeout > 0 && partial.Len() > 0 && time.Since(partialSince) >= t.partialTimeout {
+			line := partial.String()
+			partial.Reset()
+
+			if !emit(line) {
+				return nil
+			}
+		}
+
+		// caught up, wait for the file to change
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		reopened, rotated, err := t.reopenIfReplaced(file, offset)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case reopened == nil:
+		case rotated:
+			next = reopened
+		default:
+			swap(reopened)
+		}
+	}
+}
+
+// reopenIfReplaced returns a fresh handle read from the start when the file at path was
+// truncated below offset or replaced by another file (rotated), or nil if tailing can
+// continue with current.
+func (t *TailRoutine) reopenIfReplaced(current *os.File, offset int64) (reopened *os.File, rotated bool, err error) {
+	pathInfo, err := os.Stat(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// rotated away and not recreated yet
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat tailed file: %w", err)
+	}
+
+	currentInfo, err := current.Stat()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat tailed file: %w", err)
+	}
+
+	switch {
+	case !os.SameFile(pathInfo, currentInfo):
+		slog.Info("tailed file rotated, reopening", "path", t.path)
+		rotated = true
+	case pathInfo.Size() < offset:
+		slog.Info("tailed file truncated, reading from start", "path", t.path)
+	default:
+		return nil, false, nil
+	}
+
+	file, err := os.Open(t.path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reopen tailed file: %w", err)
+	}
+
+	return file, rotated, nil
+}
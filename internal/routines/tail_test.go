@@ -0,0 +1,168 @@
+package routines_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendLines(t *testing.T, path string, content string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+}
+
+func nextLine(t *testing.T, pipe pipeline.Pipe) string {
+	t.Helper()
+
+	select {
+	case msg := <-pipe.Out():
+		return msg.Data.(string)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for tailed line")
+		return ""
+	}
+}
+
+func assertNoLine(t *testing.T, pipe pipeline.Pipe, wait time.Duration) {
+	t.Helper()
+
+	select {
+	case msg := <-pipe.Out():
+		t.Fatalf("unexpected tailed line %q", msg.Data)
+	case <-time.After(wait):
+	}
+}
+
+func TestTailRoutine_Start(t *testing.T) {
+	startTail := func(t *testing.T, path string) (pipeline.Pipe, <-chan error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Tail(path).WithPollInterval(10*time.Millisecond).Start(ctx, pipe)
+		}()
+
+		return pipe, done
+	}
+
+	t.Run("emits existing and appended lines in order", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendLines(t, path, "first\nsecond\n")
+
+		pipe, _ := startTail(t, path)
+
+		assert.Equal(t, "first", nextLine(t, pipe))
+		assert.Equal(t, "second", nextLine(t, pipe))
+
+		appendLines(t, path, "third\nfou")
+		assert.Equal(t, "third", nextLine(t, pipe))
+
+		// a partial line is emitted once completed
+		appendLines(t, path, "rth\n")
+		assert.Equal(t, "fourth", nextLine(t, pipe))
+	})
+
+	t.Run("emits a line written in two chunks once", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendLines(t, path, "")
+
+		pipe, _ := startTail(t, path)
+
+		appendLines(t, path, "level=info msg=\"half")
+		assertNoLine(t, pipe, 100*time.Millisecond)
+
+		appendLines(t, path, " written\"\r\n")
+		assert.Equal(t, `level=info msg="half written"`, nextLine(t, pipe))
+		assertNoLine(t, pipe, 100*time.Millisecond)
+	})
+
+	t.Run("emits a stalled partial line after the timeout", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendLines(t, path, "")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			_ = routines.Tail(path).
+				WithPollInterval(10*time.Millisecond).
+				WithPartialLineTimeout(50*time.Millisecond).
+				Start(ctx, pipe)
+		}()
+
+		appendLines(t, path, "no newline yet")
+		assert.Equal(t, "no newline yet", nextLine(t, pipe))
+
+		// the rest of the line is emitted on its own
+		appendLines(t, path, ", now done\n")
+		assert.Equal(t, ", now done", nextLine(t, pipe))
+	})
+
+	t.Run("reads from the start after truncation", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendLines(t, path, "old line one\nold line two\n")
+
+		pipe, _ := startTail(t, path)
+		nextLine(t, pipe)
+		nextLine(t, pipe)
+
+		require.NoError(t, os.WriteFile(path, []byte("new\n"), 0644))
+
+		assert.Equal(t, "new", nextLine(t, pipe))
+	})
+
+	t.Run("reopens a rotated file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.log")
+		appendLines(t, path, "before rotation\n")
+
+		pipe, _ := startTail(t, path)
+		assert.Equal(t, "before rotation", nextLine(t, pipe))
+
+		// lines written just before rotation are still read from the old file
+		rotated := filepath.Join(dir, "app.log.1")
+		require.NoError(t, os.Rename(path, rotated))
+		appendLines(t, rotated, "last old line\n")
+		appendLines(t, path, "after rotation\n")
+
+		assert.Equal(t, "last old line", nextLine(t, pipe))
+		assert.Equal(t, "after rotation", nextLine(t, pipe))
+	})
+
+	t.Run("stops on context cancellation", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendLines(t, path, "")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		pipe := pipeline.NewChanPipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Tail(path).WithPollInterval(10*time.Millisecond).Start(ctx, pipe)
+		}()
+
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("tail did not stop")
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package routines
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// TopNRoutine counts the messages of each key and, once the input closes, emits the n
+// most frequent keys as Keyed[string, int] messages holding the key and its count, most
+// frequent first. Keys with equal counts are emitted in key order.
+//
+// Counting is exact, so a counter is held for every distinct key; only the selection of
+// the top n uses a heap bounded by n.
+type TopNRoutine struct {
+	keyFn func(pipeline.Msg) string
+	n     int
+}
+
+func TopN(keyFn func(pipeline.Msg) string, n int) *TopNRoutine {
+	return &TopNRoutine{keyFn: keyFn, n: n}
+}
+
+// Stateful marks TopNRoutine as a StatefulRoutine, since keys are counted over the whole
+// stream.
+func (t *TopNRoutine) Stateful() {}
+
+func (t *TopNRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if t.n < 1 {
+		return fmt.Errorf("top n needs a positive n, got %d", t.n)
+	}
+
+	counts := make(map[string]int)
+	for msg := range pipe.In() {
+		counts[t.keyFn(msg)]++
+	}
+
+	// a min-heap of the best n so far, whose root is the first to be displaced
+	top := &countHeap{}
+	for key, count := range counts {
+		entry := Keyed[string, int]{Key: key, Value: count}
+
+		if top.Len() < t.n {
+			heap.Push(top, entry)
+			continue
+		}
+
+		if ranksBefore(entry, (*top)[0]) {
+			(*top)[0] = entry
+			heap.Fix(top, 0)
+		}
+	}
+
+	ranked := make([]Keyed[string, int], top.Len())
+	for i := len(ranked) - 1; i >= 0; i-- {
+		ranked[i] = heap.Pop(top).(Keyed[string, int])
+	}
+
+	for _, entry := range ranked {
+		if err := pipe.Send(ctx, pipeline.Msg{ID: pipeline.NewID(ctx), Data: entry}); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// ranksBefore reports whether a is more frequent than b, or as frequent with a smaller key.
+func ranksBefore(a, b Keyed[string, int]) bool {
+	if a.Value != b.Value {
+		return a.Value > b.Value
+	}
+	return a.Key < b.Key
+}
+
+// countHeap is a heap.Interface whose root is the lowest ranked entry.
+type countHeap []Keyed[string, int]
+
+func (h countHeap) Len() int           { return len(h) }
+func (h countHeap) Less(i, j int) bool { return ranksBefore(h[j], h[i]) }
+func (h countHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *countHeap) Push(x any)        { *h = append(*h, x.(Keyed[string, int])) }
+
+func (h *countHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,62 @@
+package routines_test
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dataKey(msg pipeline.Msg) string {
+	return msg.Data.(string)
+}
+
+func TestTopNRoutine_Start(t *testing.T) {
+	t.Run("emits the most frequent keys over a skewed distribution", func(t *testing.T) {
+		// key-i appears 1000/(i+1) times, a Zipf-like tail of rare keys
+		var input []pipeline.Msg
+		for i := range 200 {
+			for range 1000 / (i + 1) {
+				input = append(input, pipeline.Msg{Data: "key-" + strconv.Itoa(i)})
+			}
+		}
+		rand.New(rand.NewPCG(1, 2)).Shuffle(len(input), func(i, j int) { input[i], input[j] = input[j], input[i] })
+
+		results := runRoutine(t, routines.TopN(dataKey, 3), input)
+
+		require.Len(t, results, 3)
+		assert.Equal(t, routines.Keyed[string, int]{Key: "key-0", Value: 1000}, results[0].Data)
+		assert.Equal(t, routines.Keyed[string, int]{Key: "key-1", Value: 500}, results[1].Data)
+		assert.Equal(t, routines.Keyed[string, int]{Key: "key-2", Value: 333}, results[2].Data)
+	})
+
+	t.Run("breaks ties by key and emits fewer when keys run out", func(t *testing.T) {
+		input := []pipeline.Msg{{Data: "b"}, {Data: "a"}, {Data: "c"}, {Data: "b"}, {Data: "a"}}
+
+		results := runRoutine(t, routines.TopN(dataKey, 5), input)
+
+		data := make([]any, len(results))
+		for i, msg := range results {
+			data[i] = msg.Data
+		}
+		assert.Equal(t, []any{
+			routines.Keyed[string, int]{Key: "a", Value: 2},
+			routines.Keyed[string, int]{Key: "b", Value: 2},
+			routines.Keyed[string, int]{Key: "c", Value: 1},
+		}, data)
+	})
+
+	t.Run("rejects a non-positive n", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.TopN(dataKey, 0).Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "positive n")
+	})
+}
@@ -0,0 +1,14 @@
+package routines
+
+import "github.com/caiorcferreira/goscript/internal/routines/filesystem"
+
+// WatchDir creates a source that ingests every file matching pattern in dir, first the
+// files already present and then each new one as it is created. It lives in the filesystem
+// package with the file codecs it parses with; see filesystem.WatchDir.
+//
+// Example:
+//
+//	script.In(routines.WatchDir("inbox", "*.csv").MoveProcessed("done"))
+func WatchDir(dir string, pattern string) *filesystem.WatchDirRoutine {
+	return filesystem.WatchDir(dir, pattern)
+}
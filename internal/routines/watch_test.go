@@ -0,0 +1,44 @@
+package routines_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a1\na2"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pipe := pipeline.NewChanPipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- routines.WatchDir(dir, "*.txt").Start(ctx, pipe)
+	}()
+
+	var lines []any
+	for len(lines) < 2 {
+		select {
+		case msg := <-pipe.Out():
+			lines = append(lines, msg.Data)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after receiving %v", lines)
+		}
+	}
+	assert.Equal(t, []any{"a1", "a2"}, lines)
+
+	cancel()
+	for range pipe.Out() {
+	}
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,87 @@
+package routines
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// WindowRoutine groups messages by wall-clock time, e.g. to aggregate log lines per
+// minute. Every interval it emits one message whose Data is the []any of the data of the
+// messages that arrived since the previous window. Windows are consecutive and aligned to
+// when the routine started, not to the first message; a window without messages emits
+// nothing. The partial window is flushed when the input closes, and when ctx is cancelled
+// as long as the next stage takes it within the cancel flush timeout, as in Batch.
+//
+// Example:
+//
+//	script.Chain(routines.Window(time.Minute)).Chain(routines.Transform(countErrors))
+type WindowRoutine struct {
+	interval    time.Duration
+	cancelFlush time.Duration
+	newTicker   tickerFunc
+}
+
+func Window(d time.Duration) *WindowRoutine {
+	return &WindowRoutine{interval: d, cancelFlush: defaultCancelFlushTimeout, newTicker: newTimeTicker}
+}
+
+// WithCancelFlush sets how long a cancelled routine waits for the next stage to take its
+// partial window; zero or less drops it. Defaults to 100ms.
+func (w *WindowRoutine) WithCancelFlush(d time.Duration) *WindowRoutine {
+	w.cancelFlush = d
+	return w
+}
+
+// Stateful marks WindowRoutine as a StatefulRoutine, since a window spans several messages.
+func (w *WindowRoutine) Stateful() {}
+
+func (w *WindowRoutine) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	if w.interval <= 0 {
+		return fmt.Errorf("window needs a positive interval, got %s", w.interval)
+	}
+
+	ticks, stop := w.newTicker(w.interval)
+	defer stop()
+
+	var window []any
+
+	flush := func(ctx context.Context) error {
+		if len(window) == 0 {
+			return nil
+		}
+
+		msg := pipeline.Msg{ID: pipeline.NewID(ctx), Data: window}
+		window = nil
+
+		return pipe.Send(ctx, msg)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if w.cancelFlush > 0 {
+				flushCtx, cancel := cancelFlushContext(ctx, w.cancelFlush)
+				defer cancel()
+
+				flush(flushCtx)
+			}
+			return nil
+		case <-ticks:
+			if err := flush(ctx); err != nil {
+				return nil
+			}
+		case msg, ok := <-pipe.In():
+			if !ok {
+				flush(ctx)
+				return nil
+			}
+
+			window = append(window, msg.Data)
+		}
+	}
+}
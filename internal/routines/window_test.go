@@ -0,0 +1,139 @@
+package routines_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowRoutine_Start(t *testing.T) {
+	// start runs a window over an unbuffered input, so every send returns once the
+	// routine has taken the message and ticks land exactly between messages
+	start := func(t *testing.T) (chan<- time.Time, *pipeline.ChannelPipe, <-chan error) {
+		t.Helper()
+
+		ticks, ticker := fakeTicker()
+
+		pipe := pipeline.NewChanPipe()
+		pipe.SetInChan(make(chan pipeline.Msg))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Window(time.Minute).WithTicker(ticker).Start(context.Background(), pipe)
+		}()
+
+		return ticks, pipe, done
+	}
+
+	t.Run("groups messages between ticks", func(t *testing.T) {
+		ticks, pipe, done := start(t)
+
+		pipe.In() <- pipeline.Msg{Data: 1}
+		pipe.In() <- pipeline.Msg{Data: 2}
+		ticks <- time.Now()
+		assert.Equal(t, []any{1, 2}, (<-pipe.Out()).Data)
+
+		pipe.In() <- pipeline.Msg{Data: 3}
+		ticks <- time.Now()
+		assert.Equal(t, []any{3}, (<-pipe.Out()).Data)
+
+		close(pipe.In())
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("emits nothing for empty windows", func(t *testing.T) {
+		ticks, pipe, done := start(t)
+
+		ticks <- time.Now()
+		ticks <- time.Now()
+		pipe.In() <- pipeline.Msg{Data: "a"}
+		ticks <- time.Now()
+		ticks <- time.Now()
+
+		close(pipe.In())
+
+		var windows []any
+		for msg := range pipe.Out() {
+			windows = append(windows, msg.Data)
+		}
+		require.NoError(t, <-done)
+
+		assert.Equal(t, []any{[]any{"a"}}, windows)
+	})
+
+	t.Run("flushes the partial window when the input closes", func(t *testing.T) {
+		ticks, pipe, done := start(t)
+
+		pipe.In() <- pipeline.Msg{Data: 1}
+		ticks <- time.Now()
+		assert.Equal(t, []any{1}, (<-pipe.Out()).Data)
+
+		pipe.In() <- pipeline.Msg{Data: 2}
+		pipe.In() <- pipeline.Msg{Data: 3}
+		close(pipe.In())
+
+		assert.Equal(t, []any{2, 3}, (<-pipe.Out()).Data)
+		_, open := <-pipe.Out()
+		assert.False(t, open)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("flushes the partial window when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, ticker := fakeTicker()
+
+		pipe := pipeline.NewChanPipe()
+		pipe.SetInChan(make(chan pipeline.Msg))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- routines.Window(time.Minute).WithTicker(ticker).Start(ctx, pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: 1}
+		pipe.In() <- pipeline.Msg{Data: 2}
+		cancel()
+
+		var windows []any
+		for msg := range pipe.Out() {
+			windows = append(windows, msg.Data)
+		}
+		require.NoError(t, <-done)
+
+		assert.Equal(t, []any{[]any{1, 2}}, windows)
+	})
+
+	t.Run("windows by wall-clock time", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		go func() {
+			_ = routines.Window(50*time.Millisecond).Start(context.Background(), pipe)
+		}()
+
+		pipe.In() <- pipeline.Msg{Data: 1}
+
+		// the window closes with the input still open
+		select {
+		case msg := <-pipe.Out():
+			assert.Equal(t, []any{1}, msg.Data)
+		case <-time.After(time.Second):
+			t.Fatal("expected the window to close after its interval")
+		}
+
+		close(pipe.In())
+	})
+	t.Run("rejects a non-positive interval", func(t *testing.T) {
+		pipe := pipeline.NewChanPipe()
+		close(pipe.In())
+
+		err := routines.Window(0).Start(context.Background(), pipe)
+
+		assert.ErrorContains(t, err, "positive interval")
+	})
+}
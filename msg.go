@@ -0,0 +1,41 @@
+package goscript
+
+import (
+	"context"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+)
+
+// Msg is a message flowing through a script: an ID, the data and optional metadata. Build
+// new messages with NewMsg and derive changed copies with WithData, WithID and WithMeta.
+type Msg = pipeline.Msg
+
+// Pipe connects a routine to its neighbours: it reads from In and sends with Send.
+type Pipe = pipeline.Pipe
+
+// Routine is a step of a script. Start reads messages from the pipe until In is closed,
+// sends its results and closes the pipe before returning.
+type Routine = pipeline.Routine
+
+// NewMsg creates a message holding data, stamped with the current time and an ID from the
+// generator set with Script.WithIDGenerator, or a random UUID. Pass the ctx given to the
+// routine's Start so the script's generator is used.
+//
+// Example:
+//
+//	func (r *Words) Start(ctx context.Context, pipe goscript.Pipe) error {
+//		defer pipe.Close()
+//
+//		for msg := range pipe.In() {
+//			for _, word := range strings.Fields(msg.Data.(string)) {
+//				if err := pipe.Send(ctx, goscript.NewMsg(ctx, word)); err != nil {
+//					return nil
+//				}
+//			}
+//		}
+//
+//		return nil
+//	}
+func NewMsg(ctx context.Context, data any) Msg {
+	return pipeline.NewMsg(pipeline.NewID(ctx), data)
+}
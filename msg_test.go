@@ -0,0 +1,45 @@
+package goscript_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caiorcferreira/goscript"
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMsg(t *testing.T) {
+	t.Run("uses the configured ID generator", func(t *testing.T) {
+		ctx := pipeline.WithIDGenerator(context.Background(), func() string { return "fixed" })
+
+		msg := goscript.NewMsg(ctx, "data")
+
+		assert.Equal(t, "fixed", msg.ID)
+		assert.Equal(t, "data", msg.Data)
+		assert.False(t, msg.IngestedAt.IsZero())
+	})
+
+	t.Run("falls back to random IDs", func(t *testing.T) {
+		first := goscript.NewMsg(context.Background(), 1)
+		second := goscript.NewMsg(context.Background(), 2)
+
+		assert.NotEmpty(t, first.ID)
+		assert.NotEqual(t, first.ID, second.ID)
+	})
+
+	t.Run("meta changes do not leak into copies", func(t *testing.T) {
+		original := goscript.NewMsg(context.Background(), "data").WithMeta("source", "a")
+		changed := original.WithMeta("source", "b").WithMeta("extra", true)
+
+		value, ok := original.MetaValue("source")
+		assert.True(t, ok)
+		assert.Equal(t, "a", value)
+
+		_, ok = original.MetaValue("extra")
+		assert.False(t, ok)
+
+		value, _ = changed.MetaValue("source")
+		assert.Equal(t, "b", value)
+	})
+}
@@ -0,0 +1,279 @@
+package goscript
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+)
+
+// FieldType is the type inferred for a field of a sampled file.
+type FieldType string
+
+const (
+	TypeNull    FieldType = "null"
+	TypeBoolean FieldType = "boolean"
+	TypeInteger FieldType = "integer"
+	TypeNumber  FieldType = "number"
+	TypeString  FieldType = "string"
+	TypeObject  FieldType = "object"
+	TypeArray   FieldType = "array"
+	// TypeMixed is a JSON field holding values of incompatible types across records.
+	TypeMixed FieldType = "mixed"
+)
+
+// Field describes one field of a Schema.
+type Field struct {
+	Name string
+	Type FieldType
+	// Nullable reports that the field was missing, null or an empty CSV cell in some record
+	Nullable bool
+}
+
+// Schema describes the shape of the records sampled from a file.
+type Schema struct {
+	// Records is how many records were sampled
+	Records int
+	// Fields holds the fields in CSV header order, or sorted by name for JSON
+	Fields []Field
+}
+
+// String lists the fields one per line, as "name: type", marking nullable ones.
+func (s Schema) String() string {
+	var b strings.Builder
+	for _, field := range s.Fields {
+		fmt.Fprintf(&b, "%s: %s", field.Name, field.Type)
+		if field.Nullable {
+			b.WriteString(" (nullable)")
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// ErrNoSchema is returned by InferSchema when a file does not hold CSV rows or JSON objects.
+var ErrNoSchema = errors.New("records have no fields to infer a schema from")
+
+// InferSchema reads up to sample records of the file at path and infers the name and type
+// of their fields, to help pick codecs and coercions before building a pipeline. The file is
+// read with the codec registered for its extension: CSV files use their first row as the
+// header and have their cells' types inferred from the text, while JSON lines files must
+// hold objects and JSON files an array of objects. The array is streamed, so only the
+// sampled elements are decoded.
+//
+// Parameters:
+//   - ctx: Context for execution control and cancellation
+//   - path: The CSV, JSON or JSON lines file to sample
+//   - sample: The maximum number of records to read, not counting a CSV header
+//
+// Returns:
+//   - Schema: The inferred fields and how many records were sampled
+//   - error: ErrNoSchema if the records are neither CSV rows nor JSON objects, or any read error
+//
+// Example:
+//
+//	schema, err := goscript.InferSchema(ctx, "orders.csv", 100)
+//	fmt.Print(schema)
+func InferSchema(ctx context.Context, path string, sample int) (Schema, error) {
+	if sample < 1 {
+		return Schema{}, fmt.Errorf("schema sample must be positive, got %d", sample)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pipe := pipeline.NewChanPipe()
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- sampleReader(path).Start(ctx, pipe)
+	}()
+
+	inferrer := newSchemaInferrer()
+
+	var err error
+	for msg := range pipe.Out() {
+		if err = inferrer.add(msg.Data); err != nil || inferrer.records == sample {
+			break
+		}
+	}
+
+	// stop the reader once the sample is taken, and let it finish
+	cancel()
+	for range pipe.Out() {
+	}
+
+	if err != nil {
+		return Schema{}, err
+	}
+	// a reader stopped after a full sample may report its cancellation
+	if err := <-readErr; err != nil && inferrer.records < sample {
+		return Schema{}, err
+	}
+
+	return inferrer.schema(), nil
+}
+
+// sampleReader reads the records of path with the codec for its extension, except that a
+// JSON file is streamed element by element rather than decoded whole.
+func sampleReader(path string) *filesystem.ReadFileRoutine {
+	read := filesystem.File(path).Read()
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		read = read.WithCodec(filesystem.NewJSONCodec().WithJSONArrayMode())
+	}
+
+	return read
+}
+
+// schemaInferrer merges the fields of sampled records.
+type schemaInferrer struct {
+	header  []string
+	records int
+	fields  map[string]*Field
+	// seen counts in how many records each field was present
+	seen map[string]int
+}
+
+func newSchemaInferrer() *schemaInferrer {
+	return &schemaInferrer{fields: make(map[string]*Field), seen: make(map[string]int)}
+}
+
+func (s *schemaInferrer) add(data any) error {
+	switch record := data.(type) {
+	case []string:
+		if s.header == nil {
+			s.header = record
+			return nil
+		}
+
+		s.records++
+		for i, name := range s.header {
+			var cell string
+			if i < len(record) {
+				cell = record[i]
+			}
+			s.observe(name, textType(cell), true)
+		}
+	case map[string]any:
+		s.records++
+		for name, value := range record {
+			s.observe(name, jsonType(value), false)
+		}
+	default:
+		return fmt.Errorf("%w: record %d is a %T", ErrNoSchema, s.records+1, data)
+	}
+
+	return nil
+}
+
+func (s *schemaInferrer) observe(name string, typ FieldType, textual bool) {
+	s.seen[name]++
+
+	field, ok := s.fields[name]
+	if !ok {
+		s.fields[name] = &Field{Name: name, Type: typ, Nullable: typ == TypeNull}
+		return
+	}
+
+	if typ == TypeNull {
+		field.Nullable = true
+		return
+	}
+
+	field.Type = mergeTypes(field.Type, typ, textual)
+}
+
+func (s *schemaInferrer) schema() Schema {
+	names := s.header
+	if names == nil {
+		names = make([]string, 0, len(s.fields))
+		for name := range s.fields {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+
+	schema := Schema{Records: s.records}
+	for _, name := range names {
+		field, ok := s.fields[name]
+		if !ok {
+			// a CSV header without data rows
+			field = &Field{Name: name, Type: TypeNull, Nullable: true}
+		}
+		if s.seen[name] < s.records {
+			field.Nullable = true
+		}
+
+		schema.Fields = append(schema.Fields, *field)
+	}
+
+	return schema
+}
+
+// mergeTypes combines the types a field had in two records. Integers widen to numbers;
+// other conflicts become strings for CSV text and TypeMixed for JSON.
+func mergeTypes(a, b FieldType, textual bool) FieldType {
+	switch {
+	case a == b:
+		return a
+	case a == TypeNull:
+		return b
+	case b == TypeNull:
+		return a
+	case (a == TypeInteger && b == TypeNumber) || (a == TypeNumber && b == TypeInteger):
+		return TypeNumber
+	case textual:
+		return TypeString
+	default:
+		return TypeMixed
+	}
+}
+
+// textType infers the type of a CSV cell from its text.
+func textType(cell string) FieldType {
+	cell = strings.TrimSpace(cell)
+
+	if cell == "" {
+		return TypeNull
+	}
+	if _, err := strconv.ParseInt(cell, 10, 64); err == nil {
+		return TypeInteger
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return TypeNumber
+	}
+	if strings.EqualFold(cell, "true") || strings.EqualFold(cell, "false") {
+		return TypeBoolean
+	}
+
+	return TypeString
+}
+
+// jsonType infers the type of a decoded JSON value.
+func jsonType(value any) FieldType {
+	switch v := value.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case float64:
+		if v == float64(int64(v)) {
+			return TypeInteger
+		}
+		return TypeNumber
+	case string:
+		return TypeString
+	case map[string]any:
+		return TypeObject
+	case []any:
+		return TypeArray
+	default:
+		return TypeMixed
+	}
+}
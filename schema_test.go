@@ -0,0 +1,101 @@
+package goscript_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caiorcferreira/goscript"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSample(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func TestInferSchema(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("infers CSV columns from their text", func(t *testing.T) {
+		path := writeSample(t, "orders.csv", strings.Join([]string{
+			"id,customer,total,paid,note",
+			"1,ana,10,true,",
+			"2,bob,12.5,false,rush",
+			"3,carol,7,TRUE,",
+		}, "\n"))
+
+		schema, err := goscript.InferSchema(ctx, path, 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, schema.Records)
+		assert.Equal(t, []goscript.Field{
+			{Name: "id", Type: goscript.TypeInteger},
+			{Name: "customer", Type: goscript.TypeString},
+			{Name: "total", Type: goscript.TypeNumber},
+			{Name: "paid", Type: goscript.TypeBoolean},
+			{Name: "note", Type: goscript.TypeString, Nullable: true},
+		}, schema.Fields)
+		assert.Equal(t, "id: integer\ncustomer: string\ntotal: number\npaid: boolean\nnote: string (nullable)\n", schema.String())
+	})
+
+	t.Run("infers JSON lines fields", func(t *testing.T) {
+		path := writeSample(t, "events.jsonl", strings.Join([]string{
+			`{"id": 1, "user": {"name": "ana"}, "tags": ["a"], "score": 1}`,
+			`{"id": 2, "user": null, "tags": [], "score": 0.5, "extra": "x"}`,
+			`{"id": 3, "user": {"name": "bob"}, "tags": [], "score": "high"}`,
+		}, "\n"))
+
+		schema, err := goscript.InferSchema(ctx, path, 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, schema.Records)
+		assert.Equal(t, []goscript.Field{
+			{Name: "extra", Type: goscript.TypeString, Nullable: true},
+			{Name: "id", Type: goscript.TypeInteger},
+			{Name: "score", Type: goscript.TypeMixed},
+			{Name: "tags", Type: goscript.TypeArray},
+			{Name: "user", Type: goscript.TypeObject, Nullable: true},
+		}, schema.Fields)
+	})
+
+	t.Run("reads at most sample records", func(t *testing.T) {
+		lines := []string{`{"n": 1}`, `{"n": 2}`, `{"n": "three"}`}
+		path := writeSample(t, "sample.jsonl", strings.Join(lines, "\n"))
+
+		schema, err := goscript.InferSchema(ctx, path, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, schema.Records)
+		assert.Equal(t, []goscript.Field{{Name: "n", Type: goscript.TypeInteger}}, schema.Fields)
+	})
+
+	t.Run("streams the elements of a JSON array", func(t *testing.T) {
+		// the array is cut short after the sample, so decoding it whole would fail
+		path := writeSample(t, "orders.json", `[{"id": 1, "total": 10}, {"id": 2, "total": 12.5}, {"id": 3, "tot`)
+
+		schema, err := goscript.InferSchema(ctx, path, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, schema.Records)
+		assert.Equal(t, []goscript.Field{
+			{Name: "id", Type: goscript.TypeInteger},
+			{Name: "total", Type: goscript.TypeNumber},
+		}, schema.Fields)
+	})
+
+	t.Run("rejects records without fields", func(t *testing.T) {
+		path := writeSample(t, "notes.txt", "just\nlines")
+
+		_, err := goscript.InferSchema(ctx, path, 10)
+
+		assert.ErrorIs(t, err, goscript.ErrNoSchema)
+	})
+}
@@ -2,8 +2,12 @@ package goscript
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/caiorcferreira/goscript/internal/pipeline"
@@ -24,10 +28,42 @@ type Script struct {
 	outPipe       pipeline.Pipe
 	outputRoutine pipeline.Routine
 
-	hasPipeline bool
-	pipeline    *pipeline.Pipeline
+	hasPipeline  bool
+	pipeline     *pipeline.Pipeline
+	pipelinePipe pipeline.Pipe
+	inspectMu    sync.Mutex
+
+	timeout      time.Duration
+	maxDuration  time.Duration
+	drainTimeout time.Duration
+	onPanic      func(recovered any, stack []byte)
+	idGenerator  pipeline.IDGenerator
+	errorPolicy  pipeline.ErrorPolicy
+
+	countMessages bool
+
+	requireOrdered bool
+	// unorderedStages holds the indexes of stages allowed to break ordering
+	unorderedStages map[int]bool
 }
 
+// ErrTimeout is returned when a script does not finish before its deadline.
+var ErrTimeout = errors.New("script timed out")
+
+// ErrMaxDurationExceeded is returned when a script runs past its WithMaxDuration limit.
+var ErrMaxDurationExceeded = errors.New("script exceeded maximum duration")
+
+// shutdownGrace bounds how long Run waits for routines to return once the maximum
+// duration has passed, so a routine ignoring cancellation cannot block Run forever.
+const shutdownGrace = 5 * time.Second
+
+// ErrUnorderedStage is returned by Run when ordered output is required and a stage may
+// reorder messages.
+var ErrUnorderedStage = errors.New("stage may reorder messages")
+
+// ErrPanic is returned by Run when a routine panicked and OnPanic is set.
+var ErrPanic = errors.New("routine panicked")
+
 // New creates a new Script instance with default input (stdin) and output (stdout) routines.
 // The returned Script is ready to be configured with additional routines and executed.
 //
@@ -106,7 +142,8 @@ func (s *Script) Chain(routine pipeline.Routine) *Script {
 }
 
 // FileIn configures the script to read input from a file, processing it line by line.
-// Each line is treated as a separate data item in the pipeline.
+// Each line is treated as a separate data item in the pipeline. Line endings are recorded
+// in the message meta, so FileOut writes each line back with its original ending.
 //
 // Parameters:
 //   - path: The file path to read from
@@ -122,6 +159,7 @@ func (s *Script) FileIn(path string) *Script {
 }
 
 // FileOut configures the script to write output to a file, with each data item written as a separate line.
+// Lines read by FileIn keep their original ending, so FileIn(x).FileOut(y) copies x byte for byte.
 //
 // Parameters:
 //   - path: The file path to write to
@@ -185,7 +223,8 @@ func (s *Script) CSVIn(path string) *Script {
 }
 
 // CSVOut configures the script to write output to a CSV file.
-// Each data item is formatted as a CSV row.
+// Each data item is formatted as a CSV row. A static path is opened once, replacing any
+// existing content, and every row is streamed into it.
 //
 // Parameters:
 //   - path: The CSV file path to write to
@@ -232,6 +271,54 @@ func (s *Script) BlobFileOut(path string) *Script {
 	return s
 }
 
+// ReflowJSON configures the script to convert between a JSON array file and a JSON lines
+// file. With toLines, the elements of the array at inPath, pretty-printed or not, are
+// written compacted one per line to outPath; otherwise each line of inPath becomes an
+// element of a compact array written to outPath.
+//
+// Both directions stream, holding a single document in memory at a time, and documents
+// are copied as raw JSON, so key order and number precision are preserved.
+//
+// Parameters:
+//   - inPath: The JSON array or JSON lines file to read from
+//   - outPath: The JSON lines or JSON array file to write to
+//   - toLines: Whether to convert an array to lines rather than lines to an array
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	script.New().ReflowJSON("export.json", "export.jsonl", true).Run(ctx)
+func (s *Script) ReflowJSON(inPath, outPath string, toLines bool) *Script {
+	readCodec := filesystem.NewJSONCodec().WithJSONLinesMode()
+	writeCodec := filesystem.NewJSONWriteCodec().WithJSONArrayMode()
+	if toLines {
+		readCodec = filesystem.NewJSONCodec().WithJSONArrayMode()
+		writeCodec = filesystem.NewJSONWriteCodec().WithJSONLinesMode()
+	}
+
+	s.In(filesystem.File(inPath).Read().WithCodec(filesystem.WithJSONType[json.RawMessage](readCodec)))
+	s.Out(filesystem.File(outPath).Write().WithCodec(writeCodec))
+	return s
+}
+
+// WithBufferSize sets how many messages each pipeline stage may queue on its input.
+// Deeper buffers let multi-stage pipelines overlap more work when stage speeds vary.
+//
+// Parameters:
+//   - size: Input buffer size per stage; the default is one message
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	script.WithBufferSize(64).Chain(parse).Chain(enrich).Chain(format).Run(ctx)
+func (s *Script) WithBufferSize(size int) *Script {
+	s.pipeline.WithBufferSize(size)
+
+	return s
+}
+
 // Parallel adds a routine to the pipeline that will process data items concurrently.
 // The routine will be executed in parallel up to the specified maximum concurrency limit.
 //
@@ -250,6 +337,24 @@ func (s *Script) Parallel(r pipeline.Routine, maxConcurrency int) *Script {
 	return s
 }
 
+// OrderedParallel is like Parallel but emits results in input order. The routine must emit
+// exactly one message per input, as a transform does.
+//
+// Parameters:
+//   - r: The routine to execute in parallel
+//   - maxConcurrency: Maximum number of concurrent executions of the routine
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	script.FileIn("input.txt").OrderedParallel(expensiveTransform, 4).FileOut("output.txt").Run(ctx)
+func (s *Script) OrderedParallel(r pipeline.Routine, maxConcurrency int) *Script {
+	s.Chain(routines.Parallel(r, maxConcurrency).Ordered())
+
+	return s
+}
+
 // Debounce adds a debouncing mechanism to the pipeline that delays processing until
 // no new data has been received for the specified duration. This is useful for
 // batch processing or reducing noise from rapidly changing data.
@@ -268,6 +373,212 @@ func (s *Script) Debounce(delay time.Duration) *Script {
 	return s
 }
 
+// RequireOrdered states that the output must keep the input order end to end. Run fails
+// with ErrUnorderedStage before starting if the input or any stage may reorder messages,
+// such as a Glob reading files concurrently or a stage added with Parallel rather than
+// OrderedParallel. Stages are never switched to an ordered variant, since that only works
+// for routines emitting exactly one message per input. Stages chained with ChainUnordered
+// are exempt.
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	script.FileIn("input.txt").OrderedParallel(enrich, 8).RequireOrdered().FileOut("output.txt").Run(ctx)
+func (s *Script) RequireOrdered() *Script {
+	s.requireOrdered = true
+
+	return s
+}
+
+// ChainUnordered adds a routine to the pipeline like Chain, allowing it to reorder messages
+// even when RequireOrdered is set, e.g. a stage whose output order does not matter.
+//
+// Parameters:
+//   - routine: The processing routine to add to the pipeline
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	script.RequireOrdered().ChainUnordered(routines.Parallel(notify, 4))
+func (s *Script) ChainUnordered(routine pipeline.Routine) *Script {
+	s.Chain(routine)
+
+	if s.unorderedStages == nil {
+		s.unorderedStages = make(map[int]bool)
+	}
+	s.unorderedStages[len(s.pipeline.Routines())-1] = true
+
+	return s
+}
+
+// enforceOrder rejects an input or stage that may reorder messages, when ordered output
+// is required.
+func (s *Script) enforceOrder() error {
+	if !s.requireOrdered {
+		return nil
+	}
+
+	if !preservesOrder(s.inputRoutine) {
+		return fmt.Errorf("%w: input (%T) is not ordered", ErrUnorderedStage, s.inputRoutine)
+	}
+
+	for i, routine := range s.pipeline.Routines() {
+		if preservesOrder(routine) || s.unorderedStages[i] {
+			continue
+		}
+
+		return fmt.Errorf("%w: stage %d (%T) is not ordered; make it ordered or chain it with ChainUnordered", ErrUnorderedStage, i+1, routine)
+	}
+
+	return nil
+}
+
+// preservesOrder reports whether r keeps the order of its messages. Routines that do not
+// implement routines.OrderAware are assumed to.
+func preservesOrder(r pipeline.Routine) bool {
+	aware, ok := r.(routines.OrderAware)
+
+	return !ok || aware.PreservesOrder()
+}
+
+// OnPanic installs a handler for panics escaping any routine of the script: input, output
+// or pipeline stage, or a goroutine one of them started, such as a Parallel worker. The
+// handler is called for one panic at a time. After it runs the script shuts down,
+// cancelling every routine, and Run returns an error wrapping ErrPanic. Without a handler a
+// panic crashes the process. With one, Run waits for the routines to return before
+// reporting success, so a routine ignoring cancellation delays it by up to 5 seconds.
+//
+// Parameters:
+//   - handler: Called with the recovered value and the stack of the panicking goroutine
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	script.OnPanic(func(recovered any, stack []byte) {
+//		slog.Error("script crashed", "panic", recovered, "stack", string(stack))
+//	})
+func (s *Script) OnPanic(handler func(recovered any, stack []byte)) *Script {
+	s.onPanic = handler
+
+	return s
+}
+
+// WithTimeout bounds how long the script may run. Run and the terminal helpers such as
+// ToString return an error wrapping ErrTimeout if the pipeline has not finished by then,
+// instead of blocking on a source that never closes.
+//
+// Parameters:
+//   - timeout: Maximum run duration, or zero for no limit
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	result, err := script.FileIn("input.txt").WithTimeout(30*time.Second).ToString(ctx)
+func (s *Script) WithTimeout(timeout time.Duration) *Script {
+	s.timeout = timeout
+
+	return s
+}
+
+// WithIDGenerator sets how routines of the script create message IDs, replacing the random
+// UUIDs used by default. The generator is shared by every routine, parallel workers included.
+//
+// Parameters:
+//   - gen: Generator safe for concurrent use, such as pipeline.SequentialIDs()
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	script.FileIn("input.txt").WithIDGenerator(pipeline.SequentialIDs()).Run(ctx)
+func (s *Script) WithIDGenerator(gen pipeline.IDGenerator) *Script {
+	s.idGenerator = gen
+
+	return s
+}
+
+// WithErrorPolicy sets what happens when a routine returns an error. By default errors are
+// logged and the other routines keep running; with pipeline.FailFast the first error of
+// any routine, input and output included, cancels the script and is returned by Run.
+//
+// Parameters:
+//   - policy: pipeline.ContinueOnError or pipeline.FailFast
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	err := script.FileIn("input.txt").Chain(validate).WithErrorPolicy(pipeline.FailFast).Run(ctx)
+func (s *Script) WithErrorPolicy(policy pipeline.ErrorPolicy) *Script {
+	s.errorPolicy = policy
+	s.pipeline.WithErrorPolicy(policy)
+
+	return s
+}
+
+// WithMessageCounts makes every pipe of the script count the messages crossing it, reported
+// by Inspect. Each counted pipe relays its messages through an extra goroutine, so it is
+// meant for diagnosing stalls rather than production runs.
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	script := goscript.New().Chain(slowStage).WithMessageCounts()
+func (s *Script) WithMessageCounts() *Script {
+	s.countMessages = true
+	s.pipeline.WithMessageCounts()
+
+	return s
+}
+
+// WithMaxDuration sets a hard wall-clock limit for batch jobs. Once it passes, every routine
+// is cancelled and Run waits for them to return before failing with ErrMaxDurationExceeded,
+// so no routine is left writing after Run returns. Unlike WithTimeout, the error is set
+// apart from deadlines of the caller's context.
+//
+// Parameters:
+//   - d: Maximum run duration, or zero for no limit
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	err := script.FileIn("input.txt").WithMaxDuration(time.Hour).FileOut("output.txt").Run(ctx)
+//	if errors.Is(err, goscript.ErrMaxDurationExceeded) {
+//		// the job ran too long
+//	}
+func (s *Script) WithMaxDuration(d time.Duration) *Script {
+	s.maxDuration = d
+
+	return s
+}
+
+// WithDrainTimeout lets a cancelled script finish the messages already read before it
+// stops. Once ctx is cancelled or the timeout passes, the input stops reading while the
+// chained routines and the output keep running for up to d to drain what is in flight,
+// after which they are cancelled too. Run still returns the cancellation error. Failures,
+// panics and WithMaxDuration stop every routine at once.
+//
+// Parameters:
+//   - d: Longest time to wait for in-flight messages, or zero to cancel every routine at once
+//
+// Returns the Script instance for method chaining.
+//
+// Example:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	err := script.In(source).Chain(enrich).WithDrainTimeout(10 * time.Second).FileOut("out.jsonl").Run(ctx)
+func (s *Script) WithDrainTimeout(d time.Duration) *Script {
+	s.drainTimeout = d
+
+	return s
+}
+
 // ToString executes the script and returns all output as a concatenated string.
 // This is a convenience method that replaces the output routine with a string accumulator
 // and runs the script to completion.
@@ -305,6 +616,105 @@ func (s *Script) ToString(ctx context.Context) (string, error) {
 	return str, nil
 }
 
+// Count executes the script and returns the number of messages that reached the end of
+// the pipeline. Like ToString, it replaces the output routine, here with a counting sink.
+//
+// Parameters:
+//   - ctx: Context for execution control and cancellation
+//
+// Returns:
+//   - int: Number of messages produced by the pipeline
+//   - error: Any error that occurred during execution
+//
+// Example:
+//
+//	n, err := script.FileIn("access.log").Chain(onlyErrors).Count(ctx)
+func (s *Script) Count(ctx context.Context) (int, error) {
+	s.outputRoutine = routines.Reduce(
+		func(count int, _ any) int {
+			return count + 1
+		},
+		0,
+	)
+
+	err := s.Run(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	result := <-s.outPipe.Out()
+
+	count, ok := result.Data.(int)
+	if !ok {
+		return 0, fmt.Errorf("failed to convert result to count: %v", result.Data)
+	}
+
+	return count, nil
+}
+
+// Inspect returns a snapshot of every pipe of the script in flow order: the input link,
+// each pipeline stage and the output. Message counts are only tracked with
+// WithMessageCounts; buffer depth and closed state are always reported. Call it while Run is in progress to find a stalled stage.
+//
+// Example:
+//
+//	for _, stats := range script.Inspect() {
+//		slog.Info("pipe", "name", stats.Name, "buffered", stats.Buffered, "closed", stats.Closed)
+//	}
+func (s *Script) Inspect() []pipeline.PipeStats {
+	var stats []pipeline.PipeStats
+
+	add := func(name string, pipe pipeline.Pipe) {
+		inspectable, ok := pipe.(interface{ Stats() pipeline.PipeStats })
+		if !ok {
+			return
+		}
+
+		pipeStats := inspectable.Stats()
+		pipeStats.Name = name
+		stats = append(stats, pipeStats)
+	}
+
+	add("input", s.inPipe)
+
+	s.inspectMu.Lock()
+	pipelinePipe := s.pipelinePipe
+	s.inspectMu.Unlock()
+
+	if pipelinePipe != nil {
+		stats = append(stats, s.pipeline.Stats()...)
+		add("pipeline", pipelinePipe)
+	}
+
+	add("output", s.outPipe)
+
+	return stats
+}
+
+// Describe returns the stages of the script in flow order, one per line: the input, each
+// chained stage, numbered from 1 as in stage errors, and the output. Routines implementing
+// pipeline.Describer, such as file readers and writers or Parallel, describe their
+// settings; others are named by type. Use it to document or check a script built
+// programmatically.
+//
+// Example:
+//
+//	fmt.Print(goscript.New().FileIn("in.txt").Parallel(enrich, 4).FileOut("out.txt").Describe())
+//	// input: read file "in.txt" with LineCodec
+//	// stage 1: parallel x4 unordered: *main.Enrich
+//	// output: write file "out.txt" with LineCodec
+func (s *Script) Describe() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "input: %s\n", pipeline.Describe(s.inputRoutine))
+	for i, routine := range s.pipeline.Routines() {
+		fmt.Fprintf(&b, "stage %d: %s\n", i+1, pipeline.Describe(routine))
+	}
+	fmt.Fprintf(&b, "output: %s\n", pipeline.Describe(s.outputRoutine))
+
+	return b.String()
+}
+
 // Run executes the configured script pipeline. This method starts all routines in the
 // proper order (output → middlewares → input) and manages their lifecycle through
 // goroutines. The execution follows the concurrency model where only routines that
@@ -320,49 +730,347 @@ func (s *Script) ToString(ctx context.Context) (string, error) {
 //   - ctx: Context for execution control and cancellation
 //
 // Returns:
-//   - error: Any error that occurred during execution (currently always returns nil)
+//   - error: ErrMaxDurationExceeded if the maximum duration passed, ErrTimeout if the script
+//     deadline passed before the pipeline finished, ErrPanic if a routine panicked with
+//     OnPanic set, ErrUnorderedStage if ordered output is required but a stage may
+//     reorder messages, or the context error if ctx was cancelled first
 //
 // Example:
 //
 //	err := script.FileIn("input.txt").Chain(processData).FileOut("output.txt").Run(ctx)
 func (s *Script) Run(ctx context.Context) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	if err := s.enforceOrder(); err != nil {
+		return err
+	}
+
+	ctx, cancelLimits := s.limitContext(ctx)
+	defer cancelLimits()
+
+	r := s.newRun(ctx)
+	defer r.stopAll()
+
+	r.start()
+
+	return r.wait()
+}
+
+// limitContext applies the maximum duration, the timeout and the ID generator of the script
+// to ctx.
+func (s *Script) limitContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	cancelMaxDuration, cancelTimeout := context.CancelFunc(func() {}), context.CancelFunc(func() {})
+
+	if s.maxDuration > 0 {
+		ctx, cancelMaxDuration = context.WithTimeoutCause(ctx, s.maxDuration, ErrMaxDurationExceeded)
+	}
+
+	if s.timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, s.timeout)
+	}
+
+	if s.idGenerator != nil {
+		ctx = pipeline.WithIDGenerator(ctx, s.idGenerator)
+	}
+
+	return ctx, func() {
+		cancelTimeout()
+		cancelMaxDuration()
+	}
+}
+
+// scriptRun holds the state shared by the goroutines of a single Run.
+type scriptRun struct {
+	script *Script
+
+	// ctx runs the input; stageCtx runs the pipeline and the output, which may outlive ctx
+	// to drain
+	ctx        context.Context
+	cancel     context.CancelFunc
+	stageCtx   context.Context
+	stopStages context.CancelFunc
+
+	// panicked receives the first recovered panic, failed the first routine error under the
+	// fail-fast policy
+	panicked chan error
+	failed   chan error
+	panicMu  sync.Mutex
+
+	// running tracks the routine goroutines, awaited when the maximum duration passes
+	running sync.WaitGroup
+	// pipelineDone is closed once the pipeline has returned, or right away without one
+	pipelineDone chan struct{}
+	// waitRecovered waits for every goroutine recovering through the context, see
+	// pipeline.Guard
+	waitRecovered func()
+}
+
+func (s *Script) newRun(ctx context.Context) *scriptRun {
+	r := &scriptRun{
+		script:        s,
+		panicked:      make(chan error, 1),
+		failed:        make(chan error, 1),
+		pipelineDone:  make(chan struct{}),
+		waitRecovered: func() {},
+	}
+
+	if s.onPanic != nil {
+		ctx, r.waitRecovered = pipeline.WithPanicHandler(ctx, r.recoverPanic)
+	}
+
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	r.stageCtx, r.stopStages = s.drainContext(r.ctx)
+
+	return r
+}
+
+// stopAll cancels every routine, without draining.
+func (r *scriptRun) stopAll() {
+	r.cancel()
+	r.stopStages()
+}
+
+// recoverPanic reports a panic of the calling goroutine and shuts the script down; the
+// handler is called by one goroutine at a time, as several may panic together.
+func (r *scriptRun) recoverPanic(recovered any, stack []byte) {
+	r.panicMu.Lock()
+	r.script.onPanic(recovered, stack)
+	r.panicMu.Unlock()
+
+	select {
+	case r.panicked <- fmt.Errorf("%w: %v", ErrPanic, recovered):
+	default:
+	}
+
+	r.stopAll()
+}
+
+// fail records the first routine error and shuts the script down.
+func (r *scriptRun) fail(err error) {
+	select {
+	case r.failed <- err:
+	default:
+	}
+
+	r.stopAll()
+}
+
+// start launches the routines in reverse order: output, pipeline, input.
+func (r *scriptRun) start() {
+	s := r.script
+
+	if counted, ok := s.inPipe.(interface{ CountMessages(context.Context) }); ok && s.countMessages {
+		// the relay feeds the stage after the input, so it lives as long as that stage
+		counted.CountMessages(r.stageCtx)
+	}
 
 	if s.hasPipeline {
-		slog.Debug("Starting pipeline...")
+		r.startPipeline()
+	} else {
+		s.inPipe.Chain(s.outPipe)
+		close(r.pipelineDone)
+	}
+
+	r.startRoutine(r.stageCtx, "output", s.outputRoutine, s.outPipe)
+	r.startRoutine(r.ctx, "input", s.inputRoutine, s.inPipe)
+}
 
-		pipelinePipe := pipeline.NewChanPipe()
+func (r *scriptRun) startPipeline() {
+	s := r.script
 
-		s.inPipe.Chain(pipelinePipe)
-		pipelinePipe.Chain(s.outPipe)
+	slog.Debug("Starting pipeline...")
 
-		go func() {
-			err := s.pipeline.Start(ctx, pipelinePipe)
-			if err != nil {
-				slog.Error("pipeline routine error", "error", err)
-			}
-		}()
+	pipelinePipe := pipeline.NewChanPipe()
+	if s.countMessages {
+		pipelinePipe.CountMessages(r.stageCtx)
 	}
 
-	// start routines in reverse order: output, middlewares, input
-	go func() {
-		err := s.outputRoutine.Start(ctx, s.outPipe)
-		if err != nil {
-			slog.Error("output routine error", "error", err)
+	s.inPipe.Chain(pipelinePipe)
+	pipelinePipe.Chain(s.outPipe)
+
+	s.inspectMu.Lock()
+	s.pipelinePipe = pipelinePipe
+	s.inspectMu.Unlock()
+
+	r.running.Add(1)
+	pipeline.Go(r.stageCtx, func() {
+		defer r.running.Done()
+		defer close(r.pipelineDone)
+
+		// the pipeline only returns stage errors under the fail-fast policy
+		if err := s.pipeline.Start(r.stageCtx, pipelinePipe); err != nil {
+			r.fail(err)
 		}
-	}()
+	})
+}
+
+func (r *scriptRun) startRoutine(ctx context.Context, name string, routine pipeline.Routine, pipe pipeline.Pipe) {
+	r.running.Add(1)
+	pipeline.Go(ctx, func() {
+		defer r.running.Done()
+
+		if err := routine.Start(ctx, pipe); err != nil {
+			r.script.routineFailed(r.fail, name, err)
+		}
+	})
+}
+
+// wait waits for the output routine to finish, or gives up once ctx ends; all routines
+// should exit when the context is cancelled.
+func (r *scriptRun) wait() error {
+	select {
+	case <-r.script.outPipe.Done():
+		return r.outputDone()
+	case err := <-r.panicked:
+		return err
+	case err := <-r.failed:
+		return err
+	case <-r.ctx.Done():
+		return r.cancelled()
+	}
+}
+
+// outputDone settles the run once the output routine has finished.
+func (r *scriptRun) outputDone() error {
+	s := r.script
+
+	// routines that stop on cancellation close their pipes, so the output may also finish
+	// because the maximum duration passed
+	if errors.Is(context.Cause(r.ctx), ErrMaxDurationExceeded) {
+		return s.maxDurationExceeded(&r.running)
+	}
+
+	// a panicking routine closes its pipes before the panic is reported, so wait for every
+	// routine goroutine to return, stopping what is left of them
+	if s.onPanic != nil {
+		r.stopAll()
+		waitWithGrace(r.waitRecovered)
+
+		select {
+		case err := <-r.panicked:
+			return err
+		default:
+		}
+	}
+
+	// a failing stage closes the pipeline output before the pipeline returns its error, so
+	// wait for the pipeline to settle, stopping what is left of it
+	if s.errorPolicy == pipeline.FailFast {
+		r.stopAll()
+		<-r.pipelineDone
+
+		select {
+		case err := <-r.failed:
+			return err
+		default:
+		}
+	}
+
+	return nil
+}
+
+// cancelled settles the run once ctx has ended before the output finished, draining
+// in-flight messages when a drain timeout is set.
+func (r *scriptRun) cancelled() error {
+	s := r.script
+
+	if errors.Is(context.Cause(r.ctx), ErrMaxDurationExceeded) {
+		return s.maxDurationExceeded(&r.running)
+	}
+
+	if s.drainTimeout > 0 {
+		// the input has stopped, wait for the rest to finish what it holds
+		select {
+		case err := <-r.panicked:
+			return err
+		case err := <-r.failed:
+			return err
+		case <-s.outPipe.Done():
+		case <-r.stageCtx.Done():
+			slog.Warn("in-flight messages did not drain before the drain timeout", "timeout", s.drainTimeout)
+		}
+	} else {
+		select {
+		case err := <-r.panicked:
+			return err
+		case err := <-r.failed:
+			return err
+		case <-s.outPipe.Done():
+			return nil
+		default:
+		}
+	}
+
+	if errors.Is(r.ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, r.ctx.Err())
+	}
+
+	return r.ctx.Err()
+}
+
+// drainContext returns the context for the routines after the input. Without a drain
+// timeout it is ctx itself; otherwise it is cancelled drainTimeout after ctx ends, or right
+// away when the maximum duration passed, or by the returned stop function.
+func (s *Script) drainContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.drainTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	stageCtx, stopStages := context.WithCancel(context.WithoutCancel(ctx))
 
 	go func() {
-		err := s.inputRoutine.Start(ctx, s.inPipe)
-		if err != nil {
-			slog.Error("input routine error", "error", err)
+		select {
+		case <-stageCtx.Done():
+			return
+		case <-ctx.Done():
+		}
+
+		if errors.Is(context.Cause(ctx), ErrMaxDurationExceeded) {
+			stopStages()
+			return
+		}
+
+		select {
+		case <-stageCtx.Done():
+		case <-time.After(s.drainTimeout):
+			stopStages()
 		}
 	}()
 
-	// wait for input routine to finish
-	<-s.outPipe.Done()
+	return stageCtx, stopStages
+}
 
-	// all routines should exit when context is cancelled
-	return nil
+// routineFailed logs a routine error, or fails the script with it under the fail-fast policy.
+func (s *Script) routineFailed(fail func(error), name string, err error) {
+	if s.errorPolicy == pipeline.FailFast {
+		fail(fmt.Errorf("%s routine failed: %w", name, err))
+		return
+	}
+
+	slog.Error(name+" routine error", "error", err)
+}
+
+// maxDurationExceeded waits for the cancelled routines to return, up to shutdownGrace,
+// and reports the exceeded limit.
+func (s *Script) maxDurationExceeded(running *sync.WaitGroup) error {
+	if !waitWithGrace(running.Wait) {
+		slog.Warn("routines did not stop after maximum duration", "grace", shutdownGrace)
+	}
+
+	return fmt.Errorf("%w after %s", ErrMaxDurationExceeded, s.maxDuration)
+}
+
+// waitWithGrace calls wait, giving up after shutdownGrace so a routine ignoring
+// cancellation cannot block Run forever. It reports whether wait returned in time.
+func waitWithGrace(wait func()) bool {
+	stopped := make(chan struct{})
+	go func() {
+		wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return true
+	case <-time.After(shutdownGrace):
+		return false
+	}
 }
@@ -0,0 +1,840 @@
+package goscript_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript"
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScript_ReduceToFile(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "numbers.txt")
+	output := filepath.Join(dir, "sum.txt")
+
+	lines := make([]string, 0, 100)
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	require.NoError(t, os.WriteFile(input, []byte(strings.Join(lines, "\n")), 0644))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := goscript.New().
+		FileIn(input).
+		Chain(routines.Transform(func(s string) int {
+			n, _ := strconv.Atoi(s)
+			return n
+		})).
+		Chain(routines.Reduce(func(acc int, n int) int { return acc + n }, 0)).
+		FileOut(output).
+		Run(ctx)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "5050\n", string(content))
+}
+
+func TestScript_FileInFileOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "trailing newline", content: "a\nb\nc\n"},
+		{name: "no trailing newline", content: "a\nb\nc"},
+		{name: "crlf and blank lines", content: "a\r\n\r\nb\n\nc"},
+		{name: "carriage return ending the last line", content: "a\nb\r"},
+		{name: "empty file", content: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.txt")
+			output := filepath.Join(dir, "out.log")
+			require.NoError(t, os.WriteFile(input, []byte(tt.content), 0644))
+
+			err := goscript.New().FileIn(input).FileOut(output).Run(context.Background())
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(output)
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(content))
+		})
+	}
+
+	t.Run("keeps the endings of transformed lines", func(t *testing.T) {
+		dir := t.TempDir()
+		input := filepath.Join(dir, "in.txt")
+		output := filepath.Join(dir, "out.txt")
+		require.NoError(t, os.WriteFile(input, []byte("a\r\nb\nc"), 0644))
+
+		err := goscript.New().
+			FileIn(input).
+			Chain(routines.Transform(strings.ToUpper)).
+			FileOut(output).
+			Run(context.Background())
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Equal(t, "A\r\nB\nC", string(content))
+	})
+}
+
+// keepIf forwards only the string messages matching keep.
+type keepIf func(string) bool
+
+func (k keepIf) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		if line, ok := msg.Data.(string); !ok || !k(line) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case pipe.Out() <- msg:
+		}
+	}
+
+	return nil
+}
+
+func TestScript_Count(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "access.log")
+	content := "GET / 200\nGET /missing 404\nPOST /login 200\nGET /admin 403\nGET /health 200\n"
+	require.NoError(t, os.WriteFile(input, []byte(content), 0644))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("counts every line", func(t *testing.T) {
+		count, err := goscript.New().FileIn(input).Count(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 5, count)
+	})
+
+	t.Run("counts lines passing a filter", func(t *testing.T) {
+		count, err := goscript.New().
+			FileIn(input).
+			Chain(keepIf(func(line string) bool { return strings.HasSuffix(line, " 200") })).
+			Count(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("returns zero for an empty input", func(t *testing.T) {
+		count, err := goscript.FromString("").Count(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+}
+
+// neverEnding is a source that emits one message and then blocks until ctx is done
+// without closing its pipe.
+type neverEnding struct{}
+
+func (neverEnding) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	pipe.Out() <- pipeline.Msg{Data: "partial"}
+	<-ctx.Done()
+	return nil
+}
+
+func TestScript_Timeout(t *testing.T) {
+	t.Run("ToString returns a timeout error for a non-terminating source", func(t *testing.T) {
+		start := time.Now()
+
+		_, err := goscript.New().In(neverEnding{}).WithTimeout(50 * time.Millisecond).ToString(context.Background())
+
+		assert.ErrorIs(t, err, goscript.ErrTimeout)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("Run reports a context deadline as a timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := goscript.New().In(neverEnding{}).Out(routines.Reduce(func(acc, s string) string { return acc + s }, "")).Run(ctx)
+
+		assert.ErrorIs(t, err, goscript.ErrTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Run returns the cancellation error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		err := goscript.New().In(neverEnding{}).Out(routines.Reduce(func(acc, s string) string { return acc + s }, "")).Run(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, errors.Is(err, goscript.ErrTimeout))
+	})
+
+	t.Run("finishes normally within the timeout", func(t *testing.T) {
+		result, err := goscript.FromString("a\nb").WithTimeout(5 * time.Second).ToString(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "ab", result)
+	})
+
+	t.Run("ReadFileWithTimeout reads a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.txt")
+		require.NoError(t, os.WriteFile(path, []byte("key=value"), 0644))
+
+		content, err := goscript.ReadFileWithTimeout(context.Background(), path, 5*time.Second)
+
+		require.NoError(t, err)
+		assert.Equal(t, "key=value", content)
+	})
+}
+
+// tickingSource emits until cancelled and records when it has returned.
+type tickingSource struct {
+	stopped atomic.Bool
+}
+
+func (s *tickingSource) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer s.stopped.Store(true)
+	defer pipe.Close()
+
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case pipe.Out() <- pipeline.Msg{Data: strconv.Itoa(i)}:
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScript_WithMaxDuration(t *testing.T) {
+	t.Run("stops a long-running source with a specific error", func(t *testing.T) {
+		source := &tickingSource{}
+		start := time.Now()
+
+		err := goscript.New().In(source).WithMaxDuration(50 * time.Millisecond).Out(routines.Discard()).Run(context.Background())
+
+		assert.ErrorIs(t, err, goscript.ErrMaxDurationExceeded)
+		assert.False(t, errors.Is(err, goscript.ErrTimeout))
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.True(t, source.stopped.Load(), "source still running after Run returned")
+	})
+
+	t.Run("caller deadline is not reported as max duration", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := goscript.New().In(neverEnding{}).WithMaxDuration(time.Minute).Out(routines.Reduce(func(acc, s string) string { return acc + s }, "")).Run(ctx)
+
+		assert.ErrorIs(t, err, goscript.ErrTimeout)
+		assert.False(t, errors.Is(err, goscript.ErrMaxDurationExceeded))
+	})
+
+	t.Run("finishes normally within the limit", func(t *testing.T) {
+		result, err := goscript.FromString("a\nb").WithMaxDuration(5 * time.Second).ToString(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "ab", result)
+	})
+}
+
+func TestScript_Inspect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	script := goscript.FromString("a\nb\nc").Chain(routines.Transform(strings.ToUpper)).WithMessageCounts()
+
+	result, err := script.ToString(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "ABC", result)
+
+	stats := script.Inspect()
+
+	names := make([]string, len(stats))
+	for i, s := range stats {
+		names[i] = s.Name
+	}
+	assert.Equal(t, []string{"input", "pipeline entry", "stage 1", "pipeline", "output"}, names)
+
+	for _, s := range stats[:4] {
+		assert.True(t, s.Counted, s.Name)
+		assert.EqualValues(t, 3, s.Sent, s.Name)
+		assert.EqualValues(t, 3, s.Received, s.Name)
+	}
+}
+
+func TestScript_OnPanic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		recovered any
+		stack     []byte
+	)
+
+	_, err := goscript.FromString("1\n2\nboom\n3").
+		Chain(routines.Transform(func(s string) string {
+			if s == "boom" {
+				panic("cannot process " + s)
+			}
+			return s
+		})).
+		OnPanic(func(r any, st []byte) {
+			recovered = r
+			stack = st
+		}).
+		ToString(ctx)
+
+	require.ErrorIs(t, err, goscript.ErrPanic)
+	assert.Contains(t, err.Error(), "cannot process boom")
+	assert.Equal(t, "cannot process boom", recovered)
+	assert.Contains(t, string(stack), "script_test.go")
+	assert.NoError(t, ctx.Err(), "shutdown should not wait for the caller's deadline")
+}
+
+type panickingSource struct{}
+
+func (panickingSource) Start(context.Context, pipeline.Pipe) error {
+	panic("source failed")
+}
+
+func TestScript_OnPanic_Source(t *testing.T) {
+	var calls int
+
+	err := goscript.New().
+		In(panickingSource{}).
+		Out(routines.Reduce(func(acc, s string) string { return acc + s }, "")).
+		OnPanic(func(any, []byte) { calls++ }).
+		Run(context.Background())
+
+	require.ErrorIs(t, err, goscript.ErrPanic)
+	assert.Equal(t, 1, calls)
+}
+
+func TestScript_OnPanic_ParallelWorkers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		calls, inHandler atomic.Int32
+		overlapped       atomic.Bool
+	)
+
+	// every worker panics on its first message, from its own goroutine
+	_, err := goscript.FromString("1\n2\n3\n4\n5\n6\n7\n8").
+		Parallel(routines.Transform(func(s string) string { panic("worker failed on " + s) }), 4).
+		OnPanic(func(any, []byte) {
+			if inHandler.Add(1) > 1 {
+				overlapped.Store(true)
+			}
+			time.Sleep(10 * time.Millisecond)
+			inHandler.Add(-1)
+			calls.Add(1)
+		}).
+		ToString(ctx)
+
+	require.ErrorIs(t, err, goscript.ErrPanic)
+	assert.Contains(t, err.Error(), "worker failed on")
+	assert.GreaterOrEqual(t, calls.Load(), int32(1))
+	assert.False(t, overlapped.Load(), "the handler runs for one panic at a time")
+}
+
+type collectMsgs struct {
+	msgs []pipeline.Msg
+}
+
+func (c *collectMsgs) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		c.msgs = append(c.msgs, msg)
+	}
+
+	return nil
+}
+
+func TestScript_WithIDGenerator(t *testing.T) {
+	sink := &collectMsgs{}
+
+	err := goscript.FromString("a\nb\nc\nd").
+		WithIDGenerator(pipeline.SequentialIDs()).
+		OrderedParallel(routines.Transform(strings.ToUpper), 3).
+		Out(sink).
+		Run(context.Background())
+	require.NoError(t, err)
+
+	var ids, data []string
+	for _, msg := range sink.msgs {
+		ids = append(ids, msg.ID)
+		data = append(data, msg.Data.(string))
+	}
+
+	assert.Equal(t, []string{"1", "2", "3", "4"}, ids)
+	assert.Equal(t, []string{"A", "B", "C", "D"}, data)
+}
+
+func TestScript_CSVOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	require.NoError(t, os.WriteFile(path, []byte("stale,row\n"), 0644))
+
+	toRow := routines.Transform(func(line string) []string { return strings.Split(line, " ") })
+
+	err := goscript.FromString("alice 30\nbob 25\ncarol 41").Chain(toRow).CSVOut(path).Run(context.Background())
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "alice,30\nbob,25\ncarol,41\n", string(content))
+}
+
+// failingStage forwards messages until it sees fail, then returns an error.
+type failingStage struct {
+	fail string
+}
+
+func (f failingStage) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		if msg.Data == f.fail {
+			return errors.New("invalid record " + f.fail)
+		}
+
+		if pipe.Send(ctx, msg) != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func TestScript_WithErrorPolicy(t *testing.T) {
+	t.Run("fail fast returns the stage error", func(t *testing.T) {
+		_, err := goscript.FromString("a\nb\nc").
+			Chain(failingStage{fail: "b"}).
+			WithErrorPolicy(pipeline.FailFast).
+			ToString(context.Background())
+
+		assert.ErrorContains(t, err, "invalid record b")
+	})
+
+	t.Run("fail fast stops a never-ending source", func(t *testing.T) {
+		err := goscript.New().In(&tickingSource{}).
+			Chain(failingStage{fail: "3"}).
+			WithErrorPolicy(pipeline.FailFast).
+			Out(routines.Discard()).
+			Run(context.Background())
+
+		assert.ErrorContains(t, err, "invalid record 3")
+	})
+
+	t.Run("continue on error is the default", func(t *testing.T) {
+		result, err := goscript.FromString("a\nb\nc").
+			Chain(failingStage{fail: "b"}).
+			ToString(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "a", result)
+	})
+}
+
+// burstSource sends up to n messages, counting those accepted, then waits for ctx.
+type burstSource struct {
+	n    int
+	sent atomic.Int32
+}
+
+func (b *burstSource) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for i := range b.n {
+		if pipe.Send(ctx, pipeline.Msg{Data: strconv.Itoa(i)}) != nil {
+			return nil
+		}
+		b.sent.Add(1)
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// slowStage forwards every message after delay, giving up when ctx is done.
+type slowStage struct {
+	delay time.Duration
+}
+
+func (s slowStage) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for msg := range pipe.In() {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.delay):
+		}
+
+		if pipe.Send(ctx, msg) != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// countMsgs is a sink counting the messages it receives.
+type countMsgs struct {
+	count atomic.Int32
+}
+
+func (c *countMsgs) Start(ctx context.Context, pipe pipeline.Pipe) error {
+	defer pipe.Close()
+
+	for range pipe.In() {
+		c.count.Add(1)
+	}
+
+	return nil
+}
+
+func TestScript_WithDrainTimeout(t *testing.T) {
+	run := func(drain time.Duration) (*burstSource, *countMsgs, error, time.Duration) {
+		source := &burstSource{n: 3}
+		sink := &countMsgs{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(30*time.Millisecond, cancel)
+
+		start := time.Now()
+		err := goscript.New().In(source).
+			Chain(slowStage{delay: 200 * time.Millisecond}).
+			WithDrainTimeout(drain).
+			Out(sink).
+			Run(ctx)
+
+		return source, sink, err, time.Since(start)
+	}
+
+	t.Run("finishes in-flight messages after cancellation", func(t *testing.T) {
+		source, sink, err, elapsed := run(2 * time.Second)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Positive(t, source.sent.Load())
+		assert.Equal(t, source.sent.Load(), sink.count.Load())
+		assert.Less(t, elapsed, 2*time.Second)
+	})
+
+	t.Run("stops draining once the timeout passes", func(t *testing.T) {
+		source, sink, err, elapsed := run(20 * time.Millisecond)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, sink.count.Load(), source.sent.Load())
+		assert.Less(t, elapsed, 190*time.Millisecond)
+	})
+
+	t.Run("drops in-flight messages without a drain timeout", func(t *testing.T) {
+		source, sink, _, _ := run(0)
+
+		assert.Less(t, sink.count.Load(), source.sent.Load())
+	})
+}
+
+func TestScript_StopWhen(t *testing.T) {
+	t.Run("stops a never-ending source at the sentinel", func(t *testing.T) {
+		source := &tickingSource{}
+		sink := &collectMsgs{}
+
+		err := goscript.New().In(source).
+			Chain(routines.StopWhen(func(msg pipeline.Msg) bool { return msg.Data == "3" })).
+			Out(sink).
+			Run(context.Background())
+
+		require.NoError(t, err)
+
+		var data []any
+		for _, msg := range sink.msgs {
+			data = append(data, msg.Data)
+		}
+		assert.Equal(t, []any{"0", "1", "2"}, data)
+
+		assert.Eventually(t, source.stopped.Load, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("later stages see the end of the stream", func(t *testing.T) {
+		result, err := goscript.FromString("a\nb\nSTOP\nc").
+			Chain(routines.StopWhen(func(msg pipeline.Msg) bool { return msg.Data == "STOP" })).
+			Chain(routines.Reduce(func(acc, line string) string { return acc + line }, "")).
+			ToString(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "ab", result)
+	})
+}
+
+func TestScript_Take(t *testing.T) {
+	t.Run("stops a never-ending source after n messages", func(t *testing.T) {
+		source := &tickingSource{}
+		sink := &collectMsgs{}
+
+		err := goscript.New().In(source).Chain(routines.Take(3)).Out(sink).Run(context.Background())
+
+		require.NoError(t, err)
+
+		var data []any
+		for _, msg := range sink.msgs {
+			data = append(data, msg.Data)
+		}
+		assert.Equal(t, []any{"0", "1", "2"}, data)
+
+		assert.Eventually(t, source.stopped.Load, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("takes nothing for zero", func(t *testing.T) {
+		result, err := goscript.FromString("a\nb\nc").
+			Chain(routines.Take(0)).
+			Chain(routines.Reduce(func(acc, line string) string { return acc + line }, "")).
+			ToString(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, result)
+	})
+}
+
+func TestScript_ReflowJSON(t *testing.T) {
+	dir := t.TempDir()
+	arrayPath := filepath.Join(dir, "records.json")
+	linesPath := filepath.Join(dir, "records.jsonl")
+	roundTripPath := filepath.Join(dir, "round_trip.json")
+
+	// keys out of alphabetical order and a number beyond float64 precision must survive
+	records := make([]map[string]any, 5000)
+	for i := range records {
+		records[i] = map[string]any{"name": "record-" + strconv.Itoa(i), "tags": []string{"a", "b"}}
+	}
+	pretty, err := json.MarshalIndent(records, "", "  ")
+	require.NoError(t, err)
+	pretty = bytes.Replace(pretty, []byte(`"name"`), []byte(`"z": 9007199254740993, "name"`), 1)
+	require.NoError(t, os.WriteFile(arrayPath, pretty, 0644))
+
+	ctx := context.Background()
+
+	require.NoError(t, goscript.New().ReflowJSON(arrayPath, linesPath, true).Run(ctx))
+
+	content, err := os.ReadFile(linesPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	require.Len(t, lines, len(records))
+	assert.Equal(t, `{"z":9007199254740993,"name":"record-0","tags":["a","b"]}`, lines[0])
+	assert.Equal(t, `{"name":"record-4999","tags":["a","b"]}`, lines[4999])
+
+	require.NoError(t, goscript.New().ReflowJSON(linesPath, roundTripPath, false).Run(ctx))
+
+	roundTrip, err := os.ReadFile(roundTripPath)
+	require.NoError(t, err)
+
+	var compact bytes.Buffer
+	require.NoError(t, json.Compact(&compact, pretty))
+	assert.Equal(t, compact.String()+"\n", string(roundTrip))
+}
+
+func TestScript_RequireOrdered(t *testing.T) {
+	lines := make([]string, 50)
+	for i := range lines {
+		lines[i] = strconv.Itoa(i)
+	}
+	input := strings.Join(lines, "\n")
+
+	// jitter finishes messages out of input order
+	jitter := routines.Transform(func(line string) string {
+		time.Sleep(time.Duration(rand.IntN(3)) * time.Millisecond)
+		return line
+	})
+
+	collected := func(sink *collectMsgs) []string {
+		data := make([]string, len(sink.msgs))
+		for i, msg := range sink.msgs {
+			data[i] = msg.Data.(string)
+		}
+		return data
+	}
+
+	t.Run("rejects an unordered parallel stage", func(t *testing.T) {
+		sink := &collectMsgs{}
+
+		err := goscript.FromString(input).
+			Chain(routines.Parallel(jitter, 8)).
+			RequireOrdered().
+			Out(sink).
+			Run(context.Background())
+
+		require.ErrorIs(t, err, goscript.ErrUnorderedStage)
+		assert.ErrorContains(t, err, "stage 1")
+		assert.Empty(t, sink.msgs, "nothing runs")
+	})
+
+	t.Run("accepts ordered and explicitly unordered stages", func(t *testing.T) {
+		sink := &collectMsgs{}
+
+		err := goscript.FromString(input).
+			RequireOrdered().
+			Chain(routines.Parallel(jitter, 8).Ordered()).
+			ChainUnordered(routines.Parallel(routines.Transform(strings.ToUpper), 2)).
+			Out(sink).
+			Run(context.Background())
+
+		require.NoError(t, err)
+		assert.Len(t, sink.msgs, len(lines))
+	})
+
+	t.Run("rejects a stage added with Parallel instead of switching it", func(t *testing.T) {
+		// an ordered Parallel cannot match the results of a filtering routine to its inputs
+		evens := routines.Filter(func(line string) bool { n, _ := strconv.Atoi(line); return n%2 == 0 })
+
+		err := goscript.FromString(input).
+			Parallel(evens, 8).
+			RequireOrdered().
+			Out(&collectMsgs{}).
+			Run(context.Background())
+
+		require.ErrorIs(t, err, goscript.ErrUnorderedStage)
+	})
+
+	t.Run("keeps the order of OrderedParallel stages", func(t *testing.T) {
+		sink := &collectMsgs{}
+
+		err := goscript.FromString(input).
+			OrderedParallel(jitter, 8).
+			RequireOrdered().
+			Out(sink).
+			Run(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, lines, collected(sink))
+	})
+
+	t.Run("rejects an unordered input", func(t *testing.T) {
+		err := goscript.New().
+			In(filesystem.Glob("*.txt").WithConcurrency(4)).
+			RequireOrdered().
+			Out(&collectMsgs{}).
+			Run(context.Background())
+
+		require.ErrorIs(t, err, goscript.ErrUnorderedStage)
+		assert.ErrorContains(t, err, "input")
+	})
+
+	t.Run("rejects a merged input", func(t *testing.T) {
+		gen := func(i int) any { return i }
+
+		err := goscript.New().
+			In(routines.RoundRobinMerge(routines.Generate(3, gen), routines.Generate(3, gen))).
+			RequireOrdered().
+			Out(&collectMsgs{}).
+			Run(context.Background())
+
+		require.ErrorIs(t, err, goscript.ErrUnorderedStage)
+	})
+}
+
+func TestScript_Errors(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	t.Run("matches a missing input file", func(t *testing.T) {
+		err := goscript.New().ReflowJSON(filepath.Join(dir, "missing.json"), filepath.Join(dir, "out.jsonl"), true).
+			WithErrorPolicy(pipeline.FailFast).
+			Run(ctx)
+
+		assert.ErrorIs(t, err, goscript.ErrFileOpen)
+	})
+
+	t.Run("matches a codec parse failure", func(t *testing.T) {
+		brokenPath := filepath.Join(dir, "broken.json")
+		require.NoError(t, os.WriteFile(brokenPath, []byte(`[{"id": 1}, {"id": `), 0644))
+
+		err := goscript.New().ReflowJSON(brokenPath, filepath.Join(dir, "out.jsonl"), true).
+			WithErrorPolicy(pipeline.FailFast).
+			Run(ctx)
+
+		assert.ErrorIs(t, err, goscript.ErrCodecParse)
+		assert.NotErrorIs(t, err, goscript.ErrFileOpen)
+	})
+}
+
+func TestScript_Describe(t *testing.T) {
+	t.Run("lists the stages in order with their settings", func(t *testing.T) {
+		double := routines.Transform(func(x int) int { return x * 2 })
+
+		script := goscript.New().
+			CSVIn("orders.csv").
+			Chain(routines.Filter(func(x int) bool { return x > 0 })).
+			Parallel(double, 4).
+			OrderedParallel(double, 2).
+			JSONOut("orders.jsonl")
+
+		assert.Equal(t, `input: read file "orders.csv" with CSVCodec{Separator: ',', Comment: '#'}
+stage 1: *routines.FilterRoutine[int]
+stage 2: parallel x4 unordered: *routines.TransformRoutine[int,int]
+stage 3: parallel x2 ordered: *routines.TransformRoutine[int,int]
+output: write file "orders.jsonl" with JSONCodec
+`, script.Describe())
+	})
+
+	t.Run("names the default input and output", func(t *testing.T) {
+		assert.Equal(t, "input: *routines.StdInRoutine\noutput: *routines.StdOutRoutine\n", goscript.New().Describe())
+	})
+}
+
+func TestScript_DefaultStdio(t *testing.T) {
+	stdinReader, stdinWriter, err := os.Pipe()
+	require.NoError(t, err)
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	require.NoError(t, err)
+
+	originalStdin, originalStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdinReader, stdoutWriter
+	defer func() { os.Stdin, os.Stdout = originalStdin, originalStdout }()
+
+	_, err = stdinWriter.WriteString("first\nsecond\nthird\n")
+	require.NoError(t, err)
+	require.NoError(t, stdinWriter.Close())
+
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(stdoutReader)
+		output <- data
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = goscript.New().Run(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stdoutWriter.Close())
+
+	assert.Equal(t, "first\nsecond\nthird\n", string(<-output))
+}
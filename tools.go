@@ -1,6 +1,15 @@
 package goscript
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/caiorcferreira/goscript/internal/pipeline"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+)
 
 // ReadFile is a convenience method that creates a new script instance to read a file
 // as a blob and return its contents as a string.
@@ -26,3 +35,82 @@ func ReadFile(ctx context.Context, path string) (string, error) {
 
 	return file, nil
 }
+
+// ReadFileWithTimeout is like ReadFile but gives up after timeout, returning an error
+// wrapping ErrTimeout instead of blocking if the read does not finish.
+//
+// Example:
+//
+//	content, err := goscript.ReadFileWithTimeout(ctx, "/mnt/share/config.txt", 5*time.Second)
+func ReadFileWithTimeout(ctx context.Context, path string, timeout time.Duration) (string, error) {
+	return New().BlobFileIn(path).WithTimeout(timeout).ToString(ctx)
+}
+
+// RegisterCodec sets the codecs FileIn, FileOut and other file helpers use for files with
+// the given extension, extending or overriding the built-in mapping.
+//
+// Parameters:
+//   - ext: File extension, with or without the leading dot (case-insensitive)
+//   - read: Codec used to parse matching files, or nil for the line codec
+//   - write: Codec used to encode matching files, or nil for the line codec
+//
+// Example:
+//
+//	goscript.RegisterCodec(".ndjson", filesystem.NewJSONCodec().WithJSONLinesMode(), filesystem.NewJSONCodec())
+func RegisterCodec(ext string, read filesystem.ReadCodec, write filesystem.WriteCodec) {
+	filesystem.RegisterCodec(ext, read, write)
+}
+
+// FromString creates a new script whose input is the lines of s, handy for one-liners
+// and tests.
+//
+// Example:
+//
+//	out, err := goscript.FromString("a\nb\nc").Chain(upper).ToString(ctx)
+func FromString(s string) *Script {
+	return FromReader(strings.NewReader(s), filesystem.NewLineCodec())
+}
+
+// FromBytes creates a new script whose input is the lines of b.
+//
+// Example:
+//
+//	out, err := goscript.FromBytes(payload).Chain(process).ToString(ctx)
+func FromBytes(b []byte) *Script {
+	return FromReader(bytes.NewReader(b), filesystem.NewLineCodec())
+}
+
+// FromReader creates a new script whose input is reader parsed with codec.
+//
+// Parameters:
+//   - reader: Source of the data
+//   - codec: Codec used to parse the data, or nil for the line codec
+//
+// Example:
+//
+//	script := goscript.FromReader(strings.NewReader(`[1, 2, 3]`), filesystem.NewJSONCodec())
+func FromReader(reader io.Reader, codec filesystem.ReadCodec) *Script {
+	source := filesystem.Reader(reader)
+	if codec != nil {
+		source.WithCodec(codec)
+	}
+
+	return New().In(source)
+}
+
+// SubPipeline is a reusable chain of routines. Chained into a script it runs as a single
+// step, so one sub-pipeline can be shared by many scripts.
+type SubPipeline = pipeline.Pipeline
+
+// Pipeline creates an empty sub-pipeline to configure with Chain and pass to Script.Chain.
+//
+// Example:
+//
+//	formatting := goscript.Pipeline().
+//		Chain(routines.Transform(strings.ToUpper)).
+//		Chain(routines.Transform(strings.TrimSpace))
+//
+//	err := goscript.New().FileIn("names.txt").Chain(formatting).FileOut("out.txt").Run(ctx)
+func Pipeline() *SubPipeline {
+	return pipeline.New()
+}
@@ -0,0 +1,49 @@
+package goscript_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiorcferreira/goscript"
+	"github.com/caiorcferreira/goscript/internal/routines"
+	"github.com/caiorcferreira/goscript/internal/routines/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromString(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := goscript.FromString("a\nb\nc").
+		Chain(routines.Transform(strings.ToUpper)).
+		ToString(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ABC", result)
+}
+
+func TestFromBytes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := goscript.FromBytes([]byte("x\ny")).
+		Chain(routines.Transform(func(s string) string { return s + ";" })).
+		ToString(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, "x;y;", result)
+}
+
+func TestFromReader_WithCodec(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := goscript.FromReader(strings.NewReader(`["a", "b"]`), filesystem.NewJSONCodec()).
+		ToString(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ab", result)
+}